@@ -0,0 +1,208 @@
+// Package compat decodes the API responses of older Headscale releases into the types of the v1 resources.
+//
+// The v1 types follow the JSON schema of the newest supported release. For older releases, the codec
+// returned by ForVersion rewrites the response into that schema before decoding it:
+//
+//   - Before v0.26.0, pre-auth keys name their user instead of embedding it; the name becomes User.Name.
+//   - Before v0.28.0, nodes report forced, valid and invalid tags; forced and valid tags become Tags.
+//     The forced and valid tags are kept under ForcedTagsMember and ValidTagsMember, as the tags
+//     endpoint of these releases only sets the forced tags.
+//
+// Responses of releases before v0.26.0 carry no route lists on nodes. The nodes resource fills
+// ApprovedRoutes, AvailableRoutes and SubnetRoutes from the routes API of these releases instead.
+//
+// The package also keeps unknown response members in the Extra maps of the v1 types (UnmarshalExtra,
+// MarshalExtra) and reports responses that drift from the expected schema (CheckSchema).
+package compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+// Codec decodes Headscale API response bodies.
+type Codec interface {
+	Decode(data []byte, v any) error
+}
+
+// Options configures a codec.
+type Options struct {
+	// Strict makes Decode return a *SchemaDriftError when a response has members the target type has
+	// no field for, or lacks members the target type requires. The response is decoded regardless.
+	Strict bool
+}
+
+// ForVersion returns the codec for responses of the given Headscale release.
+// An unknown version is assumed to be the newest release.
+func ForVersion(v versions.ServerVersion) Codec {
+	return NewCodec(v, Options{})
+}
+
+// NewCodec returns the codec for responses of the given Headscale release, configured by opt.
+func NewCodec(v versions.ServerVersion, opt Options) Codec {
+	var rules []rewrite
+	for _, rw := range rewrites {
+		if !v.Supports(rw.since) {
+			rules = append(rules, rw)
+		}
+	}
+
+	if len(rules) == 0 && !opt.Strict {
+		return jsonCodec{}
+	}
+	return legacyCodec{version: v, rules: rules, strict: opt.Strict}
+}
+
+// jsonCodec decodes responses that already match the v1 types.
+type jsonCodec struct{}
+
+func (jsonCodec) Decode(data []byte, v any) error {
+	return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// legacyCodec rewrites the responses of an older release before decoding them, and checks
+// them against the schema of the target type in strict mode.
+type legacyCodec struct {
+	version versions.ServerVersion
+	rules   []rewrite
+	strict  bool
+}
+
+func (c legacyCodec) Decode(data []byte, v any) error {
+	normalized, err := c.normalize(data)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(normalized, v); err != nil {
+		return err
+	}
+	if c.strict {
+		return checkSchema(normalized, v, c.kept())
+	}
+	return nil
+}
+
+// normalize rewrites data into the schema of the newest release.
+func (c legacyCodec) normalize(data []byte) ([]byte, error) {
+	if len(c.rules) == 0 {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	c.walk(doc)
+
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("rewrite %s response: %w", c.version, err)
+	}
+	return normalized, nil
+}
+
+// kept returns the members the rules keep next to the rewritten ones.
+func (c legacyCodec) kept() []string {
+	var out []string
+	for _, rw := range c.rules {
+		out = append(out, rw.keep...)
+	}
+	return out
+}
+
+// walk applies the rules to every object in doc, depth first.
+func (c legacyCodec) walk(doc any) {
+	switch val := doc.(type) {
+	case map[string]any:
+		for key, child := range val {
+			for _, rw := range c.rules {
+				if slices.Contains(rw.keys, key) {
+					eachObject(child, rw.apply)
+				}
+			}
+			c.walk(child)
+		}
+	case []any:
+		for _, child := range val {
+			c.walk(child)
+		}
+	}
+}
+
+// eachObject calls fn for v if it is an object, or for each object in v if it is an array.
+func eachObject(v any, fn func(map[string]any)) {
+	switch val := v.(type) {
+	case map[string]any:
+		fn(val)
+	case []any:
+		for _, item := range val {
+			if obj, ok := item.(map[string]any); ok {
+				fn(obj)
+			}
+		}
+	}
+}
+
+// rewrite converts the objects stored under keys to the schema of the release providing since.
+// Members in keep are left in the objects for the v1 types to read, and not reported as unknown
+// in strict mode.
+type rewrite struct {
+	since versions.Capability
+	keys  []string
+	apply func(obj map[string]any)
+	keep  []string
+}
+
+// Members of the nodes of releases before v0.28.0 kept next to the merged Tags.
+const (
+	ForcedTagsMember = "forcedTags"
+	ValidTagsMember  = "validTags"
+)
+
+// rewrites lists the schema changes between the supported releases.
+var rewrites = []rewrite{
+	{since: versions.CapabilityPreAuthKeyUserObject, keys: []string{"preAuthKey", "preAuthKeys"}, apply: embedUser},
+	{since: versions.CapabilityNodeTags, keys: []string{"node", "nodes"}, apply: mergeTags,
+		keep: []string{ForcedTagsMember, ValidTagsMember}},
+}
+
+// embedUser replaces the user name of a pre-auth key with a user object.
+func embedUser(obj map[string]any) {
+	name, ok := obj["user"].(string)
+	if !ok {
+		return
+	}
+
+	if name == "" {
+		delete(obj, "user")
+		return
+	}
+	obj["user"] = map[string]any{"name": name}
+}
+
+// mergeTags sets the tags of a node to the tags in effect, its forced and valid tags, and drops
+// the invalid tags.
+func mergeTags(obj map[string]any) {
+	var tags []any
+	for _, key := range []string{"tags", ForcedTagsMember, ValidTagsMember} {
+		list, _ := obj[key].([]any)
+		for _, tag := range list {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	delete(obj, "invalidTags")
+	if tags != nil {
+		obj["tags"] = tags
+	}
+}
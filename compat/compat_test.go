@@ -0,0 +1,76 @@
+package compat
+
+import (
+	"testing"
+
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   versions.ServerVersion
+		wantRules int
+	}{
+		{name: "unknown", version: versions.ServerVersion{}},
+		{name: "v0.28.0", version: versions.ServerVersion{Minor: 28}},
+		{name: "v0.27.0", version: versions.ServerVersion{Minor: 27}, wantRules: 1},
+		{name: "v0.25.0", version: versions.ServerVersion{Minor: 25}, wantRules: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := ForVersion(tt.version)
+			if tt.wantRules == 0 {
+				assert.IsType(t, jsonCodec{}, codec)
+				return
+			}
+			require.IsType(t, legacyCodec{}, codec)
+			assert.Len(t, codec.(legacyCodec).rules, tt.wantRules) //nolint:errcheck // reason: type asserted above
+		})
+	}
+}
+
+func TestLegacyCodec_Decode(t *testing.T) {
+	codec := ForVersion(versions.ServerVersion{Minor: 25})
+
+	var got struct {
+		Nodes []struct {
+			ID         string   `json:"id"`
+			Tags       []string `json:"tags"`
+			ForcedTags []string `json:"forcedTags"`
+			PreAuthKey struct {
+				User struct {
+					Name string `json:"name"`
+				} `json:"user"`
+			} `json:"preAuthKey"`
+		} `json:"nodes"`
+	}
+	err := codec.Decode([]byte(`{"nodes":[{
+		"id": "18446744073709551615",
+		"forcedTags": ["tag:a"],
+		"validTags": ["tag:b", "tag:a"],
+		"invalidTags": ["tag:c"],
+		"preAuthKey": {"user": "alice"}
+	}]}`), &got)
+	require.NoError(t, err)
+
+	require.Len(t, got.Nodes, 1)
+	node := got.Nodes[0]
+	assert.Equal(t, "18446744073709551615", node.ID)
+	assert.Equal(t, []string{"tag:a", "tag:b"}, node.Tags)
+	assert.Equal(t, []string{"tag:a"}, node.ForcedTags, "forced tags are kept")
+	assert.Equal(t, "alice", node.PreAuthKey.User.Name)
+}
+
+func TestEmbedUser(t *testing.T) {
+	key := map[string]any{"user": ""}
+	embedUser(key)
+	assert.NotContains(t, key, "user")
+
+	key = map[string]any{"user": map[string]any{"id": "1"}}
+	embedUser(key)
+	assert.Equal(t, map[string]any{"id": "1"}, key["user"], "user objects are kept")
+}
@@ -0,0 +1,250 @@
+package compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// requiredTag marks a field that every response must contain, e.g. `schema:"required"`.
+const requiredTag = "required"
+
+// SchemaDriftError is returned in strict mode when a response does not match the expected schema,
+// typically because the server runs a newer or older Headscale release than the client supports.
+//
+// The response is still decoded; unknown members are kept in the Extra maps of the v1 types.
+type SchemaDriftError struct {
+	// Unknown lists the paths of members the client has no field for, e.g. "nodes[0].nodeStatus".
+	Unknown []string
+
+	// Missing lists the paths of required members absent from the response, e.g. "node.id".
+	Missing []string
+
+	ignore []string
+}
+
+func (e *SchemaDriftError) Error() string {
+	var parts []string
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown fields "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing required fields "+strings.Join(e.Missing, ", "))
+	}
+	return "schema drift: " + strings.Join(parts, "; ")
+}
+
+// CheckSchema compares the JSON object data with the type of v, which must be the value data was
+// decoded into. It returns a *SchemaDriftError listing unknown members and missing required members.
+func CheckSchema(data []byte, v any) error {
+	return checkSchema(data, v, nil)
+}
+
+// checkSchema is CheckSchema, not reporting the object members named in ignore.
+func checkSchema(data []byte, v any, ignore []string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	drift := &SchemaDriftError{ignore: ignore}
+	drift.check(doc, reflect.TypeOf(v), "")
+	if len(drift.Unknown) == 0 && len(drift.Missing) == 0 {
+		return nil
+	}
+
+	slices.Sort(drift.Unknown)
+	slices.Sort(drift.Missing)
+	return drift
+}
+
+// check records the differences between doc and the JSON schema of t.
+func (e *SchemaDriftError) check(doc any, t reflect.Type, path string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := fieldsOf(t)
+		for key, child := range obj {
+			f, ok := fields.lookup(key)
+			if !ok {
+				if slices.Contains(e.ignore, key) {
+					continue
+				}
+				e.Unknown = append(e.Unknown, joinPath(path, key))
+				continue
+			}
+			e.check(child, f.typ, joinPath(path, key))
+		}
+		for _, f := range fields.required() {
+			if _, ok := obj[f.name]; !ok {
+				e.Missing = append(e.Missing, joinPath(path, f.name))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		list, _ := doc.([]any)
+		for i, item := range list {
+			e.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		obj, _ := doc.(map[string]any)
+		for key, child := range obj {
+			e.check(child, t.Elem(), joinPath(path, key))
+		}
+	default:
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// UnmarshalExtra decodes the JSON object data into v and returns the members v has no field for.
+// It returns nil if there are none.
+//
+// Types call it from UnmarshalJSON with a pointer to a method-less copy of themselves.
+func UnmarshalExtra(data []byte, v any) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+
+	fields := fieldsOf(reflect.TypeOf(v).Elem())
+	for key := range members {
+		if _, ok := fields.lookup(key); ok {
+			delete(members, key)
+		}
+	}
+
+	if len(members) == 0 {
+		return nil, nil //nolint:nilnil // reason: no extra members is not an error
+	}
+	return members, nil
+}
+
+// MarshalExtra encodes v as a JSON object and appends the extra members, sorted by name.
+// Members that collide with a field of v are skipped.
+func MarshalExtra(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	fields := fieldsOf(reflect.TypeOf(v))
+	buf := bytes.NewBuffer(bytes.TrimSuffix(data, []byte("}")))
+	empty := buf.Len() == 1
+	for _, key := range slices.Sorted(maps.Keys(extra)) {
+		if _, ok := fields.lookup(key); ok {
+			continue
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			buf.WriteByte(',')
+		}
+		empty = false
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// field is a struct field as seen by encoding/json.
+type field struct {
+	name     string
+	typ      reflect.Type
+	required bool
+}
+
+// fieldSet is the set of JSON fields of a struct type.
+type fieldSet []field
+
+// lookup finds the field decoding the member key, preferring an exact match over a
+// case-insensitive one, as encoding/json does.
+func (s fieldSet) lookup(key string) (field, bool) {
+	for _, f := range s {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range s {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func (s fieldSet) required() []field {
+	var out []field
+	for _, f := range s {
+		if f.required {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// fieldCache maps struct types to their fieldSet.
+var fieldCache sync.Map
+
+// fieldsOf returns the JSON fields of the struct type t, including those of embedded structs.
+func fieldsOf(t reflect.Type) fieldSet {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(fieldSet) //nolint:errcheck // reason: the cache only holds fieldSets
+	}
+
+	var fields fieldSet
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		ft := sf.Type
+		if sf.Anonymous && name == "" {
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, fieldsOf(ft)...)
+				continue
+			}
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{name: name, typ: sf.Type, required: sf.Tag.Get("schema") == requiredTag})
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
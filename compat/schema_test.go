@@ -0,0 +1,111 @@
+package compat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTag struct {
+	Name string `json:"name" schema:"required"`
+}
+
+type testItem struct {
+	ID    string                     `json:"id" schema:"required"`
+	Count int                        `json:"count,omitempty"`
+	Tags  []testTag                  `json:"tags"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (i *testItem) UnmarshalJSON(data []byte) error {
+	type item testItem
+	extra, err := UnmarshalExtra(data, (*item)(i))
+	if err != nil {
+		return err
+	}
+	i.Extra = extra
+	return nil
+}
+
+func (i testItem) MarshalJSON() ([]byte, error) {
+	type item testItem
+	return MarshalExtra(item(i), i.Extra)
+}
+
+func TestExtra_RoundTrip(t *testing.T) {
+	in := `{"id":"1","tags":[],"zone":{"name":"eu"},"COUNT":3,"archived":true}`
+
+	var item testItem
+	require.NoError(t, json.Unmarshal([]byte(in), &item))
+	assert.Equal(t, 3, item.Count, "members are matched case-insensitively")
+	assert.Equal(t, map[string]json.RawMessage{
+		"zone":     json.RawMessage(`{"name":"eu"}`),
+		"archived": json.RawMessage(`true`),
+	}, item.Extra)
+
+	out, err := json.Marshal(item)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"1","count":3,"tags":[],"zone":{"name":"eu"},"archived":true}`, string(out))
+
+	item = testItem{Extra: map[string]json.RawMessage{"id": json.RawMessage(`"shadowed"`)}}
+	out, err = json.Marshal(item)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"","tags":null}`, string(out), "fields win over extra members")
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantUnknown []string
+		wantMissing []string
+	}{
+		{name: "matching", data: `{"id":"1","tags":[{"name":"a"}]}`},
+		{
+			name:        "unknown members",
+			data:        `{"id":"1","zone":"eu","tags":[{"name":"a","color":"red"}]}`,
+			wantUnknown: []string{"tags[0].color", "zone"},
+		},
+		{
+			name:        "missing members",
+			data:        `{"tags":[{"name":"a"},{}]}`,
+			wantMissing: []string{"id", "tags[1].name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var item testItem
+			require.NoError(t, json.Unmarshal([]byte(tt.data), &item))
+
+			err := CheckSchema([]byte(tt.data), &item)
+			if tt.wantUnknown == nil && tt.wantMissing == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var drift *SchemaDriftError
+			require.ErrorAs(t, err, &drift)
+			assert.Equal(t, tt.wantUnknown, drift.Unknown)
+			assert.Equal(t, tt.wantMissing, drift.Missing)
+		})
+	}
+}
+
+func TestSchemaDriftError_Error(t *testing.T) {
+	err := &SchemaDriftError{Unknown: []string{"node.zone"}, Missing: []string{"node.id"}}
+	assert.Equal(t, "schema drift: unknown fields node.zone; missing required fields node.id", err.Error())
+}
+
+func TestNewCodec_Strict(t *testing.T) {
+	codec := NewCodec(versions.ServerVersion{}, Options{Strict: true})
+
+	var item testItem
+	err := codec.Decode([]byte(`{"id":"1","zone":"eu"}`), &item)
+	var drift *SchemaDriftError
+	require.ErrorAs(t, err, &drift)
+	assert.Equal(t, "1", item.ID)
+}
@@ -0,0 +1,299 @@
+// Package credentials provides sources for the API key used to authenticate with Headscale.
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibare/headscale-client-go/logger"
+)
+
+const (
+	// DefaultFilePollInterval is the default minimum interval between checks of a key file for changes.
+	DefaultFilePollInterval = 10 * time.Second
+)
+
+var (
+	// ErrEmptyAPIKey is returned when a source yields an empty API key.
+	ErrEmptyAPIKey = errors.New("API key is empty")
+
+	// ErrCommandRequired is returned when a CommandSource has no command.
+	ErrCommandRequired = errors.New("command is required")
+)
+
+// Source provides the API key used to authenticate requests.
+//
+// APIKey is called for every request and must be safe for concurrent use.
+type Source interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// Refresher is implemented by sources that can re-fetch their API key on demand.
+//
+// The client calls Refresh once when the server answers 401 Unauthorized, then retries the request.
+type Refresher interface {
+	Refresh(ctx context.Context) (string, error)
+}
+
+// StaticSource is a Source that always returns the same API key.
+type StaticSource struct {
+	key string
+}
+
+// NewStaticSource creates a new StaticSource for key.
+func NewStaticSource(key string) *StaticSource {
+	return &StaticSource{key: key}
+}
+
+// APIKey returns the static API key.
+func (s *StaticSource) APIKey(_ context.Context) (string, error) {
+	if s.key == "" {
+		return "", ErrEmptyAPIKey
+	}
+	return s.key, nil
+}
+
+// MutableSource is a Source whose API key can be replaced while the client is in use.
+type MutableSource struct {
+	mu  sync.RWMutex
+	key string
+}
+
+// NewMutableSource creates a new MutableSource starting with key.
+func NewMutableSource(key string) *MutableSource {
+	return &MutableSource{key: key}
+}
+
+// APIKey returns the current API key.
+func (s *MutableSource) APIKey(_ context.Context) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.key == "" {
+		return "", ErrEmptyAPIKey
+	}
+	return s.key, nil
+}
+
+// Set replaces the API key used by subsequent requests.
+func (s *MutableSource) Set(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.key = key
+}
+
+// EnvSource is a Source that reads the API key from an environment variable on every request.
+type EnvSource struct {
+	name      string
+	lookupEnv func(string) (string, bool)
+}
+
+// NewEnvSource creates a new EnvSource reading the environment variable name.
+func NewEnvSource(name string) *EnvSource {
+	return &EnvSource{name: name, lookupEnv: os.LookupEnv}
+}
+
+// APIKey returns the current value of the environment variable.
+func (s *EnvSource) APIKey(_ context.Context) (string, error) {
+	v, _ := s.lookupEnv(s.name)
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", fmt.Errorf("env %s: %w", s.name, ErrEmptyAPIKey)
+	}
+	return v, nil
+}
+
+// Refresh re-reads the environment variable.
+func (s *EnvSource) Refresh(ctx context.Context) (string, error) {
+	return s.APIKey(ctx)
+}
+
+// FileSourceOptions contains options for creating a FileSource.
+type FileSourceOptions struct {
+	// PollInterval is the minimum interval between checks of the file for changes.
+	// Defaults to DefaultFilePollInterval.
+	PollInterval time.Duration
+
+	// Logger receives the errors of reads answered with the cached key.
+	// Defaults to a DefaultLogger at LevelInfo.
+	Logger logger.Logger
+}
+
+// FileSource is a Source that reads the API key from a file and reloads it when the file changes.
+//
+// Changes are detected by comparing the file's modification time and size, checked at most once
+// per poll interval. This follows symlinks, so it picks up Kubernetes secret volume updates.
+//
+// If the file cannot be read or is empty once a key was loaded, e.g. while a secret volume swaps its
+// symlinks or the file is truncated before being rewritten, the cached key is returned and the error
+// is logged; only Refresh fails.
+type FileSource struct {
+	path         string
+	pollInterval time.Duration
+	logger       logger.Logger
+	now          func() time.Time
+
+	mu        sync.Mutex
+	key       string
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+}
+
+// NewFileSource creates a new FileSource reading the file at path.
+func NewFileSource(path string, opt FileSourceOptions) *FileSource {
+	if opt.PollInterval <= 0 {
+		opt.PollInterval = DefaultFilePollInterval
+	}
+	if opt.Logger == nil {
+		opt.Logger = logger.NewDefaultLogger(logger.LevelInfo)
+	}
+
+	return &FileSource{
+		path:         path,
+		pollInterval: opt.PollInterval,
+		logger:       opt.Logger,
+		now:          time.Now,
+	}
+}
+
+// APIKey returns the API key from the file, reloading it if the file changed.
+func (s *FileSource) APIKey(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key != "" && s.now().Sub(s.lastCheck) < s.pollInterval {
+		return s.key, nil
+	}
+
+	return s.load(ctx, false)
+}
+
+// Refresh re-reads the file regardless of the poll interval.
+func (s *FileSource) Refresh(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(ctx, true)
+}
+
+// load reads the file if it changed since the last read, or unconditionally if force is set.
+func (s *FileSource) load(ctx context.Context, force bool) (string, error) {
+	s.lastCheck = s.now()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return s.cached(ctx, force, fmt.Errorf("file %s: %w", s.path, err))
+	}
+
+	if !force && s.key != "" && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.key, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return s.cached(ctx, force, fmt.Errorf("file %s: %w", s.path, err))
+	}
+
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return s.cached(ctx, force, fmt.Errorf("file %s: %w", s.path, ErrEmptyAPIKey))
+	}
+
+	s.key = key
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	return s.key, nil
+}
+
+// cached returns the cached key in place of the read error or empty key err, logging err, unless
+// force is set or no key is cached.
+func (s *FileSource) cached(ctx context.Context, force bool, err error) (string, error) {
+	if force || s.key == "" {
+		return "", err
+	}
+
+	s.logger.Warn(ctx, "using cached API key", "error", err)
+	return s.key, nil
+}
+
+// CommandSourceOptions contains options for creating a CommandSource.
+type CommandSourceOptions struct {
+	// TTL is how long the command output is reused. Zero runs the command only once, until refreshed.
+	TTL time.Duration
+}
+
+// CommandSource is a Source that runs a command and uses its trimmed standard output as the API key.
+type CommandSource struct {
+	command []string
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	key       string
+	fetchedAt time.Time
+}
+
+// NewCommandSource creates a new CommandSource running command, e.g. []string{"vault", "read", "-field=key", "secret/hs"}.
+func NewCommandSource(command []string, opt CommandSourceOptions) *CommandSource {
+	return &CommandSource{
+		command: command,
+		ttl:     opt.TTL,
+		now:     time.Now,
+	}
+}
+
+// APIKey returns the cached command output, running the command if needed.
+func (s *CommandSource) APIKey(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key != "" && (s.ttl == 0 || s.now().Sub(s.fetchedAt) < s.ttl) {
+		return s.key, nil
+	}
+
+	return s.run(ctx)
+}
+
+// Refresh runs the command again.
+func (s *CommandSource) Refresh(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.run(ctx)
+}
+
+// run executes the command and caches its output.
+func (s *CommandSource) run(ctx context.Context) (string, error) {
+	if len(s.command) == 0 {
+		return "", ErrCommandRequired
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...) //nolint:gosec // reason: running a user-configured command is the purpose of this source
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("command %s: %w: %s", s.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", fmt.Errorf("command %s: %w", s.command[0], ErrEmptyAPIKey)
+	}
+
+	s.key = key
+	s.fetchedAt = s.now()
+
+	return s.key, nil
+}
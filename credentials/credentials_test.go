@@ -0,0 +1,206 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticSource(t *testing.T) {
+	key, err := NewStaticSource("key").APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "key", key)
+
+	_, err = NewStaticSource("").APIKey(t.Context())
+	require.ErrorIs(t, err, ErrEmptyAPIKey)
+}
+
+func TestMutableSource(t *testing.T) {
+	s := NewMutableSource("old")
+	key, err := s.APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "old", key)
+
+	s.Set("new")
+	key, err = s.APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "new", key)
+
+	s.Set("")
+	_, err = s.APIKey(t.Context())
+	require.ErrorIs(t, err, ErrEmptyAPIKey)
+}
+
+func TestEnvSource(t *testing.T) {
+	env := map[string]string{"HS_KEY": " first\n"}
+	s := NewEnvSource("HS_KEY")
+	s.lookupEnv = func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	key, err := s.APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "first", key)
+
+	env["HS_KEY"] = "second"
+	key, err = s.Refresh(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "second", key)
+
+	delete(env, "HS_KEY")
+	_, err = s.APIKey(t.Context())
+	require.ErrorIs(t, err, ErrEmptyAPIKey)
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	now := time.Now()
+	s := NewFileSource(path, FileSourceOptions{PollInterval: time.Minute})
+	s.now = func() time.Time { return now }
+
+	key, err := s.APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "first", key)
+
+	require.NoError(t, os.WriteFile(path, []byte("second-key\n"), 0o600))
+
+	key, err = s.APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "first", key, "file is not checked again within the poll interval")
+
+	now = now.Add(time.Minute)
+	key, err = s.APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "second-key", key)
+
+	require.NoError(t, os.WriteFile(path, []byte("third-key\n"), 0o600))
+	key, err = s.Refresh(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "third-key", key, "refresh ignores the poll interval")
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+	_, err = s.Refresh(t.Context())
+	require.ErrorIs(t, err, ErrEmptyAPIKey)
+
+	_, err = NewFileSource(filepath.Join(t.TempDir(), "missing"), FileSourceOptions{}).APIKey(t.Context())
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileSource_CachedOnReadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	log := new(logger.MockLogger)
+	log.On("Warn", mock.Anything, "using cached API key", "error", mock.Anything).Return()
+
+	now := time.Now()
+	s := NewFileSource(path, FileSourceOptions{PollInterval: time.Minute, Logger: log})
+	s.now = func() time.Time { return now }
+
+	_, err := s.APIKey(t.Context())
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(path))
+	now = now.Add(time.Minute)
+	key, err := s.APIKey(t.Context())
+	require.NoError(t, err, "a missing file is served from the cache")
+	assert.Equal(t, "first", key)
+	log.AssertNumberOfCalls(t, "Warn", 1)
+
+	_, err = s.Refresh(t.Context())
+	require.ErrorIs(t, err, os.ErrNotExist, "refresh does not fall back to the cache")
+}
+
+func TestFileSource_CachedOnEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	log := new(logger.MockLogger)
+	log.On("Warn", mock.Anything, "using cached API key", "error", mock.Anything).Return()
+
+	now := time.Now()
+	s := NewFileSource(path, FileSourceOptions{PollInterval: time.Minute, Logger: log})
+	s.now = func() time.Time { return now }
+
+	_, err := s.APIKey(t.Context())
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	now = now.Add(time.Minute)
+	key, err := s.APIKey(t.Context())
+	require.NoError(t, err, "a truncated file is served from the cache")
+	assert.Equal(t, "first", key)
+	log.AssertNumberOfCalls(t, "Warn", 1)
+
+	require.NoError(t, os.WriteFile(path, []byte("second\n"), 0o600))
+	now = now.Add(time.Minute)
+	key, err = s.APIKey(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "second", key, "the rewritten file is read")
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(empty, nil, 0o600))
+	_, err = NewFileSource(empty, FileSourceOptions{Logger: log}).APIKey(t.Context())
+	require.ErrorIs(t, err, ErrEmptyAPIKey, "nothing is cached before the first read")
+}
+
+func TestCommandSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	counter := filepath.Join(t.TempDir(), "counter")
+	script := `echo x >> "$1"; echo "key-$(wc -l < "$1" | tr -d ' ')"`
+	command := []string{"sh", "-c", script, "sh", counter}
+
+	t.Run("cached until refreshed", func(t *testing.T) {
+		s := NewCommandSource(command, CommandSourceOptions{})
+
+		key, err := s.APIKey(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "key-1", key)
+
+		key, err = s.APIKey(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "key-1", key)
+
+		key, err = s.Refresh(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "key-2", key)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		now := time.Now()
+		s := NewCommandSource(command, CommandSourceOptions{TTL: time.Minute})
+		s.now = func() time.Time { return now }
+
+		first, err := s.APIKey(t.Context())
+		require.NoError(t, err)
+
+		now = now.Add(time.Minute)
+		second, err := s.APIKey(t.Context())
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewCommandSource(nil, CommandSourceOptions{}).APIKey(t.Context())
+		require.ErrorIs(t, err, ErrCommandRequired)
+
+		_, err = NewCommandSource([]string{"sh", "-c", "echo denied >&2; exit 1"}, CommandSourceOptions{}).APIKey(t.Context())
+		require.ErrorContains(t, err, "denied")
+
+		_, err = NewCommandSource([]string{"true"}, CommandSourceOptions{}).APIKey(t.Context())
+		require.ErrorIs(t, err, ErrEmptyAPIKey)
+	})
+}
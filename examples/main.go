@@ -14,7 +14,7 @@ import (
 	"github.com/hibare/headscale-client-go/v1/nodes"
 )
 
-var hsClientNewClient = hsClient.NewClient
+var hsClientNewFromConfig = hsClient.NewFromConfig
 var stdout = os.Stdout
 
 func listNodes(ctx context.Context, client hsClient.ClientInterface) (string, error) {
@@ -31,16 +31,19 @@ func listNodes(ctx context.Context, client hsClient.ClientInterface) (string, er
 
 func main() {
 	slog.SetLogLoggerLevel(slog.LevelDebug)
-	clientURL := os.Getenv("HS_SERVER_URL")
-	clientToken := os.Getenv("HS_SERVER_TOKEN")
-
-	client, err := hsClientNewClient(clientURL, clientToken, hsClient.ClientOptions{
+	// Reads HEADSCALE_CLI_ADDRESS, HEADSCALE_CLI_API_KEY, etc., and the file named by HEADSCALE_CONFIG.
+	cfg, err := hsClient.LoadConfig(hsClient.ConfigOptions{
 		LogLevel: utils.ToPtr(logger.LevelDebug), // Change to LevelInfo for less verbose output
 	})
 	if err != nil {
 		panic(err)
 	}
 
+	client, err := hsClientNewFromConfig(cfg, hsClient.ClientOptions{})
+	if err != nil {
+		panic(err)
+	}
+
 	_, _ = fmt.Fprintln(stdout, "Listing Nodes")
 	output, err := listNodes(context.Background(), client)
 	if err != nil {
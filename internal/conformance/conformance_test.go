@@ -0,0 +1,294 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/apikeys"
+	"github.com/hibare/headscale-client-go/v1/debug"
+	"github.com/hibare/headscale-client-go/v1/nodes"
+	"github.com/hibare/headscale-client-go/v1/policy"
+	"github.com/hibare/headscale-client-go/v1/preauthkeys"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resourceCalls invokes every method of the v1 resources that talks to the /api/v1 endpoints.
+// Add new methods here so that they are checked against the specification.
+var resourceCalls = map[string]func(ctx context.Context, r requests.RequestInterface) error{
+	"apikeys.List": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := apikeys.NewAPIKeyResource(r).List(ctx)
+		return err
+	},
+	"apikeys.Create": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := apikeys.NewAPIKeyResource(r).Create(ctx, apikeys.CreateAPIKeyRequest{Expiration: time.Now()})
+		return err
+	},
+	"apikeys.Expire": func(ctx context.Context, r requests.RequestInterface) error {
+		return apikeys.NewAPIKeyResource(r).Expire(ctx, "prefix")
+	},
+	"apikeys.ExpireByID": func(ctx context.Context, r requests.RequestInterface) error {
+		return apikeys.NewAPIKeyResource(r).ExpireByID(ctx, "1")
+	},
+	"apikeys.Delete": func(ctx context.Context, r requests.RequestInterface) error {
+		return apikeys.NewAPIKeyResource(r).Delete(ctx, "prefix")
+	},
+	"nodes.List": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).List(ctx, nodes.NodeListFilter{User: "alice"})
+		return err
+	},
+	"nodes.Query": func(ctx context.Context, r requests.RequestInterface) error {
+		q := nodes.And(nodes.User("alice"), nodes.Online())
+		for _, err := range nodes.NewNodeResource(r).Query(ctx, q) {
+			return err
+		}
+		return nil
+	},
+	"nodes.Get": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).Get(ctx, "1")
+		return err
+	},
+	"nodes.Register": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).Register(ctx, "alice", "nodekey")
+		return err
+	},
+	// The recorder lists no users, so only the user list is sent, and releases before v0.25.0 reject
+	// the registration ID up front.
+	"nodes.RegisterFrom": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).RegisterFrom(ctx, "https://hs.example.com/register/AbCdEfGhIjKlMnOpQrStUvWx",
+			nodes.RegisterOptions{User: "alice"})
+		if errors.Is(err, nodes.ErrInvalidRegistrationKey) {
+			return nil
+		}
+		return ignoreNotFound(err)
+	},
+	"nodes.Delete": func(ctx context.Context, r requests.RequestInterface) error {
+		return nodes.NewNodeResource(r).Delete(ctx, "1")
+	},
+	"nodes.Expire": func(ctx context.Context, r requests.RequestInterface) error {
+		return nodes.NewNodeResource(r).Expire(ctx, "1")
+	},
+	"nodes.SetExpiry": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).SetExpiry(ctx, "1", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+		return err
+	},
+	"nodes.Rename": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).Rename(ctx, "1", "web")
+		return err
+	},
+	"nodes.ApproveRoutes": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).ApproveRoutes(ctx, "1", []string{"10.0.0.0/24"})
+		return err
+	},
+	"nodes.SetTags": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).SetTags(ctx, "1", []string{"tag:web"})
+		return err
+	},
+	"nodes.AddTags": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).AddTags(ctx, "1", []string{"tag:web"})
+		if errors.Is(err, nodes.ErrTagConflict) {
+			// The recorder answers with an empty node, which never has the tags sent.
+			return nil
+		}
+		return err
+	},
+	"nodes.RemoveTags": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).RemoveTags(ctx, "1", []string{"tag:web"})
+		return err
+	},
+	// Reassign looks the user up when the server needs the other form of it. The recorder lists no
+	// users, so of the two calls below, only the one passing the form the release takes is sent.
+	"nodes.Reassign(id)": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).Reassign(ctx, "1", "2")
+		return ignoreNotFound(err)
+	},
+	"nodes.Reassign(name)": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).Reassign(ctx, "1", "bob")
+		return ignoreNotFound(err)
+	},
+	"nodes.ExitNodes": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).ExitNodes(ctx, nodes.User("alice"))
+		return err
+	},
+	// The recorder answers with an empty node, which advertises no exit routes and has none approved,
+	// so only the node is read.
+	"nodes.ApproveExitNode": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).ApproveExitNode(ctx, "1")
+		if errors.Is(err, nodes.ErrNotExitNode) {
+			return nil
+		}
+		return err
+	},
+	"nodes.RevokeExitNode": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).RevokeExitNode(ctx, "1")
+		return err
+	},
+	"nodes.BackfillIPs": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := nodes.NewNodeResource(r).BackfillIPs(ctx, true)
+		return err
+	},
+	"debug.CreateNode": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := debug.NewDebugResource(r).CreateNode(ctx, debug.CreateNodeRequest{
+			User: "alice", Key: debug.NewRegistrationKey(requests.ServerVersion(r)), Name: "debug-1", Routes: []string{"10.0.0.0/24"},
+		})
+		return err
+	},
+	"policy.Get": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := policy.NewPolicyResource(r).Get(ctx)
+		return err
+	},
+	"policy.Update": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := policy.NewPolicyResource(r).Update(ctx, "{}")
+		return err
+	},
+	"preauthkeys.List": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := preauthkeys.NewPreAuthKeyResource(r).List(ctx)
+		return err
+	},
+	"preauthkeys.Create": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := preauthkeys.NewPreAuthKeyResource(r).Create(ctx, preauthkeys.CreatePreAuthKeyRequest{User: "1"})
+		return err
+	},
+	"preauthkeys.Expire": func(ctx context.Context, r requests.RequestInterface) error {
+		return preauthkeys.NewPreAuthKeyResource(r).Expire(ctx, "1")
+	},
+	"preauthkeys.Delete": func(ctx context.Context, r requests.RequestInterface) error {
+		return preauthkeys.NewPreAuthKeyResource(r).Delete(ctx, "1")
+	},
+	"users.List": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := users.NewUserResource(r).List(ctx, users.UserListFilter{ID: "1", Name: "alice", Email: "a@example.com"})
+		return err
+	},
+	"users.Create": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := users.NewUserResource(r).Create(ctx, users.CreateUserRequest{Name: "alice"})
+		return err
+	},
+	"users.Delete": func(ctx context.Context, r requests.RequestInterface) error {
+		return users.NewUserResource(r).Delete(ctx, "1")
+	},
+	"users.Rename": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := users.NewUserResource(r).Rename(ctx, "1", "bob")
+		return err
+	},
+	"users.Lookup": func(ctx context.Context, r requests.RequestInterface) error {
+		_, err := users.NewUserResource(r).Lookup(ctx, "alice")
+		return ignoreNotFound(err)
+	},
+}
+
+// ignoreNotFound drops errors of lookups in the empty lists the recorder answers with.
+func ignoreNotFound(err error) error {
+	if errors.Is(err, iterate.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// TestConformance checks the requests of every resource method against the specification of each
+// supported release. Response types are checked against the newest release only, since responses of
+// older releases are converted by the compat package. Responses of endpoints the newest release
+// dropped are not checked.
+func TestConformance(t *testing.T) {
+	releases := Releases()
+	require.NotEmpty(t, releases)
+
+	latest, err := LoadSpec(releases[len(releases)-1])
+	require.NoError(t, err)
+
+	for _, release := range releases {
+		t.Run(release.String(), func(t *testing.T) {
+			spec, err := LoadSpec(release)
+			require.NoError(t, err)
+
+			rec := NewRecorder(release)
+			for _, name := range slices.Sorted(maps.Keys(resourceCalls)) {
+				rec.Start(name)
+				err := resourceCalls[name](t.Context(), rec)
+				if errors.Is(err, versions.ErrUnsupportedByServer) {
+					continue
+				}
+				require.NoError(t, err, name)
+			}
+
+			var mismatches []Mismatch
+			for _, c := range rec.Calls() {
+				mismatches = append(mismatches, spec.VerifyRequest(c)...)
+				if _, op, ok := latest.route(c.Method, c.Path); ok && op != nil {
+					mismatches = append(mismatches, latest.VerifyResponse(c)...)
+				}
+			}
+			if len(mismatches) > 0 {
+				t.Errorf("requests do not match the Headscale %s specification:\n%s", release, Table(mismatches))
+			}
+		})
+	}
+}
+
+func TestSpec_Verify(t *testing.T) {
+	spec, err := LoadSpec(versions.ServerVersion{Minor: 28})
+	require.NoError(t, err)
+
+	type renameBody struct {
+		NewName string `json:"newName"`
+	}
+
+	tests := []struct {
+		name  string
+		call  Call
+		where []string
+	}{
+		{
+			name: "matching",
+			call: Call{Method: "POST", Path: "/api/v1/node/backfillips", Query: []string{"confirmed"}, Response: &nodes.BackfillIPsResponse{}},
+		},
+		{
+			name:  "wrong path layout",
+			call:  Call{Method: "POST", Path: "/api/v1/node/1/web/rename"},
+			where: []string{"path"},
+		},
+		{
+			name:  "wrong method",
+			call:  Call{Method: "PUT", Path: "/api/v1/node/1"},
+			where: []string{"method"},
+		},
+		{
+			name:  "unknown query parameter",
+			call:  Call{Method: "POST", Path: "/api/v1/node/backfillips", Query: []string{"confirm"}},
+			where: []string{"query"},
+		},
+		{
+			name:  "unknown body field",
+			call:  Call{Method: "POST", Path: "/api/v1/user", Body: renameBody{}},
+			where: []string{"body.newName"},
+		},
+		{
+			name:  "wrong response type",
+			call:  Call{Method: "GET", Path: "/api/v1/user", Response: &nodes.NodesResponse{}},
+			where: []string{"response.nodes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var where []string
+			for _, m := range spec.Verify(tt.call) {
+				where = append(where, m.Where)
+			}
+			assert.Equal(t, tt.where, where)
+		})
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := Table([]Mismatch{{Call: "nodes.Rename", Method: "POST", Path: "/api/v1/node/1", Where: "path", Problem: "no matching path"}})
+	assert.Equal(t, "CALL          METHOD  PATH            WHERE  PROBLEM\n"+
+		"nodes.Rename  POST    /api/v1/node/1  path   no matching path\n", table)
+}
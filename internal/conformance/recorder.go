@@ -0,0 +1,82 @@
+package conformance
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+// recorderBaseURL is the base URL of the requests built by a Recorder; nothing is sent to it.
+const recorderBaseURL = "http://headscale.invalid"
+
+// Recorder is a requests.RequestInterface that records calls instead of sending them.
+// URLs and requests are built by a real requests.Request, so the recorded routes are those
+// the client sends.
+type Recorder struct {
+	requests.RequestInterface
+
+	name    string
+	pending Call
+	calls   []Call
+}
+
+// NewRecorder returns a Recorder for a server running release.
+func NewRecorder(release versions.ServerVersion) *Recorder {
+	base, _ := url.Parse(recorderBaseURL)
+	return &Recorder{
+		RequestInterface: requests.NewRequest(base, "key", versions.APIVersionV1, requests.RequestConfig{ServerVersion: release}),
+	}
+}
+
+// Start names the calls recorded from now on, e.g. "nodes.Rename".
+func (r *Recorder) Start(name string) {
+	r.name = name
+}
+
+// Calls returns the recorded calls.
+func (r *Recorder) Calls() []Call {
+	return r.calls
+}
+
+// BuildRequest builds the request and remembers its body for the following Do.
+func (r *Recorder) BuildRequest(ctx context.Context, method string, uri *url.URL, opt requests.RequestOptions) (*http.Request, error) {
+	req, err := r.RequestInterface.BuildRequest(ctx, method, uri, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	r.pending = Call{Name: r.name, Method: req.Method, Path: req.URL.Path, Body: opt.Body}
+	for name := range req.URL.Query() {
+		r.pending.Query = append(r.pending.Query, name)
+	}
+	slices.Sort(r.pending.Query)
+	return req, nil
+}
+
+// Do records the call without sending it.
+func (r *Recorder) Do(_ context.Context, _ *http.Request, v any) error {
+	call := r.pending
+	call.Response = v
+	r.calls = append(r.calls, call)
+	r.pending = Call{}
+	return nil
+}
+
+// BuildServerURL builds the URL of a server path outside the versioned API.
+func (r *Recorder) BuildServerURL(pathParts ...any) *url.URL {
+	return requests.BuildServerURL(r.RequestInterface, pathParts...)
+}
+
+// ServerVersion returns the release the recorder was created for.
+func (r *Recorder) ServerVersion() versions.ServerVersion {
+	return requests.ServerVersion(r.RequestInterface)
+}
+
+// SetServerVersion replaces the release the recorder was created for.
+func (r *Recorder) SetServerVersion(v versions.ServerVersion) {
+	requests.SetServerVersion(r.RequestInterface, v)
+}
@@ -0,0 +1,165 @@
+// Package conformance checks the requests and response types of the v1 resources against the
+// OpenAPI specification published with each supported Headscale release.
+//
+// The specifications are vendored unmodified from gen/openapiv2 of the Headscale repository.
+// The package is only used by tests.
+package conformance
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+//go:embed specs/*.swagger.json
+var specFiles embed.FS
+
+// Vendored specifications are named specPrefix + release + specSuffix, e.g. headscale-v0.28.0.swagger.json.
+const (
+	specPrefix = "headscale-"
+	specSuffix = ".swagger.json"
+)
+
+// refPrefix is the prefix of schema references within a specification.
+const refPrefix = "#/definitions/"
+
+// Releases returns the Headscale releases with a vendored specification, oldest first.
+func Releases() []versions.ServerVersion {
+	entries, err := specFiles.ReadDir("specs")
+	if err != nil {
+		return nil
+	}
+
+	var out []versions.ServerVersion
+	for _, e := range entries {
+		release := strings.TrimSuffix(strings.TrimPrefix(e.Name(), specPrefix), specSuffix)
+		v, err := versions.ParseServerVersion(release)
+		if err != nil {
+			continue
+		}
+		out = append(out, v)
+	}
+
+	slices.SortFunc(out, versions.ServerVersion.Compare)
+	return out
+}
+
+// Spec is the Swagger 2.0 specification of the Headscale API of one release.
+type Spec struct {
+	Release     versions.ServerVersion          `json:"-"`
+	Paths       map[string]map[string]Operation `json:"paths"`
+	Definitions map[string]*Schema              `json:"definitions"`
+}
+
+// Operation is a method on a path.
+type Operation struct {
+	Parameters []Parameter         `json:"parameters"`
+	Responses  map[string]Response `json:"responses"`
+}
+
+// Parameter is a path, query or body parameter of an operation.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Type     string  `json:"type"`
+	Format   string  `json:"format"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Response is the response of an operation for one status code.
+type Response struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema describes a JSON value.
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+}
+
+// LoadSpec returns the vendored specification of release.
+func LoadSpec(release versions.ServerVersion) (*Spec, error) {
+	data, err := specFiles.ReadFile(path.Join("specs", specPrefix+release.String()+specSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("no specification for Headscale %s: %w", release, err)
+	}
+
+	spec := &Spec{Release: release}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("parse specification for Headscale %s: %w", release, err)
+	}
+	return spec, nil
+}
+
+// resolve follows the reference of s, if any.
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.Definitions[strings.TrimPrefix(schema.Ref, refPrefix)]
+	}
+	return schema
+}
+
+// route finds the path template matching the request path p, and its operation for method.
+// Templates providing method are preferred, then those with more literal segments, so
+// /node/register wins over /node/{nodeId}. ok is false if no template matches p.
+func (s *Spec) route(method, p string) (tmpl string, op *Operation, ok bool) {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+
+	bestScore := -1
+	for _, candidate := range slices.Sorted(maps.Keys(s.Paths)) {
+		literals, matches := matchTemplate(candidate, segments)
+		if !matches {
+			continue
+		}
+
+		score := literals
+		candidateOp, hasMethod := s.Paths[candidate][strings.ToLower(method)]
+		if hasMethod {
+			score += len(segments) + 1
+		}
+		if score > bestScore {
+			bestScore, tmpl, ok = score, candidate, true
+			op = nil
+			if hasMethod {
+				op = &candidateOp
+			}
+		}
+	}
+
+	return tmpl, op, ok
+}
+
+// matchTemplate reports whether the path template tmpl matches segments, and how many of its
+// segments are literals.
+func matchTemplate(tmpl string, segments []string) (int, bool) {
+	parts := strings.Split(strings.Trim(tmpl, "/"), "/")
+	if len(parts) != len(segments) {
+		return 0, false
+	}
+
+	literals := 0
+	for i, part := range parts {
+		switch {
+		case isPathParam(part):
+		case part == segments[i]:
+			literals++
+		default:
+			return 0, false
+		}
+	}
+	return literals, true
+}
+
+func isPathParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
@@ -0,0 +1,255 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Call is a request made by a resource method, as recorded by a Recorder.
+type Call struct {
+	// Name identifies the resource method, e.g. "nodes.Rename".
+	Name string
+
+	Method string
+	Path   string
+	Query  []string
+
+	// Body is the value passed as the request body, or nil.
+	Body any
+
+	// Response is the value the response is decoded into, or nil.
+	Response any
+}
+
+// Mismatch is a difference between a call and the specification.
+type Mismatch struct {
+	Call    string
+	Method  string
+	Path    string
+	Where   string
+	Problem string
+}
+
+// Verify checks c against s with VerifyRequest and VerifyResponse.
+func (s *Spec) Verify(c Call) []Mismatch {
+	out := s.VerifyRequest(c)
+	if len(out) > 0 && (out[0].Where == wherePath || out[0].Where == whereMethod) {
+		return out
+	}
+	return append(out, s.VerifyResponse(c)...)
+}
+
+// Locations of route mismatches.
+const (
+	wherePath   = "path"
+	whereMethod = "method"
+)
+
+// VerifyRequest checks the route, query parameters and request body of c against s. Body fields
+// that are omitted because they are empty are not checked.
+func (s *Spec) VerifyRequest(c Call) []Mismatch {
+	var out []Mismatch
+	report := c.reporter(&out)
+
+	tmpl, op, ok := s.route(c.Method, c.Path)
+	switch {
+	case !ok:
+		report(wherePath, "no matching path")
+		return out
+	case op == nil:
+		report(whereMethod, "%s not allowed on %s", c.Method, tmpl)
+		return out
+	}
+
+	for _, name := range c.Query {
+		if !slices.ContainsFunc(op.Parameters, func(p Parameter) bool { return p.In == "query" && p.Name == name }) {
+			report("query", "parameter %q not in spec", name)
+		}
+	}
+	for _, p := range op.Parameters {
+		if p.In == "query" && p.Required && !slices.Contains(c.Query, p.Name) {
+			report("query", "required parameter %q not sent", p.Name)
+		}
+	}
+
+	bodyIdx := slices.IndexFunc(op.Parameters, func(p Parameter) bool { return p.In == "body" })
+	switch {
+	case c.Body != nil && bodyIdx < 0:
+		report("body", "request body not in spec")
+	case c.Body != nil:
+		s.compare(reflect.ValueOf(c.Body), reflect.TypeOf(c.Body), op.Parameters[bodyIdx].Schema, "body", report)
+	}
+
+	return out
+}
+
+// VerifyResponse checks the type c decodes the response into against the response schema in s.
+func (s *Spec) VerifyResponse(c Call) []Mismatch {
+	var out []Mismatch
+	if c.Response == nil {
+		return out
+	}
+	report := c.reporter(&out)
+
+	_, op, ok := s.route(c.Method, c.Path)
+	if !ok || op == nil {
+		report(wherePath, "no operation %s %s", c.Method, c.Path)
+		return out
+	}
+
+	resp, ok := op.Responses["200"]
+	if !ok || resp.Schema == nil {
+		report("response", "no response schema")
+		return out
+	}
+	s.compare(reflect.Value{}, reflect.TypeOf(c.Response), resp.Schema, "response", report)
+	return out
+}
+
+type reportFunc func(where, format string, args ...any)
+
+// reporter returns a function appending mismatches of c to out.
+func (c Call) reporter(out *[]Mismatch) reportFunc {
+	return func(where, format string, args ...any) {
+		*out = append(*out, Mismatch{Call: c.Name, Method: c.Method, Path: c.Path, Where: where, Problem: fmt.Sprintf(format, args...)})
+	}
+}
+
+var (
+	timeType    = reflect.TypeFor[time.Time]()
+	rawJSONType = reflect.TypeFor[json.RawMessage]()
+)
+
+// compare checks the Go type t against schema, reporting differences at path where. If v is
+// valid, it is the value of type t, and empty fields tagged omitempty are skipped.
+func (s *Spec) compare(v reflect.Value, t reflect.Type, schema *Schema, where string, report reportFunc) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if v.IsValid() {
+			v = v.Elem()
+		}
+	}
+	schema = s.resolve(schema)
+	if schema == nil {
+		report(where, "unresolvable schema reference")
+		return
+	}
+
+	switch {
+	case t == timeType:
+		if schema.Type != "string" || schema.Format != "date-time" {
+			report(where, "time.Time, spec %s", describe(schema))
+		}
+	case t == rawJSONType || t.Kind() == reflect.Interface:
+	case t.Kind() == reflect.Struct:
+		if schema.Type != "object" && schema.Properties == nil {
+			report(where, "struct, spec %s", describe(schema))
+			return
+		}
+		s.compareFields(v, t, schema, where, report)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if schema.Type != "array" {
+			report(where, "%s, spec %s", t.Kind(), describe(schema))
+			return
+		}
+		s.compare(reflect.Value{}, t.Elem(), schema.Items, where+"[]", report)
+	case t.Kind() == reflect.Map:
+		if schema.Type != "object" {
+			report(where, "map, spec %s", describe(schema))
+		}
+	default:
+		if want := jsonType(t.Kind()); want != schema.Type {
+			report(where, "%s, spec %s", t, describe(schema))
+		}
+	}
+}
+
+// compareFields checks the fields of struct type t against the properties of schema.
+func (s *Spec) compareFields(v reflect.Value, t reflect.Type, schema *Schema, where string, report reportFunc) {
+	for _, f := range jsonFields(t) {
+		var fv reflect.Value
+		if v.IsValid() {
+			fv = v.FieldByIndex(f.Index)
+			if f.omitEmpty && fv.IsZero() {
+				continue
+			}
+		}
+
+		prop, ok := schema.Properties[f.name]
+		if !ok {
+			report(where+"."+f.name, "field not in spec")
+			continue
+		}
+		s.compare(fv, f.Type, prop, where+"."+f.name, report)
+	}
+}
+
+// jsonType returns the JSON schema type encoding/json produces for a scalar kind.
+func jsonType(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func describe(schema *Schema) string {
+	if schema.Format != "" {
+		return schema.Type + "/" + schema.Format
+	}
+	if schema.Type == "" {
+		return "object"
+	}
+	return schema.Type
+}
+
+// jsonField is a struct field encoded by encoding/json.
+type jsonField struct {
+	reflect.StructField
+
+	name      string
+	omitEmpty bool
+}
+
+// jsonFields returns the fields of struct type t that encoding/json encodes.
+func jsonFields(t reflect.Type) []jsonField {
+	var out []jsonField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, jsonField{StructField: f, name: name, omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty")})
+	}
+	return out
+}
+
+// Table formats mismatches as an aligned table, one mismatch per line.
+func Table(mismatches []Mismatch) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CALL\tMETHOD\tPATH\tWHERE\tPROBLEM")
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Call, m.Method, m.Path, m.Where, m.Problem)
+	}
+	_ = w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,148 @@
+// Package iterate provides iterators over the results of the Headscale list endpoints, and generic
+// helpers to filter, sort, chunk and search them.
+//
+// Resources expose their lists as iter.Seq2[T, error] through their All methods. An iterator yields
+// each item with a nil error; if listing fails, it yields a single zero item with the error and stops.
+// The iterators keep working unchanged should Headscale add server-side pagination.
+package iterate
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// ErrNotFound is wrapped by NotFoundError.
+var ErrNotFound = errors.New("not found")
+
+// NotFoundError is returned by FindOne when no item matches.
+type NotFoundError struct {
+	// Type is the Go type of the items searched, e.g. "nodes.Node".
+	Type string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Type + " " + ErrNotFound.Error()
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+// Fetch returns an iterator over the items returned by list. list is called each time iteration starts.
+func Fetch[T any](list func() ([]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		items, err := list()
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// FromSlice returns an iterator over items that never fails.
+func FromSlice[T any](items []T) iter.Seq2[T, error] {
+	return Fetch(func() ([]T, error) { return items, nil })
+}
+
+// Filter returns an iterator over the items of seq for which keep returns true. Errors are passed through.
+func Filter[T any](seq iter.Seq2[T, error], keep func(T) bool) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for item, err := range seq {
+			if err != nil || keep(item) {
+				if !yield(item, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Sort returns an iterator over the items of seq, sorted by cmp. The items are collected first;
+// if seq fails, the sorted iterator yields only the error.
+func Sort[T any](seq iter.Seq2[T, error], cmp func(a, b T) int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		items, err := Collect(seq)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		slices.SortStableFunc(items, cmp)
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk returns an iterator over consecutive chunks of up to size items of seq. An error is yielded
+// after the items read before it, with a nil chunk. Chunk panics if size is less than 1.
+func Chunk[T any](seq iter.Seq2[T, error], size int) iter.Seq2[[]T, error] {
+	if size < 1 {
+		panic(fmt.Sprintf("iterate: invalid chunk size %d", size))
+	}
+
+	return func(yield func([]T, error) bool) {
+		chunk := make([]T, 0, size)
+		for item, err := range seq {
+			if err != nil {
+				if len(chunk) > 0 && !yield(chunk, nil) {
+					return
+				}
+				yield(nil, err)
+				return
+			}
+
+			chunk = append(chunk, item)
+			if len(chunk) == size {
+				if !yield(chunk, nil) {
+					return
+				}
+				chunk = make([]T, 0, size)
+			}
+		}
+
+		if len(chunk) > 0 {
+			yield(chunk, nil)
+		}
+	}
+}
+
+// Collect returns the items of seq as a slice, stopping at the first error.
+func Collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Find returns the items of seq for which match returns true. The result is empty, not an error,
+// if nothing matches.
+func Find[T any](seq iter.Seq2[T, error], match func(T) bool) ([]T, error) {
+	return Collect(Filter(seq, match))
+}
+
+// FindOne returns the first item of seq for which match returns true, or a *NotFoundError.
+func FindOne[T any](seq iter.Seq2[T, error], match func(T) bool) (T, error) {
+	for item, err := range Filter(seq, match) {
+		return item, err
+	}
+
+	var zero T
+	return zero, &NotFoundError{Type: fmt.Sprintf("%T", zero)}
+}
@@ -0,0 +1,102 @@
+package iterate
+
+import (
+	"cmp"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errList = errors.New("list failed")
+
+func failing() ([]int, error) {
+	return nil, errList
+}
+
+func TestFetch(t *testing.T) {
+	calls := 0
+	seq := Fetch(func() ([]int, error) {
+		calls++
+		return []int{1, 2, 3}, nil
+	})
+
+	items, err := Collect(seq)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, items)
+
+	for range seq {
+		break
+	}
+	assert.Equal(t, 2, calls, "list is called each time iteration starts")
+
+	_, err = Collect(Fetch(failing))
+	require.ErrorIs(t, err, errList)
+}
+
+func TestFilter(t *testing.T) {
+	items, err := Collect(Filter(FromSlice([]int{1, 2, 3, 4}), func(i int) bool { return i%2 == 0 }))
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 4}, items)
+
+	_, err = Collect(Filter(Fetch(failing), func(int) bool { return false }))
+	require.ErrorIs(t, err, errList, "errors are passed through")
+}
+
+func TestSort(t *testing.T) {
+	items, err := Collect(Sort(FromSlice([]int{3, 1, 2}), cmp.Compare[int]))
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, items)
+
+	_, err = Collect(Sort(Fetch(failing), cmp.Compare[int]))
+	require.ErrorIs(t, err, errList)
+}
+
+func TestChunk(t *testing.T) {
+	var chunks [][]int
+	for chunk, err := range Chunk(FromSlice([]int{1, 2, 3, 4, 5}), 2) {
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, chunks)
+
+	partial := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errList)
+	}
+	var errs []error
+	chunks = nil
+	for chunk, err := range Chunk(partial, 2) {
+		chunks = append(chunks, chunk)
+		errs = append(errs, err)
+	}
+	assert.Equal(t, [][]int{{1}, nil}, chunks, "items read before an error are yielded first")
+	assert.Equal(t, []error{nil, errList}, errs)
+
+	assert.Panics(t, func() { Chunk(FromSlice([]int{1}), 0) })
+}
+
+func TestFind(t *testing.T) {
+	items, err := Find(FromSlice([]int{1, 2, 3}), func(i int) bool { return i > 1 })
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, items)
+
+	items, err = Find(FromSlice([]int{1, 2, 3}), func(i int) bool { return i > 3 })
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestFindOne(t *testing.T) {
+	item, err := FindOne(FromSlice([]int{1, 2, 3}), func(i int) bool { return i > 1 })
+	require.NoError(t, err)
+	assert.Equal(t, 2, item)
+
+	_, err = FindOne(FromSlice([]int{1, 2, 3}), func(i int) bool { return i > 3 })
+	require.ErrorIs(t, err, ErrNotFound)
+	var notFound *NotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "int not found", err.Error())
+
+	_, err = FindOne(Fetch(failing), func(int) bool { return true })
+	require.ErrorIs(t, err, errList)
+}
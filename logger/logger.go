@@ -3,8 +3,11 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 )
 
 // LogLevel represents the level of logging.
@@ -24,6 +27,49 @@ const (
 	LevelError
 )
 
+// String returns the lower-case name of the level, as accepted by ParseLevel.
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("LogLevel(%d)", int(l))
+	}
+}
+
+// ParseLevel parses a level name ("debug", "info", "warn" or "warning", "error"), case-insensitively.
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("invalid log level: %q", s)
+	}
+}
+
+// Format represents the output format of the DefaultLogger.
+type Format int
+
+const (
+	// FormatJSON writes one JSON object per log entry.
+	FormatJSON Format = iota
+
+	// FormatText writes logfmt-style key=value log entries.
+	FormatText
+)
+
 // Logger is an interface for logging messages.
 type Logger interface {
 	Info(ctx context.Context, msg string, keysAndValues ...any)
@@ -37,14 +83,53 @@ type DefaultLogger struct {
 	logger *slog.Logger
 }
 
-// NewDefaultLogger creates a new DefaultLogger.
+// Options contains options for creating a DefaultLogger.
+type Options struct {
+	// Level is the minimum level written. Defaults to LevelDebug (the zero value).
+	Level LogLevel
+
+	// Writer receives the log entries. Defaults to os.Stdout.
+	Writer io.Writer
+
+	// Format is the output format. Defaults to FormatJSON.
+	Format Format
+}
+
+// NewDefaultLogger creates a new DefaultLogger writing JSON to stdout.
 func NewDefaultLogger(level LogLevel) *DefaultLogger {
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: mapLogLevel(level)})
+	return New(Options{Level: level})
+}
+
+// New creates a new DefaultLogger with the given options.
+func New(opt Options) *DefaultLogger {
+	if opt.Writer == nil {
+		opt.Writer = os.Stdout
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: mapLogLevel(opt.Level)}
+
+	var handler slog.Handler
+	switch opt.Format {
+	case FormatText:
+		handler = slog.NewTextHandler(opt.Writer, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(opt.Writer, handlerOpts)
+	default:
+		handler = slog.NewJSONHandler(opt.Writer, handlerOpts)
+	}
+
 	return &DefaultLogger{
 		logger: slog.New(handler),
 	}
 }
 
+// With returns a DefaultLogger that adds the given key/value pairs to every log entry.
+func (l *DefaultLogger) With(keysAndValues ...any) *DefaultLogger {
+	return &DefaultLogger{
+		logger: l.logger.With(keysAndValues...),
+	}
+}
+
 // mapLogLevel maps CustomLogLevel to slog.Level.
 func mapLogLevel(level LogLevel) slog.Level {
 	switch level {
@@ -61,22 +146,22 @@ func mapLogLevel(level LogLevel) slog.Level {
 	}
 }
 
-// Info writes an info-level log entry to stdout in JSON format.
+// Info writes an info-level log entry.
 func (l *DefaultLogger) Info(ctx context.Context, msg string, keysAndValues ...any) {
 	l.logger.InfoContext(ctx, msg, keysAndValues...)
 }
 
-// Error writes an error-level log entry to stdout in JSON format.
+// Error writes an error-level log entry.
 func (l *DefaultLogger) Error(ctx context.Context, msg string, keysAndValues ...any) {
 	l.logger.ErrorContext(ctx, msg, keysAndValues...)
 }
 
-// Warn writes a warning-level log entry to stdout in JSON format.
+// Warn writes a warning-level log entry.
 func (l *DefaultLogger) Warn(ctx context.Context, msg string, keysAndValues ...any) {
 	l.logger.WarnContext(ctx, msg, keysAndValues...)
 }
 
-// Debug writes a debug-level log entry to stdout in JSON format.
+// Debug writes a debug-level log entry.
 func (l *DefaultLogger) Debug(ctx context.Context, msg string, keysAndValues ...any) {
 	l.logger.DebugContext(ctx, msg, keysAndValues...)
 }
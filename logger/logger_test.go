@@ -130,6 +130,92 @@ func TestNewDefaultLogger(t *testing.T) {
 	}
 }
 
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected LogLevel
+		wantErr  bool
+	}{
+		{in: "debug", expected: LevelDebug},
+		{in: "INFO", expected: LevelInfo},
+		{in: "warn", expected: LevelWarn},
+		{in: " warning ", expected: LevelWarn},
+		{in: "error", expected: LevelError},
+		{in: "verbose", expected: LevelInfo, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			level, err := ParseLevel(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, level)
+		})
+	}
+}
+
+func TestLogLevel_String(t *testing.T) {
+	for _, level := range []LogLevel{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		parsed, err := ParseLevel(level.String())
+		require.NoError(t, err)
+		assert.Equal(t, level, parsed)
+	}
+	assert.Equal(t, "LogLevel(99)", LogLevel(99).String())
+}
+
+func TestNew(t *testing.T) {
+	t.Run("json writer", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(Options{Level: LevelInfo, Writer: &buf})
+		l.Debug(t.Context(), "filtered")
+		l.Info(t.Context(), "hello", "k", "v")
+
+		var parsed map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &parsed))
+		assert.Equal(t, "hello", parsed["msg"])
+		assert.Equal(t, "v", parsed["k"])
+	})
+
+	t.Run("text format", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(Options{Level: LevelDebug, Writer: &buf, Format: FormatText})
+		l.Warn(t.Context(), "careful", "k", "v")
+
+		out := buf.String()
+		assert.Contains(t, out, "level=WARN")
+		assert.Contains(t, out, "msg=careful")
+		assert.Contains(t, out, "k=v")
+	})
+
+	t.Run("defaults to stdout", func(t *testing.T) {
+		output := captureStdout(func() {
+			New(Options{}).Debug(t.Context(), "to stdout")
+		})
+		assert.Contains(t, output, `"msg":"to stdout"`)
+	})
+}
+
+func TestDefaultLogger_With(t *testing.T) {
+	var buf bytes.Buffer
+	base := New(Options{Writer: &buf})
+	scoped := base.With("component", "nodes")
+
+	scoped.Info(t.Context(), "scoped")
+	base.Info(t.Context(), "unscoped")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var first, second map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	assert.Equal(t, "nodes", first["component"])
+	assert.NotContains(t, second, "component")
+}
+
 func TestMockLogger(t *testing.T) {
 	mockLogger := new(MockLogger)
 	ctx := t.Context()
@@ -0,0 +1,73 @@
+// Package logrlogger adapts a logr.Logger to the headscale-client-go logger.Logger interface.
+package logrlogger
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/hibare/headscale-client-go/logger"
+)
+
+const (
+	// levelKey is the key used to mark warnings, which logr has no level for.
+	levelKey = "severity"
+
+	// errorKey is the key whose error value is passed to logr's Error method.
+	errorKey = "error"
+)
+
+// Logger is a logger.Logger that writes to a logr.Logger.
+//
+// Info and warnings are written at verbosity 0, debug entries at verbosity 1, and errors
+// through logr's Error method.
+type Logger struct {
+	logger logr.Logger
+}
+
+// New creates a new Logger writing to l.
+func New(l logr.Logger) *Logger {
+	return &Logger{logger: l}
+}
+
+// Verbosity maps a logger.LogLevel to the equivalent logr verbosity.
+//
+// Errors have no verbosity in logr and map to 0, like info and warnings.
+func Verbosity(level logger.LogLevel) int {
+	if level == logger.LevelDebug {
+		return 1
+	}
+	return 0
+}
+
+// Info writes an info-level log entry.
+func (l *Logger) Info(_ context.Context, msg string, keysAndValues ...any) {
+	l.logger.V(Verbosity(logger.LevelInfo)).Info(msg, keysAndValues...)
+}
+
+// Error writes an error-level log entry.
+//
+// If keysAndValues contains an "error" key holding an error, it is passed to logr as the error.
+func (l *Logger) Error(_ context.Context, msg string, keysAndValues ...any) {
+	var err error
+	rest := make([]any, 0, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) && keysAndValues[i] == errorKey {
+			if e, ok := keysAndValues[i+1].(error); ok && err == nil {
+				err = e
+				continue
+			}
+		}
+		rest = append(rest, keysAndValues[i:min(i+2, len(keysAndValues))]...)
+	}
+	l.logger.Error(err, msg, rest...)
+}
+
+// Warn writes a warning-level log entry.
+func (l *Logger) Warn(_ context.Context, msg string, keysAndValues ...any) {
+	l.logger.V(Verbosity(logger.LevelWarn)).Info(msg, append([]any{levelKey, "warn"}, keysAndValues...)...)
+}
+
+// Debug writes a debug-level log entry.
+func (l *Logger) Debug(_ context.Context, msg string, keysAndValues ...any) {
+	l.logger.V(Verbosity(logger.LevelDebug)).Info(msg, keysAndValues...)
+}
@@ -0,0 +1,54 @@
+package logrlogger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCapture(verbosity int) (logr.Logger, *[]string) {
+	var lines []string
+	l := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: verbosity})
+	return l, &lines
+}
+
+func TestLogger(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("verbosity 0 filters debug", func(t *testing.T) {
+		l, lines := newCapture(0)
+		New(l).Debug(ctx, "filtered")
+		assert.Empty(t, *lines)
+	})
+
+	t.Run("levels", func(t *testing.T) {
+		l, lines := newCapture(1)
+		var hl logger.Logger = New(l)
+		hl.Debug(ctx, "debug")
+		hl.Info(ctx, "info", "k", "v")
+		hl.Warn(ctx, "warn")
+		hl.Error(ctx, "failed", "status", 500, "error", errors.New("boom"))
+
+		require.Len(t, *lines, 4)
+		assert.Contains(t, (*lines)[0], `"msg"="debug"`)
+		assert.Contains(t, (*lines)[0], `"level"=1`)
+		assert.Contains(t, (*lines)[1], `"k"="v"`)
+		assert.Contains(t, (*lines)[2], `"severity"="warn"`)
+		assert.Contains(t, (*lines)[3], `"error"="boom"`)
+		assert.Contains(t, (*lines)[3], `"status"=500`)
+	})
+}
+
+func TestVerbosity(t *testing.T) {
+	assert.Equal(t, 1, Verbosity(logger.LevelDebug))
+	assert.Equal(t, 0, Verbosity(logger.LevelInfo))
+	assert.Equal(t, 0, Verbosity(logger.LevelWarn))
+	assert.Equal(t, 0, Verbosity(logger.LevelError))
+}
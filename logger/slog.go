@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger creates a Logger that writes to an existing *slog.Logger.
+func NewSlogLogger(l *slog.Logger) *DefaultLogger {
+	return &DefaultLogger{logger: l}
+}
+
+// NewSlogHandlerLogger creates a Logger that writes to an existing slog.Handler.
+func NewSlogHandlerLogger(h slog.Handler) *DefaultLogger {
+	return &DefaultLogger{logger: slog.New(h)}
+}
+
+// ToSlogLevel maps a LogLevel to the equivalent slog.Level.
+func ToSlogLevel(level LogLevel) slog.Level {
+	return mapLogLevel(level)
+}
+
+// FromSlogLevel maps a slog.Level to the closest LogLevel at or below it.
+func FromSlogLevel(level slog.Level) LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return LevelError
+	case level >= slog.LevelWarn:
+		return LevelWarn
+	case level >= slog.LevelInfo:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
+// Handler is a slog.Handler that forwards records to a Logger.
+type Handler struct {
+	logger Logger
+	level  slog.Leveler
+	attrs  []slog.Attr
+	groups []string
+}
+
+// NewHandler creates a slog.Handler that forwards records at or above level to l.
+func NewHandler(l Logger, level LogLevel) *Handler {
+	return &Handler{logger: l, level: ToSlogLevel(level)}
+}
+
+// Enabled reports whether records at level are forwarded.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle forwards r to the wrapped Logger at the matching level.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	keysAndValues := make([]any, 0, 2*(len(h.attrs)+r.NumAttrs()))
+	for _, a := range h.attrs {
+		keysAndValues = append(keysAndValues, a.Key, a.Value.Resolve().Any())
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		keysAndValues = append(keysAndValues, h.qualify(a.Key), a.Value.Resolve().Any())
+		return true
+	})
+
+	switch FromSlogLevel(r.Level) {
+	case LevelDebug:
+		h.logger.Debug(ctx, r.Message, keysAndValues...)
+	case LevelInfo:
+		h.logger.Info(ctx, r.Message, keysAndValues...)
+	case LevelWarn:
+		h.logger.Warn(ctx, r.Message, keysAndValues...)
+	case LevelError:
+		h.logger.Error(ctx, r.Message, keysAndValues...)
+	}
+
+	return nil
+}
+
+// WithAttrs returns a Handler that adds attrs to every record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := *h
+	out.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	out.attrs = append(out.attrs, h.attrs...)
+	for _, a := range attrs {
+		out.attrs = append(out.attrs, slog.Attr{Key: h.qualify(a.Key), Value: a.Value})
+	}
+	return &out
+}
+
+// WithGroup returns a Handler that prefixes subsequent attribute keys with name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	out := *h
+	out.groups = append(append([]string{}, h.groups...), name)
+	return &out
+}
+
+// qualify prefixes key with the handler's groups, dot-separated.
+func (h *Handler) qualify(key string) string {
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		key = h.groups[i] + "." + key
+	}
+	return key
+}
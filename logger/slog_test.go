@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	sl := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	NewSlogLogger(sl).Debug(t.Context(), "via logger", "k", "v")
+	NewSlogHandlerLogger(sl.Handler()).Warn(t.Context(), "via handler")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var first, second map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	assert.Equal(t, "DEBUG", first["level"])
+	assert.Equal(t, "v", first["k"])
+	assert.Equal(t, "WARN", second["level"])
+}
+
+func TestLevelMapping(t *testing.T) {
+	for _, level := range []LogLevel{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		assert.Equal(t, level, FromSlogLevel(ToSlogLevel(level)))
+	}
+	assert.Equal(t, LevelWarn, FromSlogLevel(slog.LevelWarn+1))
+	assert.Equal(t, LevelDebug, FromSlogLevel(slog.LevelDebug-4))
+}
+
+func TestHandler(t *testing.T) {
+	ml := new(MockLogger)
+	ctx := t.Context()
+	ml.On("Info", ctx, "info", "svc", "hs", "req.id", "1").Return()
+	ml.On("Error", ctx, "error", "svc", "hs", "req.err", "boom").Return()
+
+	sl := slog.New(NewHandler(ml, LevelInfo)).With("svc", "hs").WithGroup("req")
+	sl.DebugContext(ctx, "filtered", "id", "0")
+	sl.InfoContext(ctx, "info", "id", "1")
+	sl.ErrorContext(ctx, "error", "err", "boom")
+
+	ml.AssertExpectations(t)
+	ml.AssertNotCalled(t, "Debug", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,20 @@
+module github.com/hibare/headscale-client-go/logger/zaplogger
+
+go 1.26.4
+
+// Comment out the following line to use the latest version of headscale-client-go from GitHub instead of the local copy.
+replace github.com/hibare/headscale-client-go => ../../
+
+require (
+	github.com/hibare/headscale-client-go v0.7.0
+	github.com/stretchr/testify v1.11.1
+	go.uber.org/zap v1.28.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.5.2 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
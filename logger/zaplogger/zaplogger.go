@@ -0,0 +1,56 @@
+// Package zaplogger adapts a zap.Logger to the headscale-client-go logger.Logger interface.
+package zaplogger
+
+import (
+	"context"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger is a logger.Logger that writes to a zap.SugaredLogger.
+type Logger struct {
+	logger *zap.SugaredLogger
+}
+
+// New creates a new Logger writing to l.
+func New(l *zap.Logger) *Logger {
+	return &Logger{logger: l.Sugar()}
+}
+
+// Level maps a logger.LogLevel to the equivalent zapcore.Level.
+func Level(level logger.LogLevel) zapcore.Level {
+	switch level {
+	case logger.LevelDebug:
+		return zapcore.DebugLevel
+	case logger.LevelInfo:
+		return zapcore.InfoLevel
+	case logger.LevelWarn:
+		return zapcore.WarnLevel
+	case logger.LevelError:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// Info writes an info-level log entry.
+func (l *Logger) Info(_ context.Context, msg string, keysAndValues ...any) {
+	l.logger.Infow(msg, keysAndValues...)
+}
+
+// Error writes an error-level log entry.
+func (l *Logger) Error(_ context.Context, msg string, keysAndValues ...any) {
+	l.logger.Errorw(msg, keysAndValues...)
+}
+
+// Warn writes a warning-level log entry.
+func (l *Logger) Warn(_ context.Context, msg string, keysAndValues ...any) {
+	l.logger.Warnw(msg, keysAndValues...)
+}
+
+// Debug writes a debug-level log entry.
+func (l *Logger) Debug(_ context.Context, msg string, keysAndValues ...any) {
+	l.logger.Debugw(msg, keysAndValues...)
+}
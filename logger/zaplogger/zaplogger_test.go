@@ -0,0 +1,39 @@
+package zaplogger
+
+import (
+	"testing"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(Level(logger.LevelInfo))
+	var l logger.Logger = New(zap.New(core))
+	ctx := t.Context()
+
+	l.Debug(ctx, "filtered")
+	l.Info(ctx, "info", "k", "v")
+	l.Warn(ctx, "warn")
+	l.Error(ctx, "error")
+
+	entries := logs.All()
+	require.Len(t, entries, 3)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, "info", entries[0].Message)
+	assert.Equal(t, map[string]any{"k": "v"}, entries[0].ContextMap())
+	assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+	assert.Equal(t, zapcore.ErrorLevel, entries[2].Level)
+}
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, zapcore.DebugLevel, Level(logger.LevelDebug))
+	assert.Equal(t, zapcore.InfoLevel, Level(logger.LevelInfo))
+	assert.Equal(t, zapcore.WarnLevel, Level(logger.LevelWarn))
+	assert.Equal(t, zapcore.ErrorLevel, Level(logger.LevelError))
+	assert.Equal(t, zapcore.InfoLevel, Level(logger.LogLevel(99)))
+}
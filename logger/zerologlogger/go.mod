@@ -0,0 +1,22 @@
+module github.com/hibare/headscale-client-go/logger/zerologlogger
+
+go 1.26.4
+
+// Comment out the following line to use the latest version of headscale-client-go from GitHub instead of the local copy.
+replace github.com/hibare/headscale-client-go => ../../
+
+require (
+	github.com/hibare/headscale-client-go v0.7.0
+	github.com/rs/zerolog v1.35.1
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.5.2 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,55 @@
+// Package zerologlogger adapts a zerolog.Logger to the headscale-client-go logger.Logger interface.
+package zerologlogger
+
+import (
+	"context"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/rs/zerolog"
+)
+
+// Logger is a logger.Logger that writes to a zerolog.Logger.
+type Logger struct {
+	logger zerolog.Logger
+}
+
+// New creates a new Logger writing to l.
+func New(l zerolog.Logger) *Logger {
+	return &Logger{logger: l}
+}
+
+// Level maps a logger.LogLevel to the equivalent zerolog.Level.
+func Level(level logger.LogLevel) zerolog.Level {
+	switch level {
+	case logger.LevelDebug:
+		return zerolog.DebugLevel
+	case logger.LevelInfo:
+		return zerolog.InfoLevel
+	case logger.LevelWarn:
+		return zerolog.WarnLevel
+	case logger.LevelError:
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
+
+// Info writes an info-level log entry.
+func (l *Logger) Info(ctx context.Context, msg string, keysAndValues ...any) {
+	l.logger.Info().Ctx(ctx).Fields(keysAndValues).Msg(msg)
+}
+
+// Error writes an error-level log entry.
+func (l *Logger) Error(ctx context.Context, msg string, keysAndValues ...any) {
+	l.logger.Error().Ctx(ctx).Fields(keysAndValues).Msg(msg)
+}
+
+// Warn writes a warning-level log entry.
+func (l *Logger) Warn(ctx context.Context, msg string, keysAndValues ...any) {
+	l.logger.Warn().Ctx(ctx).Fields(keysAndValues).Msg(msg)
+}
+
+// Debug writes a debug-level log entry.
+func (l *Logger) Debug(ctx context.Context, msg string, keysAndValues ...any) {
+	l.logger.Debug().Ctx(ctx).Fields(keysAndValues).Msg(msg)
+}
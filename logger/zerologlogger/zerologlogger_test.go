@@ -0,0 +1,42 @@
+package zerologlogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var l logger.Logger = New(zerolog.New(&buf).Level(Level(logger.LevelInfo)))
+	ctx := t.Context()
+
+	l.Debug(ctx, "filtered")
+	l.Info(ctx, "info", "k", "v")
+	l.Warn(ctx, "warn")
+	l.Error(ctx, "error")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	assert.Equal(t, "info", first["level"])
+	assert.Equal(t, "info", first["message"])
+	assert.Equal(t, "v", first["k"])
+	assert.Contains(t, string(lines[1]), `"level":"warn"`)
+	assert.Contains(t, string(lines[2]), `"level":"error"`)
+}
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, zerolog.DebugLevel, Level(logger.LevelDebug))
+	assert.Equal(t, zerolog.InfoLevel, Level(logger.LevelInfo))
+	assert.Equal(t, zerolog.WarnLevel, Level(logger.LevelWarn))
+	assert.Equal(t, zerolog.ErrorLevel, Level(logger.LevelError))
+	assert.Equal(t, zerolog.InfoLevel, Level(logger.LogLevel(99)))
+}
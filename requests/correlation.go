@@ -0,0 +1,58 @@
+package requests
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+)
+
+const (
+	// requestIDBytes is the number of random bytes in a generated request ID.
+	requestIDBytes = 16
+)
+
+type requestIDKey struct{}
+
+type headersKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request correlation ID id.
+//
+// Requests built with this context send id in the X-Request-Id header instead of a generated ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request correlation ID carried by ctx, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithHeaders returns a copy of ctx carrying extra headers for every request built with it.
+//
+// Headers already attached to ctx are kept unless overridden by headers. The Authorization and
+// X-Request-Id headers cannot be set this way: the client sets them after the context headers, from
+// its credentials and from WithRequestID.
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := maps.Clone(HeadersFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(headers))
+	}
+	maps.Copy(merged, headers)
+
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext returns the extra headers carried by ctx.
+func HeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
+
+// NewRequestID generates a random request correlation ID.
+func NewRequestID() string {
+	b := make([]byte, requestIDBytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,99 @@
+package requests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCorrelationRequest(t *testing.T, baseURL string) RequestInterface {
+	t.Helper()
+	u, err := url.Parse(baseURL)
+	require.NoError(t, err)
+	return NewRequest(u, TestAPIKey, versions.APIVersionV1, RequestConfig{
+		Logger: logger.NewDefaultLogger(logger.LevelError),
+	})
+}
+
+func TestBuildRequest_RequestID(t *testing.T) {
+	r := newCorrelationRequest(t, "http://example.com")
+
+	t.Run("generated", func(t *testing.T) {
+		first, err := r.BuildRequest(t.Context(), http.MethodGet, r.BuildURL("node"), RequestOptions{})
+		require.NoError(t, err)
+		second, err := r.BuildRequest(t.Context(), http.MethodGet, r.BuildURL("node"), RequestOptions{})
+		require.NoError(t, err)
+
+		assert.Len(t, first.Header.Get(HeaderRequestID), 2*requestIDBytes)
+		assert.NotEqual(t, first.Header.Get(HeaderRequestID), second.Header.Get(HeaderRequestID))
+	})
+
+	t.Run("from context", func(t *testing.T) {
+		ctx := WithRequestID(t.Context(), "corr-42")
+		assert.Equal(t, "corr-42", RequestIDFromContext(ctx))
+
+		req, err := r.BuildRequest(ctx, http.MethodGet, r.BuildURL("node"), RequestOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "corr-42", req.Header.Get(HeaderRequestID))
+	})
+}
+
+func TestBuildRequest_ContextHeaders(t *testing.T) {
+	r := newCorrelationRequest(t, "http://example.com")
+
+	ctx := WithHeaders(t.Context(), map[string]string{"X-Tenant": "a", "X-Trace": "1"})
+	ctx = WithHeaders(ctx, map[string]string{"X-Tenant": "b"})
+	assert.Equal(t, map[string]string{"X-Tenant": "b", "X-Trace": "1"}, HeadersFromContext(ctx))
+
+	req, err := r.BuildRequest(ctx, http.MethodGet, r.BuildURL("node"), RequestOptions{
+		Headers: map[string]string{"X-Trace": "2"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "b", req.Header.Get("X-Tenant"))
+	assert.Equal(t, "2", req.Header.Get("X-Trace"), "request options take precedence over context headers")
+	assert.Equal(t, "Bearer "+TestAPIKey, req.Header.Get("Authorization"))
+}
+
+func TestBuildRequest_ContextHeadersKeepCredentials(t *testing.T) {
+	r := newCorrelationRequest(t, "http://example.com")
+
+	ctx := WithRequestID(t.Context(), "corr-42")
+	ctx = WithHeaders(ctx, map[string]string{
+		"Authorization": "Bearer other",
+		HeaderRequestID: "spoofed",
+		"User-Agent":    "custom",
+	})
+	req, err := r.BuildRequest(ctx, http.MethodGet, r.BuildURL("node"), RequestOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer "+TestAPIKey, req.Header.Get("Authorization"))
+	assert.Equal(t, "corr-42", req.Header.Get(HeaderRequestID))
+	assert.Equal(t, "custom", req.Header.Get("User-Agent"))
+}
+
+func TestDo_APIErrorCarriesRequestID(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(HeaderRequestID)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("denied"))
+	}))
+	defer ts.Close()
+
+	r := newCorrelationRequest(t, ts.URL)
+	ctx := WithRequestID(t.Context(), "corr-7")
+	req, err := r.BuildRequest(ctx, http.MethodGet, r.BuildURL("node"), RequestOptions{})
+	require.NoError(t, err)
+
+	err = r.Do(ctx, req, nil)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "corr-7", received)
+	assert.Equal(t, "corr-7", apiErr.RequestID)
+	assert.Equal(t, "api error status 403: denied (request id corr-7)", apiErr.Error())
+}
@@ -0,0 +1,216 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHealthPath is the Headscale endpoint used to probe server health.
+	DefaultHealthPath = "/health"
+
+	// DefaultProbeInterval is the default interval between re-probes of the primary endpoint after a failover.
+	DefaultProbeInterval = 30 * time.Second
+
+	// DefaultProbeTimeout bounds a probe of the primary endpoint, which delays the request that triggers it.
+	DefaultProbeTimeout = 5 * time.Second
+)
+
+// HealthChecker checks whether a Headscale endpoint is able to serve requests.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context, baseURL *url.URL) error
+}
+
+// HealthCheckerFunc adapts an ordinary function to the HealthChecker interface.
+type HealthCheckerFunc func(ctx context.Context, baseURL *url.URL) error
+
+// CheckHealth calls f(ctx, baseURL).
+func (f HealthCheckerFunc) CheckHealth(ctx context.Context, baseURL *url.URL) error {
+	return f(ctx, baseURL)
+}
+
+// HTTPHealthChecker probes an endpoint by issuing a GET request against its health path.
+type HTTPHealthChecker struct {
+	Client *http.Client
+	Path   string
+}
+
+// CheckHealth reports an error if the health path of baseURL does not answer with a 2xx status.
+func (h *HTTPHealthChecker) CheckHealth(ctx context.Context, baseURL *url.URL) error {
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPClientTimeout}
+	}
+
+	path := h.Path
+	if path == "" {
+		path = DefaultHealthPath
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL.JoinPath(path).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// EndpointPool tracks an ordered list of base URLs for the same Headscale server.
+//
+// The pool prefers the endpoint that last served a request successfully. When that endpoint
+// is not the primary (the first URL), the primary is re-probed at most once per probe interval
+// and preferred again as soon as it reports healthy.
+type EndpointPool struct {
+	mu            sync.Mutex
+	endpoints     []*url.URL
+	active        int
+	lastProbe     time.Time
+	probeInterval time.Duration
+	probeTimeout  time.Duration
+	checker       HealthChecker
+	now           func() time.Time
+}
+
+// NewEndpointPool creates a new EndpointPool for the given endpoints, in order of preference.
+func NewEndpointPool(endpoints []*url.URL, checker HealthChecker, probeInterval time.Duration) *EndpointPool {
+	if probeInterval <= 0 {
+		probeInterval = DefaultProbeInterval
+	}
+
+	return &EndpointPool{
+		endpoints:     endpoints,
+		probeInterval: probeInterval,
+		probeTimeout:  DefaultProbeTimeout,
+		checker:       checker,
+		now:           time.Now,
+	}
+}
+
+// Primary returns the first endpoint of the pool.
+func (p *EndpointPool) Primary() *url.URL {
+	return p.endpoints[0]
+}
+
+// Active returns the endpoint currently preferred by the pool.
+func (p *EndpointPool) Active() *url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.endpoints[p.active]
+}
+
+// Candidates returns the endpoints in the order they should be tried, starting with the active one.
+func (p *EndpointPool) Candidates(ctx context.Context) []*url.URL {
+	p.probePrimary(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make([]*url.URL, 0, len(p.endpoints))
+	out = append(out, p.endpoints[p.active])
+	for i, u := range p.endpoints {
+		if i != p.active {
+			out = append(out, u)
+		}
+	}
+
+	return out
+}
+
+// MarkHealthy makes u the preferred endpoint for subsequent requests.
+func (p *EndpointPool) MarkHealthy(u *url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, e := range p.endpoints {
+		if e == u {
+			if i != p.active && i != 0 {
+				// Start the probe interval from the moment we moved away from the primary.
+				p.lastProbe = p.now()
+			}
+			p.active = i
+			return
+		}
+	}
+}
+
+// probePrimary checks the primary endpoint if the pool has failed over and the probe interval elapsed.
+// The probe runs within the request that triggers it, so it is bounded by the probe timeout.
+func (p *EndpointPool) probePrimary(ctx context.Context) {
+	if p.checker == nil {
+		return
+	}
+
+	p.mu.Lock()
+	if p.active == 0 || p.now().Sub(p.lastProbe) < p.probeInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastProbe = p.now()
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, p.probeTimeout)
+	defer cancel()
+
+	if err := p.checker.CheckHealth(ctx, p.endpoints[0]); err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.active = 0
+	p.mu.Unlock()
+}
+
+// isIdempotent reports whether a request with method may be sent again without changing its effect.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// canFailOver reports whether a request with method that failed with err may be sent to another
+// endpoint. Non-idempotent requests may only be sent again if they never reached the server.
+func canFailOver(method string, err error) bool {
+	if isIdempotent(method) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// rebaseURL moves u, which was built from the base URL from, onto the base URL to.
+func rebaseURL(u, from, to *url.URL) *url.URL {
+	out := *u
+	out.Scheme = to.Scheme
+	out.Host = to.Host
+	out.User = to.User
+
+	out.Path = strings.TrimSuffix(to.Path, "/") + strings.TrimPrefix(u.Path, strings.TrimSuffix(from.Path, "/"))
+	if u.RawPath != "" {
+		out.RawPath = strings.TrimSuffix(to.EscapedPath(), "/") + strings.TrimPrefix(u.RawPath, strings.TrimSuffix(from.EscapedPath(), "/"))
+	}
+
+	return &out
+}
@@ -0,0 +1,234 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, status int, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `","body":"` + string(body) + `"}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newFailoverRequest(t *testing.T, checker HealthChecker, urls ...string) *Request {
+	t.Helper()
+	parsed := make([]*url.URL, 0, len(urls))
+	for _, u := range urls {
+		p, err := url.Parse(u)
+		require.NoError(t, err)
+		parsed = append(parsed, p)
+	}
+	r := NewRequest(parsed[0], TestAPIKey, versions.APIVersionV1, RequestConfig{
+		Logger:        logger.NewDefaultLogger(logger.LevelError),
+		FallbackURLs:  parsed[1:],
+		HealthChecker: checker,
+	})
+	req, ok := r.(*Request)
+	require.True(t, ok)
+	return req
+}
+
+func doRequest(t *testing.T, r *Request, method string, meta *ResponseMeta) (map[string]string, error) {
+	t.Helper()
+	ctx := WithResponseMeta(t.Context(), meta)
+	req, err := r.BuildRequest(ctx, method, r.BuildURL("node"), RequestOptions{Body: "payload"})
+	require.NoError(t, err)
+	var out map[string]string
+	err = r.Do(ctx, req, &out)
+	return out, err
+}
+
+func TestDo_FailoverOn5xx(t *testing.T) {
+	var primaryHits, secondaryHits atomic.Int32
+	primary := newTestServer(t, http.StatusBadGateway, &primaryHits)
+	secondary := newTestServer(t, http.StatusOK, &secondaryHits)
+
+	unhealthy := HealthCheckerFunc(func(context.Context, *url.URL) error { return errors.New("down") })
+	r := newFailoverRequest(t, unhealthy, primary.URL, secondary.URL+"/prefix")
+
+	meta := &ResponseMeta{}
+	out, err := doRequest(t, r, http.MethodPut, meta)
+	require.NoError(t, err)
+	assert.Equal(t, "/prefix/api/v1/node", out["path"])
+	assert.Equal(t, "payload", out["body"])
+	assert.Equal(t, int32(1), primaryHits.Load())
+	assert.Equal(t, secondary.URL+"/prefix", meta.Endpoint)
+	assert.Equal(t, 2, meta.Attempts)
+
+	// The healthy endpoint is sticky.
+	_, err = doRequest(t, r, http.MethodPut, meta)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), primaryHits.Load())
+	assert.Equal(t, int32(2), secondaryHits.Load())
+	assert.Equal(t, 1, meta.Attempts)
+}
+
+func TestDo_FailoverOnTransportError(t *testing.T) {
+	var hits atomic.Int32
+	healthy := newTestServer(t, http.StatusOK, &hits)
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	r := newFailoverRequest(t, nil, deadURL, healthy.URL)
+	meta := &ResponseMeta{}
+	_, err := doRequest(t, r, http.MethodPost, meta)
+	require.NoError(t, err)
+	assert.Equal(t, healthy.URL, meta.Endpoint)
+	assert.Equal(t, int32(1), hits.Load())
+}
+
+func TestDo_AllEndpointsFail(t *testing.T) {
+	var hits atomic.Int32
+	a := newTestServer(t, http.StatusInternalServerError, &hits)
+	b := newTestServer(t, http.StatusServiceUnavailable, &hits)
+
+	r := newFailoverRequest(t, nil, a.URL, b.URL)
+	_, err := doRequest(t, r, http.MethodPut, nil)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestDo_NoFailoverOn4xx(t *testing.T) {
+	var primaryHits, secondaryHits atomic.Int32
+	primary := newTestServer(t, http.StatusNotFound, &primaryHits)
+	secondary := newTestServer(t, http.StatusOK, &secondaryHits)
+
+	r := newFailoverRequest(t, nil, primary.URL, secondary.URL)
+	_, err := doRequest(t, r, http.MethodPut, nil)
+	require.Error(t, err)
+	assert.Equal(t, int32(1), primaryHits.Load())
+	assert.Equal(t, int32(0), secondaryHits.Load())
+}
+
+func TestDo_NoFailoverOn5xxForPost(t *testing.T) {
+	var primaryHits, secondaryHits atomic.Int32
+	primary := newTestServer(t, http.StatusBadGateway, &primaryHits)
+	secondary := newTestServer(t, http.StatusOK, &secondaryHits)
+
+	r := newFailoverRequest(t, nil, primary.URL, secondary.URL)
+	_, err := doRequest(t, r, http.MethodPost, nil)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Equal(t, int32(1), primaryHits.Load())
+	assert.Equal(t, int32(0), secondaryHits.Load(), "the server may have acted on the request")
+}
+
+func TestCanFailOver(t *testing.T) {
+	dialErr := &url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	readErr := &url.Error{Op: "Post", Err: &net.OpError{Op: "read", Err: errors.New("connection reset")}}
+
+	assert.True(t, canFailOver(http.MethodGet, readErr))
+	assert.True(t, canFailOver(http.MethodDelete, readErr))
+	assert.True(t, canFailOver(http.MethodPost, dialErr))
+	assert.False(t, canFailOver(http.MethodPost, readErr))
+	assert.False(t, canFailOver(http.MethodPatch, context.DeadlineExceeded))
+}
+
+func TestEndpointPool_ReprobesPrimary(t *testing.T) {
+	primary, _ := url.Parse("http://primary")
+	secondary, _ := url.Parse("http://secondary")
+
+	var healthy atomic.Bool
+	var probes atomic.Int32
+	checker := HealthCheckerFunc(func(_ context.Context, u *url.URL) error {
+		probes.Add(1)
+		assert.Equal(t, primary, u)
+		if healthy.Load() {
+			return nil
+		}
+		return errors.New("down")
+	})
+
+	now := time.Now()
+	pool := NewEndpointPool([]*url.URL{primary, secondary}, checker, time.Minute)
+	pool.now = func() time.Time { return now }
+
+	pool.MarkHealthy(secondary)
+	assert.Equal(t, secondary, pool.Candidates(t.Context())[0])
+	assert.Equal(t, int32(0), probes.Load(), "primary must not be probed before the interval elapses")
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, secondary, pool.Candidates(t.Context())[0])
+	assert.Equal(t, int32(1), probes.Load())
+
+	healthy.Store(true)
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, []*url.URL{primary, secondary}, pool.Candidates(t.Context()))
+	assert.Equal(t, primary, pool.Active())
+}
+
+func TestEndpointPool_ProbeTimeout(t *testing.T) {
+	primary, _ := url.Parse("http://primary")
+	secondary, _ := url.Parse("http://secondary")
+
+	hanging := HealthCheckerFunc(func(ctx context.Context, _ *url.URL) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	now := time.Now()
+	pool := NewEndpointPool([]*url.URL{primary, secondary}, hanging, time.Minute)
+	pool.now = func() time.Time { return now }
+	pool.probeTimeout = 10 * time.Millisecond
+
+	pool.MarkHealthy(secondary)
+	now = now.Add(2 * time.Minute)
+
+	done := make(chan []*url.URL)
+	go func() { done <- pool.Candidates(t.Context()) }()
+	select {
+	case candidates := <-done:
+		assert.Equal(t, secondary, candidates[0])
+	case <-time.After(5 * time.Second):
+		t.Fatal("the probe was not bounded by the probe timeout")
+	}
+}
+
+func TestHTTPHealthChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == DefaultHealthPath {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	require.NoError(t, (&HTTPHealthChecker{Client: ts.Client()}).CheckHealth(t.Context(), u))
+	require.Error(t, (&HTTPHealthChecker{Client: ts.Client(), Path: "/other"}).CheckHealth(t.Context(), u))
+}
+
+func TestRebaseURL(t *testing.T) {
+	from, _ := url.Parse("http://a.example.com/")
+	to, _ := url.Parse("https://b.example.com:8443/hs/")
+	u, _ := url.Parse("http://a.example.com/api/v1/node/bar%2Fbaz?x=1")
+
+	got := rebaseURL(u, from, to)
+	assert.Equal(t, "https://b.example.com:8443/hs/api/v1/node/bar%2Fbaz?x=1", got.String())
+}
@@ -0,0 +1,83 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	// HeaderRequestID is the header carrying the request correlation ID.
+	HeaderRequestID = "X-Request-Id"
+)
+
+// ResponseMeta holds metadata about the HTTP exchange behind a single API call.
+type ResponseMeta struct {
+	// StatusCode is the HTTP status of the final response, or 0 if no response was received.
+	StatusCode int
+
+	// Header holds the headers of the final response.
+	Header http.Header
+
+	// RequestID is the request correlation ID echoed by the server, or the one sent if not echoed.
+	RequestID string
+
+	// Duration is the time spent in Do, across all attempts.
+	Duration time.Duration
+
+	// Endpoint is the base URL of the endpoint that served the response.
+	Endpoint string
+
+	// URL is the final request URL, with the values of secret query parameters redacted.
+	URL string
+
+	// Attempts is the number of endpoints tried before a response was received.
+	Attempts int
+}
+
+type responseMetaKey struct{}
+
+// WithResponseMeta returns a copy of ctx that makes Do record response metadata into meta.
+//
+// Because every resource method passes its context to Do, this works for all resources
+// without changing their signatures. A ResponseMeta must not be shared by concurrent calls.
+func WithResponseMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaKey{}, meta)
+}
+
+// CaptureResponseMeta returns a copy of ctx together with a new ResponseMeta that Do will populate.
+func CaptureResponseMeta(ctx context.Context) (context.Context, *ResponseMeta) {
+	meta := &ResponseMeta{}
+	return WithResponseMeta(ctx, meta), meta
+}
+
+// responseMetaFromContext returns the ResponseMeta attached to ctx, if any.
+func responseMetaFromContext(ctx context.Context) *ResponseMeta {
+	meta, _ := ctx.Value(responseMetaKey{}).(*ResponseMeta)
+	return meta
+}
+
+// recordResponseMeta fills the ResponseMeta attached to ctx, if any. resp may be nil.
+func recordResponseMeta(ctx context.Context, req *http.Request, resp *http.Response, endpoint string, attempts int, start time.Time) {
+	meta := responseMetaFromContext(ctx)
+	if meta == nil {
+		return
+	}
+
+	*meta = ResponseMeta{
+		Duration: time.Since(start),
+		Endpoint: endpoint,
+		URL:      RedactURL(req.URL),
+		Attempts: attempts,
+	}
+
+	if resp != nil {
+		meta.StatusCode = resp.StatusCode
+		meta.Header = resp.Header.Clone()
+		meta.RequestID = resp.Header.Get(HeaderRequestID)
+	}
+
+	if meta.RequestID == "" {
+		meta.RequestID = req.Header.Get(HeaderRequestID)
+	}
+}
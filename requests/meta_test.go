@@ -0,0 +1,103 @@
+package requests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo_RecordsResponseMeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "success", status: http.StatusOK},
+		{name: "api error", status: http.StatusNotFound, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderRequestID, "req-123")
+				w.Header().Set("X-Custom", "yes")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer ts.Close()
+
+			baseURL, _ := url.Parse(ts.URL)
+			r := NewRequest(baseURL, TestAPIKey, versions.APIVersionV1, RequestConfig{
+				Logger:     logger.NewDefaultLogger(logger.LevelError),
+				HTTPClient: ts.Client(),
+			})
+
+			ctx, meta := CaptureResponseMeta(t.Context())
+			req, err := r.BuildRequest(ctx, http.MethodGet, r.BuildURL("node"), RequestOptions{})
+			require.NoError(t, err)
+
+			err = r.Do(ctx, req, &struct{}{})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.status, meta.StatusCode)
+			assert.Equal(t, "req-123", meta.RequestID)
+			assert.Equal(t, "yes", meta.Header.Get("X-Custom"))
+			assert.Equal(t, ts.URL+"/api/v1/node", meta.URL)
+			assert.Equal(t, ts.URL, meta.Endpoint)
+			assert.Equal(t, 1, meta.Attempts)
+			assert.Positive(t, meta.Duration)
+		})
+	}
+}
+
+func TestDo_RecordsResponseMetaOnTransportError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	baseURL, _ := url.Parse(ts.URL)
+	r := NewRequest(baseURL, TestAPIKey, versions.APIVersionV1, RequestConfig{
+		Logger: logger.NewDefaultLogger(logger.LevelError),
+	})
+
+	ctx, meta := CaptureResponseMeta(t.Context())
+	req, err := r.BuildRequest(ctx, http.MethodGet, r.BuildURL("node"), RequestOptions{})
+	require.NoError(t, err)
+
+	require.Error(t, r.Do(ctx, req, nil))
+	assert.Zero(t, meta.StatusCode)
+	assert.Nil(t, meta.Header)
+	assert.Equal(t, 1, meta.Attempts)
+}
+
+func TestDo_RedactsResponseMetaURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	baseURL, _ := url.Parse(ts.URL)
+	r := NewRequest(baseURL, TestAPIKey, versions.APIVersionV1, RequestConfig{
+		Logger:     logger.NewDefaultLogger(logger.LevelError),
+		HTTPClient: ts.Client(),
+	})
+
+	ctx, meta := CaptureResponseMeta(t.Context())
+	req, err := r.BuildRequest(ctx, http.MethodPost, r.BuildURL("node", "register"), RequestOptions{
+		QueryParams: map[string]any{"user": "alice", "key": "nodekey:secret"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Do(ctx, req, &struct{}{}))
+	assert.NotContains(t, meta.URL, "secret")
+	assert.Contains(t, meta.URL, "user=alice")
+}
@@ -0,0 +1,115 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// RedactedValue replaces secrets in logged headers, URLs and bodies.
+	RedactedValue = "[REDACTED]"
+)
+
+// sensitiveHeaders are headers whose values are never logged.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// sensitiveFields are JSON members and query parameters whose values are never logged.
+//
+// "apiKey" holds the secret returned when creating an API key, and "key" holds pre-auth key
+// and node registration key values.
+var sensitiveFields = map[string]bool{
+	"apiKey":  true,
+	"api_key": true,
+	"key":     true,
+}
+
+// RedactHeaders returns a copy of h with the values of sensitive headers replaced.
+func RedactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range sensitiveHeaders {
+		if _, ok := out[name]; ok {
+			out[name] = []string{RedactedValue}
+		}
+	}
+	return out
+}
+
+// RedactURL returns the string form of u with the values of sensitive query parameters replaced.
+func RedactURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for k := range query {
+		if sensitiveFields[k] {
+			query[k] = []string{RedactedValue}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+
+	out := *u
+	out.RawQuery = query.Encode()
+	return out.String()
+}
+
+// redactError replaces the values of sensitive query parameters in the URL of a *url.Error
+// wrapped by err, as the http.Client reports the full request URL in transport errors.
+func redactError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		urlErr.URL = RedactURL(u)
+	}
+	return err
+}
+
+// RedactBody returns body with the values of sensitive JSON members replaced at any depth.
+//
+// Bodies that are not valid JSON are returned unchanged.
+func RedactBody(body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return string(body)
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+// redactValue replaces sensitive members in a decoded JSON value.
+func redactValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if sensitiveFields[k] {
+				if s, ok := child.(string); ok && s != "" {
+					val[k] = RedactedValue
+				}
+				continue
+			}
+			val[k] = redactValue(child)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = redactValue(child)
+		}
+		return val
+	default:
+		return v
+	}
+}
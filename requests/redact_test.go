@@ -0,0 +1,125 @@
+package requests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("User-Agent", DefaultUserAgent)
+
+	out := RedactHeaders(h)
+	assert.Equal(t, RedactedValue, out.Get("Authorization"))
+	assert.Equal(t, DefaultUserAgent, out.Get("User-Agent"))
+	assert.Equal(t, "Bearer secret", h.Get("Authorization"), "input must not be modified")
+}
+
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("http://example.com/api/v1/node/register?key=nodekey%3Aabc&user=alice")
+	assert.Equal(t, "http://example.com/api/v1/node/register?key=%5BREDACTED%5D&user=alice", RedactURL(u))
+
+	plain, _ := url.Parse("http://example.com/api/v1/node?user=alice")
+	assert.Equal(t, plain.String(), RedactURL(plain))
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "api key",
+			body:     `{"apiKey":"abc.secret"}`,
+			expected: `{"apiKey":"[REDACTED]"}`,
+		},
+		{
+			name:     "nested pre-auth key",
+			body:     `{"nodes":[{"id":"1","nodeKey":"nodekey:1","preAuthKey":{"id":"2","key":"secret","used":true}}]}`,
+			expected: `{"nodes":[{"id":"1","nodeKey":"nodekey:1","preAuthKey":{"id":"2","key":"[REDACTED]","used":true}}]}`,
+		},
+		{
+			name:     "not json",
+			body:     "plain text",
+			expected: "plain text",
+		},
+		{
+			name:     "empty",
+			body:     "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RedactBody([]byte(tt.body)))
+		})
+	}
+}
+
+func TestDo_DumpHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiKey":"prefix.topsecret"}`))
+	}))
+	defer ts.Close()
+
+	var logs bytes.Buffer
+	baseURL, _ := url.Parse(ts.URL)
+	r := NewRequest(baseURL, TestAPIKey, versions.APIVersionV1, RequestConfig{
+		Logger:   logger.New(logger.Options{Level: logger.LevelDebug, Writer: &logs}),
+		DumpHTTP: true,
+	})
+
+	req, err := r.BuildRequest(t.Context(), http.MethodPost, r.BuildURL("apikey"), RequestOptions{
+		Body: map[string]string{"expiration": "2030-01-01T00:00:00Z"},
+	})
+	require.NoError(t, err)
+
+	var resp struct {
+		APIKey string `json:"apiKey"`
+	}
+	require.NoError(t, r.Do(t.Context(), req, &resp))
+	assert.Equal(t, "prefix.topsecret", resp.APIKey, "dumping must not consume the body")
+
+	out := logs.String()
+	assert.Contains(t, out, "HTTP request dump")
+	assert.Contains(t, out, "HTTP response dump")
+	assert.Contains(t, out, "2030-01-01T00:00:00Z")
+	assert.NotContains(t, out, TestAPIKey)
+	assert.NotContains(t, out, "topsecret")
+}
+
+func TestDo_RedactsTransportErrors(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	var logs bytes.Buffer
+	baseURL, _ := url.Parse(dead.URL)
+	r := NewRequest(baseURL, TestAPIKey, versions.APIVersionV1, RequestConfig{
+		Logger: logger.New(logger.Options{Level: logger.LevelDebug, Writer: &logs}),
+	})
+
+	req, err := r.BuildRequest(t.Context(), http.MethodPost, r.BuildURL("node", "register"), RequestOptions{
+		QueryParams: map[string]any{"user": "alice", "key": "nodekey:topsecret"},
+	})
+	require.NoError(t, err)
+
+	err = r.Do(t.Context(), req, nil)
+	var urlErr *url.Error
+	require.ErrorAs(t, err, &urlErr)
+	assert.Contains(t, urlErr.URL, "user=alice")
+	assert.NotContains(t, err.Error(), "topsecret")
+	assert.Contains(t, logs.String(), "Request failed")
+	assert.NotContains(t, logs.String(), "topsecret")
+}
@@ -10,8 +10,12 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
+	"sync"
 	"time"
 
+	"github.com/hibare/headscale-client-go/compat"
+	"github.com/hibare/headscale-client-go/credentials"
 	"github.com/hibare/headscale-client-go/logger"
 	"github.com/hibare/headscale-client-go/versions"
 )
@@ -33,9 +37,13 @@ var (
 type APIError struct {
 	StatusCode int
 	Body       string
+	RequestID  string
 }
 
 func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("api error status %d: %s (request id %s)", e.StatusCode, e.Body, e.RequestID)
+	}
 	return fmt.Sprintf("api error status %d: %s", e.StatusCode, e.Body)
 }
 
@@ -46,14 +54,64 @@ type RequestInterface interface {
 	Do(ctx context.Context, req *http.Request, v any) error
 }
 
+// ServerRequestInterface is implemented by a RequestInterface that reaches server paths outside the
+// versioned API and tracks the Headscale version of its server. It is separate from RequestInterface
+// so that implementations written before it existed keep working; resources use it through
+// BuildServerURL, ServerVersion and SetServerVersion, which fall back when it is not implemented.
+type ServerRequestInterface interface {
+	BuildServerURL(pathParts ...any) *url.URL
+	ServerVersion() versions.ServerVersion
+	SetServerVersion(v versions.ServerVersion)
+}
+
+// BuildServerURL returns the URL of a server path outside the versioned API built by r. If r does not
+// implement ServerRequestInterface, the URL is derived from r.BuildURL without the API base path.
+func BuildServerURL(r RequestInterface, pathParts ...any) *url.URL {
+	if sr, ok := r.(ServerRequestInterface); ok {
+		return sr.BuildServerURL(pathParts...)
+	}
+
+	u := r.BuildURL()
+	u.Path = path.Dir(path.Dir(u.Path))
+	u.RawPath = ""
+	parts := make([]string, 0, len(pathParts))
+	for _, p := range pathParts {
+		parts = append(parts, url.PathEscape(fmt.Sprint(p)))
+	}
+	return u.JoinPath(parts...)
+}
+
+// ServerVersion returns the Headscale version known to r, or the zero value if it is unknown or r
+// does not implement ServerRequestInterface.
+func ServerVersion(r RequestInterface) versions.ServerVersion {
+	if sr, ok := r.(ServerRequestInterface); ok {
+		return sr.ServerVersion()
+	}
+	return versions.ServerVersion{}
+}
+
+// SetServerVersion records v as the Headscale version of r's server, if r implements ServerRequestInterface.
+func SetServerVersion(r RequestInterface, v versions.ServerVersion) {
+	if sr, ok := r.(ServerRequestInterface); ok {
+		sr.SetServerVersion(v)
+	}
+}
+
 // Request represents an HTTP request builder and executor.
 type Request struct {
-	baseURL    *url.URL
-	apiKey     string
-	apiVersion versions.APIVersion
-	userAgent  string
-	logger     logger.Logger
-	httpClient *http.Client
+	baseURL     *url.URL
+	apiKey      string
+	credentials credentials.Source
+	apiVersion  versions.APIVersion
+	userAgent   string
+	logger      logger.Logger
+	httpClient  *http.Client
+	endpoints   *EndpointPool
+	dumpHTTP    bool
+	strict      bool
+
+	mu            sync.RWMutex
+	serverVersion versions.ServerVersion
 }
 
 // BuildURL constructs a URL from the base URL, API version, and additional path parts.
@@ -67,6 +125,32 @@ func (r *Request) BuildURL(pathParts ...any) *url.URL {
 	return r.baseURL.JoinPath(parts...)
 }
 
+// BuildServerURL constructs a URL for a server path outside the versioned API, such as /health.
+func (r *Request) BuildServerURL(pathParts ...any) *url.URL {
+	parts := make([]string, 0, len(pathParts))
+	for _, p := range pathParts {
+		parts = append(parts, url.PathEscape(fmt.Sprint(p)))
+	}
+
+	return r.baseURL.JoinPath(parts...)
+}
+
+// ServerVersion returns the configured or detected Headscale version, or the zero value if unknown.
+func (r *Request) ServerVersion() versions.ServerVersion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.serverVersion
+}
+
+// SetServerVersion records the Headscale version of the server.
+func (r *Request) SetServerVersion(v versions.ServerVersion) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.serverVersion = v
+}
+
 // RequestOptions contains options for building an HTTP request.
 type RequestOptions struct {
 	Body        any
@@ -76,6 +160,9 @@ type RequestOptions struct {
 }
 
 // BuildRequest creates an HTTP request with the specified method, URL, and options.
+//
+// Every request carries an X-Request-Id header, taken from ctx (see WithRequestID) or generated.
+// Headers attached to ctx with WithHeaders are added before the headers in opt.
 func (r *Request) BuildRequest(ctx context.Context, method string, uri *url.URL, opt RequestOptions) (*http.Request, error) {
 	if uri == nil {
 		return nil, ErrURIRequired
@@ -109,8 +196,24 @@ func (r *Request) BuildRequest(ctx context.Context, method string, uri *url.URL,
 		return nil, err
 	}
 
+	apiKey, err := r.currentAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	req.Header.Set("User-Agent", r.userAgent)
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.apiKey))
+	for k, v := range HeadersFromContext(ctx) {
+		req.Header.Set(k, v)
+	}
+
+	// Set after the context headers, which must not replace the API key or the correlation ID.
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = NewRequestID()
+	}
+	req.Header.Set(HeaderRequestID, requestID)
 
 	if opt.ContentType != "" {
 		req.Header.Set("Content-Type", opt.ContentType)
@@ -123,14 +226,185 @@ func (r *Request) BuildRequest(ctx context.Context, method string, uri *url.URL,
 	return req, nil
 }
 
+// currentAPIKey returns the API key from the credential source, or the static key if there is none.
+func (r *Request) currentAPIKey(ctx context.Context) (string, error) {
+	if r.credentials == nil {
+		return r.apiKey, nil
+	}
+
+	key, err := r.credentials.APIKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get API key: %w", err)
+	}
+	return key, nil
+}
+
 // Do executes the HTTP request and decodes the response into v if provided.
+//
+// When the Request has several endpoints, Do fails over to the next endpoint on transport
+// errors and 5xx responses, and remembers the endpoint that answered for subsequent calls.
+// Requests with a non-idempotent method, such as POST, only fail over when the connection could
+// not be established, as the server may have acted on them.
 func (r *Request) Do(ctx context.Context, req *http.Request, v any) error {
-	r.logger.Debug(ctx, "Request: ", "method", req.Method, "url", req.URL.String())
+	start := time.Now()
+	targets := r.targets(ctx)
+	requestID := req.Header.Get(HeaderRequestID)
+
+	var lastErr error
+	for i, target := range targets {
+		attemptReq, err := r.requestFor(ctx, req, target)
+		if err != nil {
+			return err
+		}
+
+		r.logger.Debug(ctx, "Request: ", "method", attemptReq.Method, "url", RedactURL(attemptReq.URL), "endpoint", target.String(), "attempt", i+1,
+			"request_id", requestID)
+		if r.dumpHTTP {
+			r.dumpRequest(ctx, attemptReq, requestID)
+		}
+
+		resp, err := r.send(ctx, attemptReq, requestID)
+		last := i == len(targets)-1
+
+		if err != nil {
+			err = redactError(err)
+			if last || ctx.Err() != nil || !canFailOver(attemptReq.Method, err) {
+				r.logger.Error(ctx, "Request failed: ", "endpoint", target.String(), "request_id", requestID, "error", err)
+				recordResponseMeta(ctx, attemptReq, nil, target.String(), i+1, start)
+				return err
+			}
+			r.logger.Warn(ctx, "Endpoint failed, failing over: ", "endpoint", target.String(), "request_id", requestID, "error", err)
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode >= http.StatusInternalServerError && !last && isIdempotent(attemptReq.Method) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			r.logger.Warn(ctx, "Endpoint failed, failing over: ", "endpoint", target.String(), "request_id", requestID, "status", resp.StatusCode)
+			lastErr = &APIError{StatusCode: resp.StatusCode, RequestID: requestID}
+			continue
+		}
+
+		if r.endpoints != nil && resp.StatusCode < http.StatusInternalServerError {
+			r.endpoints.MarkHealthy(target)
+		}
+
+		recordResponseMeta(ctx, attemptReq, resp, target.String(), i+1, start)
+		if r.dumpHTTP {
+			r.dumpResponse(ctx, resp, requestID)
+		}
+
+		return r.handleResponse(ctx, resp, requestID, v)
+	}
+
+	return lastErr
+}
+
+// targets returns the base URLs to try for a request, in order.
+func (r *Request) targets(ctx context.Context) []*url.URL {
+	if r.endpoints == nil {
+		return []*url.URL{r.baseURL}
+	}
+
+	return r.endpoints.Candidates(ctx)
+}
+
+// send executes req. If the server answers 401 and the credential source can refresh its
+// API key, the key is refreshed once and the request is retried with it.
+func (r *Request) send(ctx context.Context, req *http.Request, requestID string) (*http.Response, error) {
 	resp, err := r.httpClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	refresher, ok := r.credentials.(credentials.Refresher)
+	if !ok {
+		return resp, nil
+	}
+
+	key, rErr := refresher.Refresh(ctx)
+	if rErr != nil {
+		r.logger.Warn(ctx, "Failed to refresh API key: ", "request_id", requestID, "error", rErr)
+		return resp, nil
+	}
+	if "Bearer "+key == req.Header.Get("Authorization") {
+		return resp, nil
+	}
+
+	retry, cErr := cloneRequest(ctx, req)
+	if cErr != nil {
+		return resp, nil //nolint:nilerr // reason: the original 401 response is the meaningful outcome
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	r.logger.Debug(ctx, "Retrying with refreshed API key: ", "request_id", requestID)
+	retry.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	return r.httpClient.Do(retry)
+}
+
+// requestFor returns req rewritten to target the given base URL.
+func (r *Request) requestFor(ctx context.Context, req *http.Request, target *url.URL) (*http.Request, error) {
+	if target == r.baseURL {
+		return req, nil
+	}
+
+	out, err := cloneRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out.URL = rebaseURL(req.URL, r.baseURL, target)
+	out.Host = ""
+
+	return out, nil
+}
+
+// cloneRequest returns a copy of req with a fresh body, so that it can be sent again.
+func cloneRequest(ctx context.Context, req *http.Request) (*http.Request, error) {
+	out := req.Clone(ctx)
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+
+	return out, nil
+}
+
+// dumpRequest logs the headers and body of req with secrets redacted.
+func (r *Request) dumpRequest(ctx context.Context, req *http.Request, requestID string) {
+	var body []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(rc)
+			_ = rc.Close()
+		}
+	}
+
+	r.logger.Debug(ctx, "HTTP request dump: ", "method", req.Method, "url", RedactURL(req.URL), "request_id", requestID,
+		"headers", RedactHeaders(req.Header), "body", RedactBody(body))
+}
+
+// dumpResponse logs the headers and body of resp with secrets redacted, leaving the body readable.
+func (r *Request) dumpResponse(ctx context.Context, resp *http.Response, requestID string) {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
 	if err != nil {
-		return err
+		r.logger.Debug(ctx, "HTTP response dump failed: ", "status", resp.StatusCode, "request_id", requestID, "error", err)
+		return
 	}
 
+	r.logger.Debug(ctx, "HTTP response dump: ", "status", resp.StatusCode, "request_id", requestID,
+		"headers", RedactHeaders(resp.Header), "body", RedactBody(body))
+}
+
+// handleResponse checks the status of resp and decodes its body into v if provided.
+func (r *Request) handleResponse(ctx context.Context, resp *http.Response, requestID string, v any) error {
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
@@ -138,21 +412,26 @@ func (r *Request) Do(ctx context.Context, req *http.Request, v any) error {
 		var bodyStr string
 		if rErr == nil {
 			bodyStr = string(respBody)
-			r.logger.Error(ctx, "Response: ", "status", resp.StatusCode, "body", bodyStr)
+			r.logger.Error(ctx, "Response: ", "status", resp.StatusCode, "request_id", requestID, "body", bodyStr)
 		} else {
-			r.logger.Error(ctx, "Failed to read response body: ", "status", resp.StatusCode, "error", rErr)
+			r.logger.Error(ctx, "Failed to read response body: ", "status", resp.StatusCode, "request_id", requestID, "error", rErr)
 		}
 		return &APIError{
 			StatusCode: resp.StatusCode,
 			Body:       bodyStr,
+			RequestID:  requestID,
 		}
 	}
 
 	if v != nil {
-		err = json.NewDecoder(resp.Body).Decode(v)
+		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return err
 		}
+		codec := compat.NewCodec(r.ServerVersion(), compat.Options{Strict: r.strict})
+		if err := codec.Decode(body, v); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -163,6 +442,29 @@ type RequestConfig struct {
 	UserAgent  *string
 	Logger     logger.Logger
 	HTTPClient *http.Client
+
+	// FallbackURLs are tried in order when the base URL fails with a transport error or a 5xx status.
+	// Non-idempotent requests only fail over when the connection fails.
+	FallbackURLs []*url.URL
+
+	// HealthChecker probes the base URL after a failover. Defaults to an HTTPHealthChecker.
+	HealthChecker HealthChecker
+
+	// ProbeInterval is the minimum interval between probes of the base URL. Defaults to DefaultProbeInterval.
+	ProbeInterval *time.Duration
+
+	// DumpHTTP logs request and response headers and bodies at debug level, with secrets redacted.
+	DumpHTTP bool
+
+	// StrictDecoding makes Do return a *compat.SchemaDriftError when a response has unknown
+	// members or lacks required ones. The response is still decoded into v.
+	StrictDecoding bool
+
+	// Credentials provides the API key for each request. When set, it replaces the static API key.
+	Credentials credentials.Source
+
+	// ServerVersion is the Headscale version of the server, if known.
+	ServerVersion versions.ServerVersion
 }
 
 // NewRequest creates a new Request instance with the given configuration.
@@ -179,12 +481,33 @@ func NewRequest(baseURL *url.URL, apiKey string, apiVersion versions.APIVersion,
 		opt.HTTPClient = httpClient
 	}
 
-	return &Request{
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		apiVersion: apiVersion,
-		userAgent:  *opt.UserAgent,
-		logger:     opt.Logger,
-		httpClient: opt.HTTPClient,
+	r := &Request{
+		baseURL:     baseURL,
+		apiKey:      apiKey,
+		credentials: opt.Credentials,
+		apiVersion:  apiVersion,
+		userAgent:   *opt.UserAgent,
+		logger:      opt.Logger,
+		httpClient:  opt.HTTPClient,
+		dumpHTTP:    opt.DumpHTTP,
+		strict:      opt.StrictDecoding,
+
+		serverVersion: opt.ServerVersion,
 	}
+
+	if len(opt.FallbackURLs) > 0 {
+		if opt.HealthChecker == nil {
+			opt.HealthChecker = &HTTPHealthChecker{Client: opt.HTTPClient}
+		}
+
+		var probeInterval time.Duration
+		if opt.ProbeInterval != nil {
+			probeInterval = *opt.ProbeInterval
+		}
+
+		endpoints := append([]*url.URL{baseURL}, opt.FallbackURLs...)
+		r.endpoints = NewEndpointPool(endpoints, opt.HealthChecker, probeInterval)
+	}
+
+	return r
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/hibare/headscale-client-go/versions"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -30,3 +31,20 @@ func (m *MockRequest) Do(ctx context.Context, req *http.Request, v any) error {
 	args := m.Called(ctx, req, v)
 	return args.Error(0)
 }
+
+// BuildServerURL is a mock for the BuildServerURL method.
+func (m *MockRequest) BuildServerURL(pathParts ...any) *url.URL {
+	args := m.Called(pathParts...)
+	return args.Get(0).(*url.URL) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// ServerVersion is a mock for the ServerVersion method.
+func (m *MockRequest) ServerVersion() versions.ServerVersion {
+	args := m.Called()
+	return args.Get(0).(versions.ServerVersion) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// SetServerVersion is a mock for the SetServerVersion method.
+func (m *MockRequest) SetServerVersion(v versions.ServerVersion) {
+	m.Called(v)
+}
@@ -1,12 +1,15 @@
 package requests
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
 
+	"github.com/hibare/headscale-client-go/credentials"
 	"github.com/hibare/headscale-client-go/logger"
 	"github.com/hibare/headscale-client-go/versions"
 	"github.com/stretchr/testify/require"
@@ -187,3 +190,114 @@ func TestBuildRequest_InvalidBody(t *testing.T) {
 	_, err = r.BuildRequest(t.Context(), http.MethodPost, uri, opt)
 	require.Error(t, err)
 }
+
+// rotatingSource is a credential source whose key changes on Refresh.
+type rotatingSource struct {
+	keys      []string
+	refreshes int
+}
+
+func (s *rotatingSource) APIKey(_ context.Context) (string, error) {
+	return s.keys[s.refreshes], nil
+}
+
+func (s *rotatingSource) Refresh(_ context.Context) (string, error) {
+	if s.refreshes < len(s.keys)-1 {
+		s.refreshes++
+	}
+	return s.keys[s.refreshes], nil
+}
+
+// TestDo_RefreshOnUnauthorized checks that a 401 triggers one credential refresh and a retry with the new key.
+func TestDo_RefreshOnUnauthorized(t *testing.T) {
+	var auths, bodies []string
+	h := func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		auths = append(auths, req.Header.Get("Authorization"))
+		bodies = append(bodies, string(body))
+		if req.Header.Get("Authorization") != "Bearer new-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"foo":"bar"}`))
+	}
+	ts := httptest.NewServer(http.HandlerFunc(h))
+	defer ts.Close()
+
+	newRequest := func(source credentials.Source) RequestInterface {
+		baseURL, _ := url.Parse(ts.URL + "/")
+		return NewRequest(baseURL, "", versions.APIVersionV1, RequestConfig{
+			Logger:      logger.NewDefaultLogger(logger.LevelError),
+			HTTPClient:  ts.Client(),
+			Credentials: source,
+		})
+	}
+
+	t.Run("refreshed key is retried", func(t *testing.T) {
+		auths, bodies = nil, nil
+		r := newRequest(&rotatingSource{keys: []string{"old-key", "new-key"}})
+		req, err := r.BuildRequest(t.Context(), http.MethodPost, r.BuildURL("foo"), RequestOptions{Body: map[string]string{"a": "b"}})
+		require.NoError(t, err)
+
+		var resp struct{ Foo string }
+		require.NoError(t, r.Do(t.Context(), req, &resp))
+		require.Equal(t, "bar", resp.Foo)
+		require.Equal(t, []string{"Bearer old-key", "Bearer new-key"}, auths)
+		require.Equal(t, bodies[0], bodies[1], "body is resent on retry")
+	})
+
+	t.Run("unchanged key is not retried", func(t *testing.T) {
+		auths = nil
+		r := newRequest(&rotatingSource{keys: []string{"old-key"}})
+		req, err := r.BuildRequest(t.Context(), http.MethodGet, r.BuildURL("foo"), RequestOptions{})
+		require.NoError(t, err)
+
+		var apiErr *APIError
+		require.ErrorAs(t, r.Do(t.Context(), req, nil), &apiErr)
+		require.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+		require.Len(t, auths, 1)
+	})
+
+	t.Run("static source is not retried", func(t *testing.T) {
+		auths = nil
+		r := newRequest(credentials.NewStaticSource("old-key"))
+		req, err := r.BuildRequest(t.Context(), http.MethodGet, r.BuildURL("foo"), RequestOptions{})
+		require.NoError(t, err)
+
+		require.Error(t, r.Do(t.Context(), req, nil))
+		require.Len(t, auths, 1)
+	})
+}
+
+// TestBuildRequest_CredentialsError checks that a failing credential source fails the request.
+func TestBuildRequest_CredentialsError(t *testing.T) {
+	baseURL, _ := url.Parse("http://example.com/")
+	r := NewRequest(baseURL, "", versions.APIVersionV1, RequestConfig{
+		Logger:      logger.NewDefaultLogger(logger.LevelError),
+		Credentials: credentials.NewStaticSource(""),
+	})
+
+	_, err := r.BuildRequest(t.Context(), http.MethodGet, r.BuildURL("foo"), RequestOptions{})
+	require.ErrorIs(t, err, credentials.ErrEmptyAPIKey)
+}
+
+// plainRequest implements RequestInterface only, as implementations written before
+// ServerRequestInterface do.
+type plainRequest struct {
+	RequestInterface
+}
+
+func TestServerRequestInterface_Fallback(t *testing.T) {
+	baseURL, err := url.Parse("http://example.com/headscale")
+	require.NoError(t, err)
+	r := NewRequest(baseURL, TestAPIKey, versions.APIVersionV1, RequestConfig{ServerVersion: versions.ServerVersion{Minor: 27}})
+
+	require.Equal(t, versions.ServerVersion{Minor: 27}, ServerVersion(r))
+	require.Equal(t, "http://example.com/headscale/health", BuildServerURL(r, "health").String())
+
+	plain := plainRequest{RequestInterface: r}
+	require.True(t, ServerVersion(plain).IsZero(), "the version is unknown")
+	require.Equal(t, "http://example.com/headscale/health", BuildServerURL(plain, "health").String())
+	SetServerVersion(plain, versions.ServerVersion{Minor: 28})
+	require.Equal(t, versions.ServerVersion{Minor: 27}, ServerVersion(r), "nothing is recorded")
+}
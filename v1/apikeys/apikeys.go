@@ -3,15 +3,21 @@ package apikeys
 
 import (
 	"context"
+	"encoding/json"
+	"iter"
 	"net/http"
 	"time"
 
+	"github.com/hibare/headscale-client-go/compat"
+	"github.com/hibare/headscale-client-go/iterate"
 	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
 )
 
 // APIKeyResourceInterface is an interface for managing API keys in Headscale.
 type APIKeyResourceInterface interface {
 	List(ctx context.Context) (APIKeysResponse, error)
+	All(ctx context.Context) iter.Seq2[APIKey, error]
 	Create(ctx context.Context, createAPIKeyRequest CreateAPIKeyRequest) (CreateAPIKeyResponse, error)
 	Expire(ctx context.Context, prefix string) error
 	ExpireByID(ctx context.Context, id string) error
@@ -30,11 +36,31 @@ func NewAPIKeyResource(r requests.RequestInterface) *APIKeyResource {
 
 // APIKey represents an API key in Headscale.
 type APIKey struct {
-	ID         string    `json:"id"`
-	Prefix     string    `json:"prefix"`
+	ID         string    `json:"id" schema:"required"`
+	Prefix     string    `json:"prefix" schema:"required"`
 	Expiration time.Time `json:"expiration"`
 	CreatedAt  time.Time `json:"createdAt"`
 	LastSeen   time.Time `json:"lastSeen"`
+
+	// Extra holds response members without a field, e.g. those added by newer Headscale releases.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes an APIKey, keeping unknown members in Extra.
+func (a *APIKey) UnmarshalJSON(data []byte) error {
+	type apiKey APIKey
+	extra, err := compat.UnmarshalExtra(data, (*apiKey)(a))
+	if err != nil {
+		return err
+	}
+	a.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes an APIKey, including the members in Extra.
+func (a APIKey) MarshalJSON() ([]byte, error) {
+	type apiKey APIKey
+	return compat.MarshalExtra(apiKey(a), a.Extra)
 }
 
 // APIKeysResponse represents a list of API keys response from the API.
@@ -56,6 +82,14 @@ func (a *APIKeyResource) List(ctx context.Context) (APIKeysResponse, error) {
 	return keys, err
 }
 
+// All returns an iterator over all API keys. The list is fetched when iteration starts.
+func (a *APIKeyResource) All(ctx context.Context) iter.Seq2[APIKey, error] {
+	return iterate.Fetch(func() ([]APIKey, error) {
+		resp, err := a.List(ctx)
+		return resp.APIKeys, err
+	})
+}
+
 // CreateAPIKeyRequest represents a request to create a new API key.
 type CreateAPIKeyRequest struct {
 	Expiration time.Time `json:"expiration"`
@@ -105,6 +139,10 @@ func (a *APIKeyResource) Expire(ctx context.Context, prefix string) error {
 
 // ExpireByID expires an API key by ID in Headscale.
 func (a *APIKeyResource) ExpireByID(ctx context.Context, id string) error {
+	if err := requests.ServerVersion(a.r).Require(versions.CapabilityAPIKeyExpireByID); err != nil {
+		return err
+	}
+
 	url := a.r.BuildURL("apikey", "expire")
 	req, err := a.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{
 		Body: ExpireAPIKeyRequest{
@@ -2,6 +2,7 @@ package apikeys
 
 import (
 	"context"
+	"iter"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -17,6 +18,12 @@ func (m *MockAPIKeyResource) List(ctx context.Context) (APIKeysResponse, error)
 	return args.Get(0).(APIKeysResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// All returns a mock iterator over API keys.
+func (m *MockAPIKeyResource) All(ctx context.Context) iter.Seq2[APIKey, error] {
+	args := m.Called(ctx)
+	return args.Get(0).(iter.Seq2[APIKey, error]) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // Create creates a mock API key from the Headscale.
 func (m *MockAPIKeyResource) Create(ctx context.Context, createAPIKeyRequest CreateAPIKeyRequest) (CreateAPIKeyResponse, error) {
 	args := m.Called(ctx, createAPIKeyRequest)
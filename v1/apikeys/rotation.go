@@ -0,0 +1,435 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibare/headscale-client-go/credentials"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+const (
+	// DefaultRotationWindow is the default time before expiry at which a key is rotated.
+	DefaultRotationWindow = 14 * 24 * time.Hour
+
+	// DefaultKeyLifetime is the default lifetime of a successor key.
+	DefaultKeyLifetime = 90 * 24 * time.Hour
+
+	// DefaultGracePeriod is the default delay before the previous key is expired.
+	DefaultGracePeriod = 5 * time.Minute
+
+	// DefaultCheckInterval is the default interval between expiry checks in Run.
+	DefaultCheckInterval = time.Hour
+
+	// expiryRetryDelay is the delay before a failed expiry of a previous key is first retried. The
+	// delay doubles with each failure, up to maxExpiryRetryDelay.
+	expiryRetryDelay = time.Minute
+
+	// maxExpiryRetryDelay is the longest delay between retries of a failed expiry.
+	maxExpiryRetryDelay = time.Hour
+
+	// keyFilePerm is the permission of files written by FileStore.
+	keyFilePerm = 0o600
+
+	// newKeyFormatPrefix is the prefix of API keys in the hskey-api-<prefix>-<secret> format.
+	newKeyFormatPrefix = "hskey-api-"
+)
+
+var (
+	// ErrKeyNotFound is returned when the API key in use is not in the server's key list.
+	ErrKeyNotFound = errors.New("API key not found")
+
+	// ErrCredentialsRequired is returned when a RotationManager has no credential source.
+	ErrCredentialsRequired = errors.New("credentials are required")
+)
+
+// KeyStore persists a new API key so that it survives restarts.
+type KeyStore interface {
+	Save(ctx context.Context, key string) error
+}
+
+// KeyStoreFunc is an adapter to allow the use of ordinary functions as KeyStore.
+type KeyStoreFunc func(ctx context.Context, key string) error
+
+// Save calls f(ctx, key).
+func (f KeyStoreFunc) Save(ctx context.Context, key string) error {
+	return f(ctx, key)
+}
+
+// FileStore is a KeyStore that writes the API key to a file readable only by its owner.
+//
+// The file is replaced atomically, so readers such as credentials.FileSource never see a partial key.
+type FileStore struct {
+	Path string
+}
+
+// Save writes key to the file.
+func (s FileStore) Save(_ context.Context, key string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // reason: the temp file is gone after a successful rename
+
+	if err := tmp.Chmod(keyFilePerm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.WriteString(key + "\n"); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// RotationEventType identifies a step of a key rotation.
+type RotationEventType string
+
+const (
+	// EventKeyChecked is emitted after the expiry of the key in use was checked.
+	EventKeyChecked RotationEventType = "key_checked"
+
+	// EventKeyCreated is emitted after a successor key was created.
+	EventKeyCreated RotationEventType = "key_created"
+
+	// EventKeyPersisted is emitted after the successor key was saved to the KeyStore.
+	EventKeyPersisted RotationEventType = "key_persisted"
+
+	// EventKeySwitched is emitted after the client switched to the successor key.
+	EventKeySwitched RotationEventType = "key_switched"
+
+	// EventKeyExpired is emitted after the previous key was expired.
+	EventKeyExpired RotationEventType = "key_expired"
+
+	// EventRotationFailed is emitted when a step fails. Err holds the cause.
+	EventRotationFailed RotationEventType = "rotation_failed"
+)
+
+// RotationEvent describes a step of a key rotation. Key secrets are never included.
+type RotationEvent struct {
+	Type RotationEventType
+
+	// Prefix is the prefix of the key the event is about.
+	Prefix string
+
+	// Expiration is the expiration of the key the event is about, if known.
+	Expiration time.Time
+
+	Err  error
+	Time time.Time
+}
+
+// RotationOptions contains options for creating a RotationManager.
+type RotationOptions struct {
+	// Window is how long before expiry the key is rotated. Defaults to DefaultRotationWindow.
+	Window time.Duration
+
+	// Lifetime is the lifetime of successor keys. Defaults to DefaultKeyLifetime.
+	Lifetime time.Duration
+
+	// GracePeriod is how long the previous key stays valid after the switch, so that other
+	// processes sharing it can pick up the successor. Defaults to DefaultGracePeriod; a negative
+	// value expires the previous key immediately.
+	GracePeriod time.Duration
+
+	// CheckInterval is the interval between expiry checks in Run. Defaults to DefaultCheckInterval.
+	CheckInterval time.Duration
+
+	// Store persists successor keys. Optional.
+	Store KeyStore
+
+	// OnEvent is called for every rotation step. Optional.
+	OnEvent func(RotationEvent)
+}
+
+// pendingExpiry is a previous key waiting for its grace period to end, or for the retry of a
+// failed expiry.
+type pendingExpiry struct {
+	key      APIKey
+	at       time.Time
+	failures int
+}
+
+// RotationManager keeps the API key used by a client from expiring by rotating it before expiry.
+//
+// The client must use the manager's credential source, and resource must be the client's
+// APIKeyResource, so that the manager authenticates with the key it manages.
+type RotationManager struct {
+	keys   APIKeyResourceInterface
+	source *credentials.MutableSource
+	opt    RotationOptions
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending []pendingExpiry
+}
+
+// NewRotationManager creates a new RotationManager for the key held by source.
+func NewRotationManager(keys APIKeyResourceInterface, source *credentials.MutableSource, opt RotationOptions) (*RotationManager, error) {
+	if source == nil {
+		return nil, ErrCredentialsRequired
+	}
+
+	if opt.Window <= 0 {
+		opt.Window = DefaultRotationWindow
+	}
+	if opt.Lifetime <= 0 {
+		opt.Lifetime = DefaultKeyLifetime
+	}
+	if opt.GracePeriod < 0 {
+		opt.GracePeriod = 0
+	} else if opt.GracePeriod == 0 {
+		opt.GracePeriod = DefaultGracePeriod
+	}
+	if opt.CheckInterval <= 0 {
+		opt.CheckInterval = DefaultCheckInterval
+	}
+
+	return &RotationManager{
+		keys:   keys,
+		source: source,
+		opt:    opt,
+		now:    time.Now,
+	}, nil
+}
+
+// Check expires previous keys whose grace period has ended, then rotates the key in use if it
+// expires within the rotation window. It reports whether the key was rotated.
+//
+// A failed expiry of a previous key does not stop the check of the key in use; its error is
+// joined with the result.
+func (m *RotationManager) Check(ctx context.Context) (bool, error) {
+	expireErr := m.expireDue(ctx)
+
+	current, err := m.currentKey(ctx)
+	if err != nil {
+		return false, errors.Join(expireErr, m.fail(current, err))
+	}
+	m.emit(RotationEvent{Type: EventKeyChecked, Prefix: current.Prefix, Expiration: current.Expiration})
+
+	if current.Expiration.IsZero() || current.Expiration.Sub(m.now()) > m.opt.Window {
+		return false, expireErr
+	}
+
+	rotated, err := m.rotate(ctx, current)
+	return rotated, errors.Join(expireErr, err)
+}
+
+// Rotate replaces the key in use with a successor regardless of its expiry.
+func (m *RotationManager) Rotate(ctx context.Context) error {
+	current, err := m.currentKey(ctx)
+	if err != nil {
+		return m.fail(current, err)
+	}
+
+	_, err = m.rotate(ctx, current)
+	return err
+}
+
+// Run calls Check immediately and then every CheckInterval, and expires previous keys when their
+// grace period ends, until ctx is cancelled. Failures are reported through OnEvent and do not stop Run.
+// A failed expiry is retried with a growing delay.
+//
+// Previous keys still in their grace period when ctx is cancelled are left to expire naturally.
+func (m *RotationManager) Run(ctx context.Context) error {
+	nextCheck := m.now()
+	for {
+		if !m.now().Before(nextCheck) {
+			_, _ = m.Check(ctx)
+			nextCheck = m.now().Add(m.opt.CheckInterval)
+		} else {
+			_ = m.expireDue(ctx)
+		}
+
+		wake := nextCheck
+		if at, ok := m.nextExpiry(); ok && at.Before(wake) {
+			wake = at
+		}
+
+		timer := time.NewTimer(wake.Sub(m.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rotate creates, persists and switches to a successor of current, and schedules current for expiry.
+// It reports whether the client was switched to the successor.
+func (m *RotationManager) rotate(ctx context.Context, current APIKey) (bool, error) {
+	expiration := m.now().Add(m.opt.Lifetime)
+	created, err := m.keys.Create(ctx, CreateAPIKeyRequest{Expiration: expiration})
+	if err != nil {
+		return false, m.fail(current, fmt.Errorf("create successor key: %w", err))
+	}
+	successor := created.APIKey
+	successorPrefix := keyPrefix(successor)
+	m.emit(RotationEvent{Type: EventKeyCreated, Prefix: successorPrefix, Expiration: expiration})
+
+	if m.opt.Store != nil {
+		if err := m.opt.Store.Save(ctx, successor); err != nil {
+			// The successor is unusable if it cannot be persisted, so do not leave it valid.
+			if expErr := m.keys.Expire(ctx, successorPrefix); expErr != nil {
+				err = errors.Join(err, fmt.Errorf("expire unsaved successor key: %w", expErr))
+			}
+			return false, m.fail(current, fmt.Errorf("persist successor key: %w", err))
+		}
+		m.emit(RotationEvent{Type: EventKeyPersisted, Prefix: successorPrefix, Expiration: expiration})
+	}
+
+	m.source.Set(successor)
+	m.emit(RotationEvent{Type: EventKeySwitched, Prefix: successorPrefix, Expiration: expiration})
+
+	m.mu.Lock()
+	m.pending = append(m.pending, pendingExpiry{key: current, at: m.now().Add(m.opt.GracePeriod)})
+	m.mu.Unlock()
+
+	return true, m.expireDue(ctx)
+}
+
+// expireDue expires the previous keys whose grace period has ended.
+func (m *RotationManager) expireDue(ctx context.Context) error {
+	m.mu.Lock()
+	var due []pendingExpiry
+	remaining := m.pending[:0]
+	for _, p := range m.pending {
+		if m.now().Before(p.at) {
+			remaining = append(remaining, p)
+		} else {
+			due = append(due, p)
+		}
+	}
+	m.pending = remaining
+	m.mu.Unlock()
+
+	var errs []error
+	for _, p := range due {
+		if err := m.expire(ctx, p.key); err != nil {
+			p.at = m.now().Add(retryDelay(p.failures))
+			p.failures++
+			m.mu.Lock()
+			m.pending = append(m.pending, p)
+			m.mu.Unlock()
+			errs = append(errs, m.fail(p.key, fmt.Errorf("expire previous key: %w", err)))
+			continue
+		}
+		m.emit(RotationEvent{Type: EventKeyExpired, Prefix: p.key.Prefix, Expiration: p.key.Expiration})
+	}
+
+	return errors.Join(errs...)
+}
+
+// expire expires key by ID, or by prefix on servers that cannot expire keys by ID.
+func (m *RotationManager) expire(ctx context.Context, key APIKey) error {
+	err := m.keys.ExpireByID(ctx, key.ID)
+	if errors.Is(err, versions.ErrUnsupportedByServer) {
+		return m.keys.Expire(ctx, key.Prefix)
+	}
+	return err
+}
+
+// retryDelay returns the delay before retrying an expiry that failed failures times before.
+func retryDelay(failures int) time.Duration {
+	delay := expiryRetryDelay
+	for range failures {
+		delay *= 2
+		if delay >= maxExpiryRetryDelay {
+			return maxExpiryRetryDelay
+		}
+	}
+	return delay
+}
+
+// nextExpiry returns the earliest end of a grace period, if any key is pending.
+func (m *RotationManager) nextExpiry() (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next time.Time
+	for _, p := range m.pending {
+		if next.IsZero() || p.at.Before(next) {
+			next = p.at
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// currentKey returns the server's record of the key held by the credential source.
+func (m *RotationManager) currentKey(ctx context.Context) (APIKey, error) {
+	secret, err := m.source.APIKey(ctx)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	resp, err := m.keys.List(ctx)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("list API keys: %w", err)
+	}
+
+	key, ok := findKey(resp.APIKeys, secret)
+	if !ok {
+		return APIKey{}, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// fail emits a failure event for key and returns err.
+func (m *RotationManager) fail(key APIKey, err error) error {
+	m.emit(RotationEvent{Type: EventRotationFailed, Prefix: key.Prefix, Expiration: key.Expiration, Err: err})
+	return err
+}
+
+// emit sends e to the OnEvent callback, if any.
+func (m *RotationManager) emit(e RotationEvent) {
+	if m.opt.OnEvent == nil {
+		return
+	}
+	e.Time = m.now()
+	m.opt.OnEvent(e)
+}
+
+// findKey returns the listed key whose prefix matches secret. The longest matching prefix wins.
+func findKey(keys []APIKey, secret string) (APIKey, bool) {
+	var (
+		found APIKey
+		ok    bool
+	)
+	for _, k := range keys {
+		if k.Prefix == "" || !matchesPrefix(secret, k.Prefix) {
+			continue
+		}
+		if !ok || len(k.Prefix) > len(found.Prefix) {
+			found, ok = k, true
+		}
+	}
+	return found, ok
+}
+
+// matchesPrefix reports whether secret belongs to the key with the given prefix. Headscale lists
+// keys of the hskey-api-<prefix>-<secret> format either with or without the format marker.
+func matchesPrefix(secret, prefix string) bool {
+	return strings.HasPrefix(secret, prefix) || strings.HasPrefix(strings.TrimPrefix(secret, newKeyFormatPrefix), prefix)
+}
+
+// keyPrefix returns the public prefix of secret, as Headscale lists it.
+func keyPrefix(secret string) string {
+	if rest, ok := strings.CutPrefix(secret, newKeyFormatPrefix); ok {
+		prefix, _, _ := strings.Cut(rest, "-")
+		return prefix
+	}
+
+	prefix, _, _ := strings.Cut(secret, ".")
+	return prefix
+}
@@ -0,0 +1,239 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/credentials"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type rotationTest struct {
+	keys    *MockAPIKeyResource
+	source  *credentials.MutableSource
+	manager *RotationManager
+	now     time.Time
+	events  []RotationEventType
+	saved   []string
+}
+
+func newRotationTest(t *testing.T, opt RotationOptions) *rotationTest {
+	t.Helper()
+	rt := &rotationTest{
+		keys:   new(MockAPIKeyResource),
+		source: credentials.NewMutableSource("oldprefix.secret"),
+		now:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	opt.OnEvent = func(e RotationEvent) { rt.events = append(rt.events, e.Type) }
+	if opt.Store == nil {
+		opt.Store = KeyStoreFunc(func(_ context.Context, key string) error {
+			rt.saved = append(rt.saved, key)
+			return nil
+		})
+	}
+
+	m, err := NewRotationManager(rt.keys, rt.source, opt)
+	require.NoError(t, err)
+	m.now = func() time.Time { return rt.now }
+	rt.manager = m
+	return rt
+}
+
+func (rt *rotationTest) listKeys(expiration time.Time) {
+	rt.keys.On("List", mock.Anything).Return(APIKeysResponse{APIKeys: []APIKey{
+		{ID: "1", Prefix: "other", Expiration: rt.now},
+		{ID: "2", Prefix: "oldprefix", Expiration: expiration},
+	}}, nil)
+}
+
+func (rt *rotationTest) currentKey(t *testing.T) string {
+	t.Helper()
+	key, err := rt.source.APIKey(t.Context())
+	require.NoError(t, err)
+	return key
+}
+
+func TestRotationManager_Check(t *testing.T) {
+	t.Run("key outside window is kept", func(t *testing.T) {
+		rt := newRotationTest(t, RotationOptions{Window: 24 * time.Hour})
+		rt.listKeys(rt.now.Add(48 * time.Hour))
+
+		rotated, err := rt.manager.Check(t.Context())
+		require.NoError(t, err)
+		assert.False(t, rotated)
+		assert.Equal(t, []RotationEventType{EventKeyChecked}, rt.events)
+		rt.keys.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("key within window is rotated", func(t *testing.T) {
+		rt := newRotationTest(t, RotationOptions{Window: 24 * time.Hour, Lifetime: 30 * 24 * time.Hour, GracePeriod: time.Minute})
+		rt.listKeys(rt.now.Add(time.Hour))
+		rt.keys.On("Create", mock.Anything, CreateAPIKeyRequest{Expiration: rt.now.Add(30 * 24 * time.Hour)}).
+			Return(CreateAPIKeyResponse{APIKey: "newprefix.secret"}, nil)
+		rt.keys.On("ExpireByID", mock.Anything, "2").Return(nil)
+
+		rotated, err := rt.manager.Check(t.Context())
+		require.NoError(t, err)
+		assert.True(t, rotated)
+		assert.Equal(t, "newprefix.secret", rt.currentKey(t))
+		assert.Equal(t, []string{"newprefix.secret"}, rt.saved)
+		rt.keys.AssertNotCalled(t, "ExpireByID", mock.Anything, mock.Anything)
+
+		rt.now = rt.now.Add(time.Minute)
+		require.NoError(t, rt.manager.expireDue(t.Context()))
+		rt.keys.AssertCalled(t, "ExpireByID", mock.Anything, "2")
+		assert.Equal(t, []RotationEventType{
+			EventKeyChecked, EventKeyCreated, EventKeyPersisted, EventKeySwitched, EventKeyExpired,
+		}, rt.events)
+	})
+
+	t.Run("failed expiry of a previous key does not stop rotation", func(t *testing.T) {
+		rt := newRotationTest(t, RotationOptions{Window: 24 * time.Hour})
+		rt.manager.pending = []pendingExpiry{{key: APIKey{ID: "1", Prefix: "other"}, at: rt.now}}
+		rt.listKeys(rt.now.Add(time.Hour))
+		rt.keys.On("ExpireByID", mock.Anything, "1").Return(errors.New("forbidden"))
+		rt.keys.On("Create", mock.Anything, mock.Anything).Return(CreateAPIKeyResponse{APIKey: "newprefix.secret"}, nil)
+
+		rotated, err := rt.manager.Check(t.Context())
+		require.ErrorContains(t, err, "forbidden")
+		assert.True(t, rotated)
+		assert.Equal(t, "newprefix.secret", rt.currentKey(t))
+	})
+
+	t.Run("key not found", func(t *testing.T) {
+		rt := newRotationTest(t, RotationOptions{})
+		rt.keys.On("List", mock.Anything).Return(APIKeysResponse{APIKeys: []APIKey{{ID: "1", Prefix: "other"}}}, nil)
+
+		_, err := rt.manager.Check(t.Context())
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		assert.Equal(t, []RotationEventType{EventRotationFailed}, rt.events)
+	})
+}
+
+func TestRotationManager_Rotate(t *testing.T) {
+	t.Run("failed persist expires the successor", func(t *testing.T) {
+		rt := newRotationTest(t, RotationOptions{Store: KeyStoreFunc(func(context.Context, string) error {
+			return errors.New("disk full")
+		})})
+		rt.listKeys(rt.now.Add(time.Hour))
+		rt.keys.On("Create", mock.Anything, mock.Anything).Return(CreateAPIKeyResponse{APIKey: "newprefix.secret"}, nil)
+		rt.keys.On("Expire", mock.Anything, "newprefix").Return(nil)
+
+		err := rt.manager.Rotate(t.Context())
+		require.ErrorContains(t, err, "disk full")
+		assert.Equal(t, "oldprefix.secret", rt.currentKey(t), "client keeps the old key")
+		rt.keys.AssertCalled(t, "Expire", mock.Anything, "newprefix")
+		assert.Equal(t, []RotationEventType{EventKeyCreated, EventRotationFailed}, rt.events)
+	})
+
+	t.Run("failed expiry is retried", func(t *testing.T) {
+		rt := newRotationTest(t, RotationOptions{GracePeriod: -1})
+		rt.listKeys(rt.now.Add(time.Hour))
+		rt.keys.On("Create", mock.Anything, mock.Anything).Return(CreateAPIKeyResponse{APIKey: "newprefix.secret"}, nil)
+		rt.keys.On("ExpireByID", mock.Anything, "2").Return(errors.New("unavailable")).Once()
+		rt.keys.On("ExpireByID", mock.Anything, "2").Return(nil).Once()
+
+		require.Error(t, rt.manager.Rotate(t.Context()))
+		assert.Equal(t, "newprefix.secret", rt.currentKey(t))
+
+		next, pending := rt.manager.nextExpiry()
+		require.True(t, pending)
+		assert.Equal(t, rt.now.Add(expiryRetryDelay), next, "the retry is delayed")
+		require.NoError(t, rt.manager.expireDue(t.Context()))
+		rt.keys.AssertNumberOfCalls(t, "ExpireByID", 1)
+
+		rt.now = next
+		require.NoError(t, rt.manager.expireDue(t.Context()))
+		rt.keys.AssertNumberOfCalls(t, "ExpireByID", 2)
+		_, pending = rt.manager.nextExpiry()
+		assert.False(t, pending)
+	})
+
+	t.Run("expiry falls back to the prefix", func(t *testing.T) {
+		rt := newRotationTest(t, RotationOptions{GracePeriod: -1})
+		rt.listKeys(rt.now.Add(time.Hour))
+		rt.keys.On("Create", mock.Anything, mock.Anything).Return(CreateAPIKeyResponse{APIKey: "newprefix.secret"}, nil)
+		rt.keys.On("ExpireByID", mock.Anything, "2").Return(versions.ErrUnsupportedByServer)
+		rt.keys.On("Expire", mock.Anything, "oldprefix").Return(nil)
+
+		require.NoError(t, rt.manager.Rotate(t.Context()))
+		rt.keys.AssertCalled(t, "Expire", mock.Anything, "oldprefix")
+		_, pending := rt.manager.nextExpiry()
+		assert.False(t, pending)
+	})
+}
+
+func TestRotationManager_Run(t *testing.T) {
+	rt := newRotationTest(t, RotationOptions{})
+	rt.listKeys(rt.now.Add(365 * 24 * time.Hour))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	rt.manager.opt.OnEvent = func(RotationEvent) { cancel() }
+
+	err := rt.manager.Run(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	rt.keys.AssertCalled(t, "List", mock.Anything)
+}
+
+func TestNewRotationManager_RequiresSource(t *testing.T) {
+	_, err := NewRotationManager(new(MockAPIKeyResource), nil, RotationOptions{})
+	require.ErrorIs(t, err, ErrCredentialsRequired)
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+
+	require.NoError(t, FileStore{Path: path}.Save(t.Context(), "new"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, expiryRetryDelay, retryDelay(0))
+	assert.Equal(t, 2*expiryRetryDelay, retryDelay(1))
+	assert.Equal(t, maxExpiryRetryDelay, retryDelay(10))
+	assert.Equal(t, maxExpiryRetryDelay, retryDelay(1000))
+}
+
+func TestFindKey(t *testing.T) {
+	keys := []APIKey{{ID: "1", Prefix: "abc"}, {ID: "2", Prefix: "abcdef"}, {ID: "3", Prefix: "x1y2z3"}}
+
+	tests := []struct {
+		name   string
+		secret string
+		wantID string
+	}{
+		{name: "legacy format", secret: "abcdef.secret", wantID: "2"},
+		{name: "hskey format", secret: "hskey-api-x1y2z3-secret", wantID: "3"},
+		{name: "no match", secret: "zzz.secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := findKey(keys, tt.secret)
+			assert.Equal(t, tt.wantID != "", ok)
+			assert.Equal(t, tt.wantID, key.ID)
+		})
+	}
+
+	assert.Equal(t, "x1y2z3", keyPrefix("hskey-api-x1y2z3-secret"))
+	assert.Equal(t, "abcdef", keyPrefix("abcdef.secret"))
+}
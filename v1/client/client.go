@@ -5,14 +5,18 @@ import (
 	"errors"
 	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/hibare/headscale-client-go/credentials"
 	"github.com/hibare/headscale-client-go/logger"
 	"github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/utils"
 	"github.com/hibare/headscale-client-go/v1/apikeys"
+	"github.com/hibare/headscale-client-go/v1/debug"
 	"github.com/hibare/headscale-client-go/v1/nodes"
 	"github.com/hibare/headscale-client-go/v1/policy"
 	"github.com/hibare/headscale-client-go/v1/preauthkeys"
+	"github.com/hibare/headscale-client-go/v1/server"
 	"github.com/hibare/headscale-client-go/v1/users"
 	"github.com/hibare/headscale-client-go/versions"
 )
@@ -20,6 +24,9 @@ import (
 var (
 	// ErrAPIKeyRequired is returned when an API key is required but not provided.
 	ErrAPIKeyRequired = errors.New("API key is required")
+
+	// ErrBaseURLRequired is returned when no base URL is provided.
+	ErrBaseURLRequired = errors.New("at least one base URL is required")
 )
 
 // ClientInterface defines the methods that a Headscale client must implement.
@@ -29,6 +36,8 @@ type ClientInterface interface {
 	Policy() policy.PolicyResourceInterface
 	Users() users.UserResourceInterface
 	PreAuthKeys() preauthkeys.PreAuthKeyResourceInterface
+	Server() server.ServerResourceInterface
+	Debug() debug.DebugResourceInterface
 }
 
 // Client is a struct that implements the HeadscaleClientInterface.
@@ -38,6 +47,8 @@ type Client struct {
 	policy      policy.PolicyResourceInterface
 	users       users.UserResourceInterface
 	preAuthKeys preauthkeys.PreAuthKeyResourceInterface
+	server      server.ServerResourceInterface
+	debug       debug.DebugResourceInterface
 }
 
 // APIKeys returns the APIKeyResource for managing API keys.
@@ -65,32 +76,108 @@ func (c *Client) PreAuthKeys() preauthkeys.PreAuthKeyResourceInterface {
 	return c.preAuthKeys
 }
 
+// Server returns the ServerResource for health, version and capability discovery.
+func (c *Client) Server() server.ServerResourceInterface {
+	return c.server
+}
+
+// Debug returns the DebugResource for creating fake nodes on test servers.
+func (c *Client) Debug() debug.DebugResourceInterface {
+	return c.debug
+}
+
 // ClientOptions contains options for the Headscale client.
 type ClientOptions struct {
 	HTTPClient *http.Client
 	UserAgent  *string
 	Logger     logger.Logger
 	LogLevel   *logger.LogLevel
+
+	// Timeout is the HTTP request timeout. Defaults to requests.DefaultHTTPClientTimeout, or to
+	// HTTPClient's own timeout if it has one. Zero disables the timeout. A custom HTTPClient is
+	// copied rather than modified.
+	Timeout *time.Duration
+
+	// TLS configures a custom CA, client certificates for mutual TLS, the server name and the
+	// minimum TLS version. Cannot be combined with HTTPClient.
+	TLS *TLSOptions
+
+	// ProxyURL is the HTTP, HTTPS or SOCKS5 proxy to connect through, e.g. "http://proxy:3128".
+	// Defaults to the proxy from the environment (HTTPS_PROXY, NO_PROXY). Cannot be combined with HTTPClient.
+	ProxyURL *string
+
+	// UnixSocket is the path of a unix domain socket to dial instead of the base URL's host.
+	// The base URL still sets the scheme and path, e.g. "http://headscale". Cannot be combined with HTTPClient.
+	UnixSocket *string
+
+	// HealthChecker probes the primary endpoint after a failover (multi-endpoint clients only).
+	HealthChecker requests.HealthChecker
+
+	// ProbeInterval is the minimum interval between probes of the primary endpoint (multi-endpoint clients only).
+	ProbeInterval *time.Duration
+
+	// DumpHTTP logs request and response headers and bodies at debug level.
+	// The Authorization header, API key secrets and pre-auth key values are redacted.
+	DumpHTTP bool
+
+	// Credentials provides the API key for each request, replacing the apiKey argument of NewClient.
+	// If it implements credentials.Refresher, the key is refreshed once when the server answers 401.
+	Credentials credentials.Source
+
+	// ServerVersion is the Headscale version of the server, e.g. "v0.28.0". When set, operations the
+	// version lacks fail with versions.ErrUnsupportedByServer. Otherwise Server().Info detects it.
+	ServerVersion *string
+
+	// StrictDecoding makes every call fail with a *compat.SchemaDriftError when a response has
+	// unknown members or lacks required ones. The response is still decoded. Use it to detect
+	// schema changes early when upgrading Headscale.
+	StrictDecoding bool
 }
 
 // NewClient creates a new Headscale client with the specified base URL and API key.
 func NewClient(baseURL, apiKey string, opt ClientOptions) (ClientInterface, error) {
-	u, err := url.Parse(baseURL)
+	return NewMultiEndpointClient([]string{baseURL}, apiKey, opt)
+}
+
+// NewMultiEndpointClient creates a new Headscale client that fails over between the given base URLs.
+//
+// The URLs are tried in order on transport errors and 5xx responses. The client sticks to the
+// endpoint that last answered and re-probes the first URL periodically to move back to it.
+// Non-idempotent requests are only retried on another URL when the connection fails.
+func NewMultiEndpointClient(baseURLs []string, apiKey string, opt ClientOptions) (ClientInterface, error) {
+	if len(baseURLs) == 0 {
+		return nil, ErrBaseURLRequired
+	}
+
+	endpoints := make([]*url.URL, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, u)
+	}
+
+	if apiKey == "" && opt.Credentials == nil {
+		return nil, ErrAPIKeyRequired
+	}
+
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+
+	httpClient, err := opt.buildHTTPClient()
 	if err != nil {
 		return nil, err
 	}
+	opt.HTTPClient = httpClient
 
-	if apiKey == "" {
-		return nil, ErrAPIKeyRequired
+	var serverVersion versions.ServerVersion
+	if opt.ServerVersion != nil {
+		serverVersion, _ = versions.ParseServerVersion(*opt.ServerVersion) // validated above
 	}
 
 	// Set default values if not provided
-	if opt.HTTPClient == nil {
-		opt.HTTPClient = &http.Client{Timeout: requests.DefaultHTTPClientTimeout}
-	} else if opt.HTTPClient.Timeout == 0 {
-		// Add a default timeout if not provided
-		opt.HTTPClient.Timeout = requests.DefaultHTTPClientTimeout
-	}
 
 	if opt.UserAgent == nil {
 		userAgent := requests.DefaultUserAgent
@@ -106,10 +193,17 @@ func NewClient(baseURL, apiKey string, opt ClientOptions) (ClientInterface, erro
 	}
 
 	// Create a new request with the given base URL, API key, and options
-	request := requests.NewRequest(u, apiKey, versions.APIVersionV1, requests.RequestConfig{
-		UserAgent:  opt.UserAgent,
-		Logger:     opt.Logger,
-		HTTPClient: opt.HTTPClient,
+	request := requests.NewRequest(endpoints[0], apiKey, versions.APIVersionV1, requests.RequestConfig{
+		UserAgent:      opt.UserAgent,
+		Logger:         opt.Logger,
+		HTTPClient:     opt.HTTPClient,
+		FallbackURLs:   endpoints[1:],
+		HealthChecker:  opt.HealthChecker,
+		ProbeInterval:  opt.ProbeInterval,
+		DumpHTTP:       opt.DumpHTTP,
+		StrictDecoding: opt.StrictDecoding,
+		Credentials:    opt.Credentials,
+		ServerVersion:  serverVersion,
 	})
 
 	c := &Client{
@@ -118,6 +212,8 @@ func NewClient(baseURL, apiKey string, opt ClientOptions) (ClientInterface, erro
 		policy:      policy.NewPolicyResource(request),
 		users:       users.NewUserResource(request),
 		preAuthKeys: preauthkeys.NewPreAuthKeyResource(request),
+		server:      server.NewServerResource(request),
+		debug:       debug.NewDebugResource(request),
 	}
 
 	return c, nil
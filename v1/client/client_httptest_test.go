@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	hsRequests "github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/v1/preauthkeys"
 	"github.com/hibare/headscale-client-go/v1/users"
 	"github.com/stretchr/testify/assert"
@@ -91,6 +92,22 @@ func TestClient_HTTPRequests(t *testing.T) {
 		assert.Contains(t, reqs[0].Header.Get("Authorization"), "Bearer test-api-key")
 	})
 
+	t.Run("response metadata", func(t *testing.T) {
+		resetRequests()
+		ctx, meta := hsRequests.CaptureResponseMeta(t.Context())
+		_, listErr := client.APIKeys().List(ctx)
+		require.NoError(t, listErr)
+		assert.Equal(t, http.StatusOK, meta.StatusCode)
+		assert.Equal(t, "application/json", meta.Header.Get("Content-Type"))
+		assert.Equal(t, srv.URL+"/api/v1/apikey", meta.URL)
+		assert.Equal(t, 1, meta.Attempts)
+
+		_, getErr := client.Nodes().Get(ctx, "missing")
+		require.Error(t, getErr)
+		assert.Equal(t, http.StatusNotFound, meta.StatusCode)
+		assert.Equal(t, srv.URL+"/api/v1/node/missing", meta.URL)
+	})
+
 	t.Run("Users.List with filter", func(t *testing.T) {
 		ctx := t.Context()
 		resetRequests()
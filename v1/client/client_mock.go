@@ -2,9 +2,11 @@ package client
 
 import (
 	"github.com/hibare/headscale-client-go/v1/apikeys"
+	"github.com/hibare/headscale-client-go/v1/debug"
 	"github.com/hibare/headscale-client-go/v1/nodes"
 	"github.com/hibare/headscale-client-go/v1/policy"
 	"github.com/hibare/headscale-client-go/v1/preauthkeys"
+	"github.com/hibare/headscale-client-go/v1/server"
 	"github.com/hibare/headscale-client-go/v1/users"
 	"github.com/stretchr/testify/mock"
 )
@@ -43,3 +45,15 @@ func (m *MockClient) PreAuthKeys() preauthkeys.PreAuthKeyResourceInterface {
 	args := m.Called()
 	return args.Get(0).(preauthkeys.PreAuthKeyResourceInterface) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
+
+// Server returns the mock ServerResource for health, version and capability discovery.
+func (m *MockClient) Server() server.ServerResourceInterface {
+	args := m.Called()
+	return args.Get(0).(server.ServerResourceInterface) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// Debug returns the mock DebugResource for creating fake nodes on test servers.
+func (m *MockClient) Debug() debug.DebugResourceInterface {
+	args := m.Called()
+	return args.Get(0).(debug.DebugResourceInterface) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
@@ -5,12 +5,15 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/hibare/headscale-client-go/credentials"
 	"github.com/hibare/headscale-client-go/logger"
 	"github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/v1/apikeys"
+	"github.com/hibare/headscale-client-go/v1/debug"
 	"github.com/hibare/headscale-client-go/v1/nodes"
 	"github.com/hibare/headscale-client-go/v1/policy"
 	"github.com/hibare/headscale-client-go/v1/preauthkeys"
+	"github.com/hibare/headscale-client-go/v1/server"
 	"github.com/hibare/headscale-client-go/v1/users"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -27,6 +30,12 @@ func TestNewClient_EmptyAPIKey(t *testing.T) {
 	assert.ErrorIs(t, err, ErrAPIKeyRequired)
 }
 
+func TestNewClient_Credentials(t *testing.T) {
+	client, err := NewClient("http://localhost", "", ClientOptions{Credentials: credentials.NewStaticSource("key")})
+	require.NoError(t, err)
+	require.NotNil(t, client)
+}
+
 func TestNewClient_Defaults(t *testing.T) {
 	client, err := NewClient("http://localhost", "key", ClientOptions{})
 	require.NoError(t, err)
@@ -45,6 +54,18 @@ func TestNewClient_CustomOptions(t *testing.T) {
 	require.NotNil(t, client)
 }
 
+func TestNewMultiEndpointClient(t *testing.T) {
+	_, err := NewMultiEndpointClient(nil, "key", ClientOptions{})
+	require.ErrorIs(t, err, ErrBaseURLRequired)
+
+	_, err = NewMultiEndpointClient([]string{"http://localhost", "://invalid-url"}, "key", ClientOptions{})
+	require.Error(t, err)
+
+	client, err := NewMultiEndpointClient([]string{"http://lb.internal", "http://10.0.0.1:8080"}, "key", ClientOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, client)
+}
+
 func TestNewClient_Alias(t *testing.T) {
 	client, err := NewClient("http://localhost", "key", ClientOptions{})
 	require.NoError(t, err)
@@ -65,12 +86,16 @@ func TestClient_ResourceAccessors(t *testing.T) {
 		policy:      policy.NewPolicyResource(mockReq),
 		users:       users.NewUserResource(mockReq),
 		preAuthKeys: preauthkeys.NewPreAuthKeyResource(mockReq),
+		server:      server.NewServerResource(mockReq),
+		debug:       debug.NewDebugResource(mockReq),
 	}
 	assert.NotNil(t, c.APIKeys())
 	assert.NotNil(t, c.Nodes())
 	assert.NotNil(t, c.Policy())
 	assert.NotNil(t, c.Users())
 	assert.NotNil(t, c.PreAuthKeys())
+	assert.NotNil(t, c.Server())
+	assert.NotNil(t, c.Debug())
 }
 
 func TestClientOptions_ZeroValue(t *testing.T) {
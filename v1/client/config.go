@@ -0,0 +1,478 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/headscale-client-go/credentials"
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/hibare/headscale-client-go/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by LoadConfig. The HEADSCALE_CLI_* names match the headscale CLI.
+const (
+	EnvConfigFile = "HEADSCALE_CONFIG"
+	EnvProfile    = "HEADSCALE_CLI_PROFILE"
+	EnvAddress    = "HEADSCALE_CLI_ADDRESS"
+	EnvAPIKey     = "HEADSCALE_CLI_API_KEY"
+	EnvAPIKeyFile = "HEADSCALE_CLI_API_KEY_FILE"
+	EnvTimeout    = "HEADSCALE_CLI_TIMEOUT"
+	EnvInsecure   = "HEADSCALE_CLI_INSECURE"
+	EnvCAFile     = "HEADSCALE_CLI_CA_FILE"
+	EnvLogLevel   = "HEADSCALE_CLI_LOG_LEVEL"
+)
+
+// Configuration keys, as used in the config file.
+const (
+	keyAddress    = "address"
+	keyAPIKey     = "api_key"
+	keyAPIKeyFile = "api_key_file"
+	keyTimeout    = "timeout"
+	keyInsecure   = "insecure"
+	keyCAFile     = "ca_file"
+	keyLogLevel   = "log_level"
+)
+
+var (
+	// ErrAddressRequired is returned when no server address is configured.
+	ErrAddressRequired = errors.New("server address is required")
+
+	// ErrProfileNotFound is returned when the selected profile is not defined in the config file.
+	ErrProfileNotFound = errors.New("profile not found")
+)
+
+// ConfigError describes an invalid configuration value and where it came from.
+type ConfigError struct {
+	// Source names the origin of the value, e.g. "env HEADSCALE_CLI_TIMEOUT" or "file config.yaml (cli.timeout)".
+	Source string
+
+	// Field is the configuration key of the value.
+	Field string
+
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid %s from %s: %v", e.Field, e.Source, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Config holds client settings resolved by LoadConfig.
+type Config struct {
+	Address  string
+	APIKey   string
+	Timeout  time.Duration
+	Insecure bool
+	CAFile   string
+	LogLevel *logger.LogLevel
+
+	// APIKeyFile is the file the API key was read from, if any. NewFromConfig reloads
+	// the key from it when the file changes.
+	APIKeyFile string
+
+	// sources maps each resolved key to the description of its origin.
+	sources map[string]string
+}
+
+// ConfigOptions contains explicit settings and lookup options for LoadConfig.
+//
+// Explicit settings take precedence over all other sources.
+type ConfigOptions struct {
+	Address    *string
+	APIKey     *string
+	APIKeyFile *string
+	Timeout    *time.Duration
+	Insecure   *bool
+	CAFile     *string
+	LogLevel   *logger.LogLevel
+
+	// ConfigFile is the path of the YAML config file. Defaults to $HEADSCALE_CONFIG; no file is read if both are empty.
+	ConfigFile string
+
+	// Profile selects a named profile from the config file. Defaults to $HEADSCALE_CLI_PROFILE.
+	Profile string
+
+	// LookupEnv looks up environment variables. Defaults to os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+}
+
+// settings is one source of raw configuration values.
+type settings struct {
+	Address    *string `yaml:"address"`
+	APIKey     *string `yaml:"api_key"`
+	APIKeyFile *string `yaml:"api_key_file"`
+	Timeout    *string `yaml:"timeout"`
+	Insecure   *string `yaml:"insecure"`
+	CAFile     *string `yaml:"ca_file"`
+	LogLevel   *string `yaml:"log_level"`
+
+	// Labels annotate a profile; they are not used to configure the client.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// get returns the raw value of key, or nil if unset.
+func (s *settings) get(key string) *string {
+	switch key {
+	case keyAddress:
+		return s.Address
+	case keyAPIKey:
+		return s.APIKey
+	case keyAPIKeyFile:
+		return s.APIKeyFile
+	case keyTimeout:
+		return s.Timeout
+	case keyInsecure:
+		return s.Insecure
+	case keyCAFile:
+		return s.CAFile
+	case keyLogLevel:
+		return s.LogLevel
+	default:
+		return nil
+	}
+}
+
+// configFile is the layout of the YAML config file.
+//
+// The cli block matches the headscale CLI config; profiles hold named alternatives.
+type configFile struct {
+	CLI      settings            `yaml:"cli"`
+	Profiles map[string]settings `yaml:"profiles"`
+}
+
+// configLayer is a source of settings together with a description of where each key comes from.
+type configLayer struct {
+	values settings
+	source func(key string) string
+}
+
+// LoadConfig resolves client settings from, in order of precedence: explicit options,
+// HEADSCALE_CLI_* environment variables, the selected profile of the config file, and the
+// cli block of the config file.
+func LoadConfig(opt ConfigOptions) (Config, error) {
+	if opt.LookupEnv == nil {
+		opt.LookupEnv = os.LookupEnv
+	}
+
+	layers := []configLayer{explicitLayer(opt), envLayer(opt.LookupEnv)}
+
+	fileLayers, err := loadFileLayers(opt)
+	if err != nil {
+		return Config{}, err
+	}
+	layers = append(layers, fileLayers...)
+
+	return resolveConfig(layers)
+}
+
+// explicitLayer returns the settings passed in ConfigOptions.
+func explicitLayer(opt ConfigOptions) configLayer {
+	values := settings{
+		Address:    opt.Address,
+		APIKey:     opt.APIKey,
+		APIKeyFile: opt.APIKeyFile,
+		CAFile:     opt.CAFile,
+	}
+	if opt.Timeout != nil {
+		values.Timeout = utils.ToPtr(opt.Timeout.String())
+	}
+	if opt.Insecure != nil {
+		values.Insecure = utils.ToPtr(strconv.FormatBool(*opt.Insecure))
+	}
+	if opt.LogLevel != nil {
+		values.LogLevel = utils.ToPtr(opt.LogLevel.String())
+	}
+
+	return configLayer{
+		values: values,
+		source: func(key string) string { return "option " + key },
+	}
+}
+
+// envLayer returns the settings found in the environment.
+func envLayer(lookupEnv func(string) (string, bool)) configLayer {
+	names := map[string]string{
+		keyAddress:    EnvAddress,
+		keyAPIKey:     EnvAPIKey,
+		keyAPIKeyFile: EnvAPIKeyFile,
+		keyTimeout:    EnvTimeout,
+		keyInsecure:   EnvInsecure,
+		keyCAFile:     EnvCAFile,
+		keyLogLevel:   EnvLogLevel,
+	}
+
+	lookup := func(key string) *string {
+		if v, ok := lookupEnv(names[key]); ok && v != "" {
+			return &v
+		}
+		return nil
+	}
+
+	return configLayer{
+		values: settings{
+			Address:    lookup(keyAddress),
+			APIKey:     lookup(keyAPIKey),
+			APIKeyFile: lookup(keyAPIKeyFile),
+			Timeout:    lookup(keyTimeout),
+			Insecure:   lookup(keyInsecure),
+			CAFile:     lookup(keyCAFile),
+			LogLevel:   lookup(keyLogLevel),
+		},
+		source: func(key string) string { return "env " + names[key] },
+	}
+}
+
+// loadFileLayers reads the config file, if any, and returns its selected profile and cli block,
+// in order of precedence.
+func loadFileLayers(opt ConfigOptions) ([]configLayer, error) {
+	path := opt.ConfigFile
+	if path == "" {
+		path, _ = opt.LookupEnv(EnvConfigFile)
+	}
+
+	profile := opt.Profile
+	if profile == "" {
+		profile, _ = opt.LookupEnv(EnvProfile)
+	}
+
+	if path == "" {
+		if profile != "" {
+			return nil, &ConfigError{Source: "profile " + strconv.Quote(profile), Field: "profile", Err: ErrProfileNotFound}
+		}
+		return nil, nil
+	}
+
+	file, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []configLayer
+	if profile != "" {
+		values, ok := file.Profiles[profile]
+		if !ok {
+			return nil, &ConfigError{Source: "file " + path, Field: "profile", Err: fmt.Errorf("%w: %q", ErrProfileNotFound, profile)}
+		}
+		layers = append(layers, configLayer{
+			values: values,
+			source: func(key string) string { return fmt.Sprintf("file %s (profiles.%s.%s)", path, profile, key) },
+		})
+	}
+
+	layers = append(layers, configLayer{
+		values: file.CLI,
+		source: func(key string) string { return fmt.Sprintf("file %s (cli.%s)", path, key) },
+	})
+	return layers, nil
+}
+
+// Profile is a named server configuration from the profiles section of a config file.
+type Profile struct {
+	Name   string
+	Config Config
+	Labels map[string]string
+}
+
+// LoadProfiles reads every profile defined in the config file at path, sorted by name.
+//
+// Each profile is resolved on its own: environment variables and the cli block do not apply.
+func LoadProfiles(path string) ([]Profile, error) {
+	file, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := slices.Sorted(maps.Keys(file.Profiles))
+	profiles := make([]Profile, 0, len(names))
+	for _, name := range names {
+		values := file.Profiles[name]
+		cfg, err := resolveConfig([]configLayer{{
+			values: values,
+			source: func(key string) string { return fmt.Sprintf("file %s (profiles.%s.%s)", path, name, key) },
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+		profiles = append(profiles, Profile{Name: name, Config: cfg, Labels: values.Labels})
+	}
+
+	return profiles, nil
+}
+
+// readConfigFile reads and parses the YAML config file at path.
+func readConfigFile(path string) (configFile, error) {
+	var file configFile
+
+	data, err := os.ReadFile(path) //nolint:gosec // reason: reading a user-provided config file is the purpose of this function
+	if err != nil {
+		return file, &ConfigError{Source: "file " + path, Field: "config file", Err: err}
+	}
+
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return file, &ConfigError{Source: "file " + path, Field: "config file", Err: err}
+	}
+
+	return file, nil
+}
+
+// lookup returns the first value set for key across layers, with its source.
+func lookup(layers []configLayer, key string) (string, string, bool) {
+	for _, l := range layers {
+		if v := l.values.get(key); v != nil {
+			return *v, l.source(key), true
+		}
+	}
+	return "", "", false
+}
+
+// resolveConfig merges layers into a Config, parsing and validating each value.
+func resolveConfig(layers []configLayer) (Config, error) {
+	cfg := Config{sources: map[string]string{}}
+
+	address, source, ok := lookup(layers, keyAddress)
+	if !ok || strings.TrimSpace(address) == "" {
+		return cfg, ErrAddressRequired
+	}
+	normalized, err := normalizeAddress(address)
+	if err != nil {
+		return cfg, &ConfigError{Source: source, Field: keyAddress, Err: err}
+	}
+	cfg.Address = normalized
+	cfg.sources[keyAddress] = source
+
+	if err := resolveAPIKey(layers, &cfg); err != nil {
+		return cfg, err
+	}
+
+	if v, source, ok := lookup(layers, keyTimeout); ok {
+		timeout, err := time.ParseDuration(v)
+		if err == nil && timeout < 0 {
+			err = errors.New("must not be negative")
+		}
+		if err != nil {
+			return cfg, &ConfigError{Source: source, Field: keyTimeout, Err: err}
+		}
+		cfg.Timeout = timeout
+		cfg.sources[keyTimeout] = source
+	}
+
+	if v, source, ok := lookup(layers, keyInsecure); ok {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, &ConfigError{Source: source, Field: keyInsecure, Err: err}
+		}
+		cfg.Insecure = insecure
+		cfg.sources[keyInsecure] = source
+	}
+
+	if v, source, ok := lookup(layers, keyCAFile); ok {
+		cfg.CAFile = v
+		cfg.sources[keyCAFile] = source
+	}
+
+	if v, source, ok := lookup(layers, keyLogLevel); ok {
+		level, err := logger.ParseLevel(v)
+		if err != nil {
+			return cfg, &ConfigError{Source: source, Field: keyLogLevel, Err: err}
+		}
+		cfg.LogLevel = &level
+		cfg.sources[keyLogLevel] = source
+	}
+
+	return cfg, nil
+}
+
+// resolveAPIKey sets the API key from the highest-precedence layer defining api_key or api_key_file.
+func resolveAPIKey(layers []configLayer, cfg *Config) error {
+	for _, l := range layers {
+		if v := l.values.APIKey; v != nil {
+			cfg.APIKey = strings.TrimSpace(*v)
+			cfg.sources[keyAPIKey] = l.source(keyAPIKey)
+			return nil
+		}
+
+		if v := l.values.APIKeyFile; v != nil {
+			source := l.source(keyAPIKeyFile)
+			data, err := os.ReadFile(*v)
+			if err != nil {
+				return &ConfigError{Source: source, Field: keyAPIKeyFile, Err: err}
+			}
+			key := strings.TrimSpace(string(data))
+			if key == "" {
+				return &ConfigError{Source: source, Field: keyAPIKeyFile, Err: fmt.Errorf("file %s is empty", *v)}
+			}
+			cfg.APIKey = key
+			cfg.APIKeyFile = *v
+			cfg.sources[keyAPIKey] = source
+			return nil
+		}
+	}
+
+	return ErrAPIKeyRequired
+}
+
+// normalizeAddress turns a headscale CLI address ("host:port") or URL into a base URL.
+func normalizeAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("missing host in %q", address)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	return u.String(), nil
+}
+
+// NewFromConfig creates a new Headscale client from settings resolved by LoadConfig.
+//
+// Options set in opt take precedence over the corresponding settings in cfg.
+func NewFromConfig(cfg Config, opt ClientOptions) (ClientInterface, error) {
+	if opt.LogLevel == nil {
+		opt.LogLevel = cfg.LogLevel
+	}
+
+	if opt.HTTPClient == nil {
+		if opt.Timeout == nil && cfg.Timeout > 0 {
+			opt.Timeout = &cfg.Timeout
+		}
+
+		if opt.TLS == nil && (cfg.Insecure || cfg.CAFile != "") {
+			opt.TLS = &TLSOptions{CAFile: cfg.CAFile, InsecureSkipVerify: cfg.Insecure}
+			if cfg.CAFile != "" {
+				if _, err := opt.TLS.config(); err != nil {
+					return nil, &ConfigError{Source: cfg.sources[keyCAFile], Field: keyCAFile, Err: err}
+				}
+			}
+		}
+	}
+
+	if opt.Credentials == nil && cfg.APIKeyFile != "" {
+		fileOpt := credentials.FileSourceOptions{Logger: opt.Logger}
+		if fileOpt.Logger == nil && opt.LogLevel != nil {
+			fileOpt.Logger = logger.NewDefaultLogger(*opt.LogLevel)
+		}
+		opt.Credentials = credentials.NewFileSource(cfg.APIKeyFile, fileOpt)
+	}
+
+	return NewClient(cfg.Address, cfg.APIKey, opt)
+}
@@ -0,0 +1,277 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/hibare/headscale-client-go/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+cli:
+  address: headscale.example.com:443
+  api_key: file-key
+  timeout: 10s
+  log_level: warn
+profiles:
+  staging:
+    address: https://staging.example.com
+    api_key: staging-key
+    insecure: true
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", testConfigFile)
+
+	t.Run("file cli block", func(t *testing.T) {
+		cfg, err := LoadConfig(ConfigOptions{ConfigFile: path, LookupEnv: envLookup(nil)})
+		require.NoError(t, err)
+		assert.Equal(t, "https://headscale.example.com:443", cfg.Address)
+		assert.Equal(t, "file-key", cfg.APIKey)
+		assert.Equal(t, 10*time.Second, cfg.Timeout)
+		assert.Equal(t, utils.ToPtr(logger.LevelWarn), cfg.LogLevel)
+		assert.False(t, cfg.Insecure)
+	})
+
+	t.Run("env overrides file", func(t *testing.T) {
+		cfg, err := LoadConfig(ConfigOptions{LookupEnv: envLookup(map[string]string{
+			EnvConfigFile: path,
+			EnvAddress:    "http://env.example.com",
+			EnvTimeout:    "3s",
+		})})
+		require.NoError(t, err)
+		assert.Equal(t, "http://env.example.com", cfg.Address)
+		assert.Equal(t, "file-key", cfg.APIKey)
+		assert.Equal(t, 3*time.Second, cfg.Timeout)
+	})
+
+	t.Run("explicit overrides env", func(t *testing.T) {
+		cfg, err := LoadConfig(ConfigOptions{
+			ConfigFile: path,
+			Address:    utils.ToPtr("http://explicit.example.com"),
+			LogLevel:   utils.ToPtr(logger.LevelDebug),
+			LookupEnv:  envLookup(map[string]string{EnvAddress: "http://env.example.com", EnvLogLevel: "error"}),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "http://explicit.example.com", cfg.Address)
+		assert.Equal(t, utils.ToPtr(logger.LevelDebug), cfg.LogLevel)
+	})
+
+	t.Run("profile overrides cli block", func(t *testing.T) {
+		cfg, err := LoadConfig(ConfigOptions{ConfigFile: path, Profile: "staging", LookupEnv: envLookup(nil)})
+		require.NoError(t, err)
+		assert.Equal(t, "https://staging.example.com", cfg.Address)
+		assert.Equal(t, "staging-key", cfg.APIKey)
+		assert.True(t, cfg.Insecure)
+		assert.Equal(t, 10*time.Second, cfg.Timeout, "the cli block fills values the profile leaves unset")
+	})
+
+	t.Run("env profile overrides cli block", func(t *testing.T) {
+		cfg, err := LoadConfig(ConfigOptions{ConfigFile: path, LookupEnv: envLookup(map[string]string{EnvProfile: "staging"})})
+		require.NoError(t, err)
+		assert.Equal(t, "https://staging.example.com", cfg.Address)
+	})
+
+	t.Run("env overrides profile", func(t *testing.T) {
+		cfg, err := LoadConfig(ConfigOptions{ConfigFile: path, Profile: "staging", LookupEnv: envLookup(map[string]string{
+			EnvAddress: "http://env.example.com",
+		})})
+		require.NoError(t, err)
+		assert.Equal(t, "http://env.example.com", cfg.Address)
+	})
+}
+
+func TestLoadConfig_APIKeyFile(t *testing.T) {
+	keyFile := writeFile(t, "key", "secret-from-file\n")
+	path := writeFile(t, "config.yaml", "cli:\n  address: http://localhost\n  api_key: file-key\n")
+
+	cfg, err := LoadConfig(ConfigOptions{
+		ConfigFile: path,
+		LookupEnv:  envLookup(map[string]string{EnvAPIKeyFile: keyFile}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "secret-from-file", cfg.APIKey, "env key file takes precedence over the file api key")
+	assert.Equal(t, keyFile, cfg.APIKeyFile)
+
+	_, err = LoadConfig(ConfigOptions{
+		ConfigFile: path,
+		LookupEnv:  envLookup(map[string]string{EnvAPIKeyFile: filepath.Join(t.TempDir(), "missing")}),
+	})
+	var cfgErr *ConfigError
+	require.ErrorAs(t, err, &cfgErr)
+	assert.Equal(t, "env "+EnvAPIKeyFile, cfgErr.Source)
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	path := writeFile(t, "config.yaml", testConfigFile)
+	badLevelPath := writeFile(t, "bad.yaml", "cli:\n  address: http://x\n  api_key: k\n  log_level: loud\n")
+
+	tests := []struct {
+		name       string
+		opt        ConfigOptions
+		wantErr    error
+		wantSource string
+		wantField  string
+	}{
+		{
+			name:    "no address",
+			opt:     ConfigOptions{APIKey: utils.ToPtr("key")},
+			wantErr: ErrAddressRequired,
+		},
+		{
+			name:    "no api key",
+			opt:     ConfigOptions{Address: utils.ToPtr("http://localhost")},
+			wantErr: ErrAPIKeyRequired,
+		},
+		{
+			name: "invalid env timeout",
+			opt: ConfigOptions{ConfigFile: path, LookupEnv: envLookup(map[string]string{
+				EnvTimeout: "soon",
+			})},
+			wantSource: "env " + EnvTimeout,
+			wantField:  "timeout",
+		},
+		{
+			name: "invalid env insecure",
+			opt: ConfigOptions{ConfigFile: path, LookupEnv: envLookup(map[string]string{
+				EnvInsecure: "maybe",
+			})},
+			wantSource: "env " + EnvInsecure,
+			wantField:  "insecure",
+		},
+		{
+			name:       "invalid file log level",
+			opt:        ConfigOptions{ConfigFile: badLevelPath},
+			wantSource: "file " + badLevelPath + " (cli.log_level)",
+			wantField:  "log_level",
+		},
+		{
+			name:       "invalid address scheme",
+			opt:        ConfigOptions{Address: utils.ToPtr("ftp://host"), APIKey: utils.ToPtr("k")},
+			wantSource: "option address",
+			wantField:  "address",
+		},
+		{
+			name:    "unknown profile",
+			opt:     ConfigOptions{ConfigFile: path, Profile: "prod"},
+			wantErr: ErrProfileNotFound,
+		},
+		{
+			name:    "profile without file",
+			opt:     ConfigOptions{Profile: "prod"},
+			wantErr: ErrProfileNotFound,
+		},
+		{
+			name:      "malformed file",
+			opt:       ConfigOptions{ConfigFile: writeFile(t, "broken.yaml", "cli: [")},
+			wantField: "config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opt.LookupEnv == nil {
+				tt.opt.LookupEnv = envLookup(nil)
+			}
+			_, err := LoadConfig(tt.opt)
+			require.Error(t, err)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+			if tt.wantField != "" {
+				var cfgErr *ConfigError
+				require.ErrorAs(t, err, &cfgErr)
+				assert.Equal(t, tt.wantField, cfgErr.Field)
+				assert.Contains(t, err.Error(), cfgErr.Source)
+			}
+			if tt.wantSource != "" {
+				var cfgErr *ConfigError
+				require.ErrorAs(t, err, &cfgErr)
+				assert.Equal(t, tt.wantSource, cfgErr.Source)
+			}
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		client, err := NewFromConfig(Config{Address: "http://localhost", APIKey: "key"}, ClientOptions{})
+		require.NoError(t, err)
+		require.NotNil(t, client)
+	})
+
+	t.Run("tls settings", func(t *testing.T) {
+		cfg := Config{Address: "https://localhost", APIKey: "key", Insecure: true, Timeout: time.Second}
+		client, err := NewFromConfig(cfg, ClientOptions{})
+		require.NoError(t, err)
+		require.NotNil(t, client)
+	})
+
+	t.Run("invalid ca file names its source", func(t *testing.T) {
+		cfg, err := LoadConfig(ConfigOptions{
+			Address:   utils.ToPtr("https://localhost"),
+			APIKey:    utils.ToPtr("key"),
+			LookupEnv: envLookup(map[string]string{EnvCAFile: writeFile(t, "ca.pem", "not a certificate")}),
+		})
+		require.NoError(t, err)
+
+		_, err = NewFromConfig(cfg, ClientOptions{})
+		var cfgErr *ConfigError
+		require.ErrorAs(t, err, &cfgErr)
+		assert.Equal(t, "env "+EnvCAFile, cfgErr.Source)
+	})
+}
+
+func TestLoadProfiles(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+cli:
+  address: http://ignored
+profiles:
+  prod:
+    address: https://hs.prod.example.com
+    api_key: prod-key
+    labels:
+      env: prod
+  customer-a:
+    address: hs.customer-a.example.com:8443
+    api_key: a-key
+    timeout: 5s
+`)
+
+	profiles, err := LoadProfiles(path)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	assert.Equal(t, "customer-a", profiles[0].Name)
+	assert.Equal(t, "https://hs.customer-a.example.com:8443", profiles[0].Config.Address)
+	assert.Equal(t, 5*time.Second, profiles[0].Config.Timeout)
+	assert.Nil(t, profiles[0].Labels)
+
+	assert.Equal(t, "prod", profiles[1].Name)
+	assert.Equal(t, "prod-key", profiles[1].Config.APIKey)
+	assert.Equal(t, map[string]string{"env": "prod"}, profiles[1].Labels)
+
+	_, err = LoadProfiles(writeFile(t, "bad.yaml", "profiles:\n  broken:\n    api_key: k\n"))
+	require.ErrorIs(t, err, ErrAddressRequired)
+	assert.Contains(t, err.Error(), `profile "broken"`)
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+var (
+	// ErrConflictingOptions is returned when ClientOptions contains settings that cannot be combined.
+	ErrConflictingOptions = errors.New("conflicting options")
+
+	// ErrInvalidOption is returned when a ClientOptions setting has an invalid value.
+	ErrInvalidOption = errors.New("invalid option")
+
+	// errNoCertificates is returned when a CA bundle contains no PEM certificates.
+	errNoCertificates = errors.New("no PEM certificates found")
+)
+
+// proxySchemes are the proxy URL schemes supported by net/http.
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// tlsVersions are the accepted values of TLSOptions.MinVersion.
+var tlsVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// TLSOptions contains TLS settings for connections to Headscale.
+//
+// File and PEM variants of the same setting are mutually exclusive.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of CAs trusted instead of the system roots.
+	CAFile string
+
+	// CAPEM is a PEM bundle of CAs trusted instead of the system roots.
+	CAPEM []byte
+
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS.
+	CertFile string
+	KeyFile  string
+
+	// CertPEM and KeyPEM are the PEM client certificate and key for mutual TLS.
+	CertPEM []byte
+	KeyPEM  []byte
+
+	// ServerName overrides the name used to verify the server certificate.
+	ServerName string
+
+	// MinVersion is the minimum TLS version, e.g. tls.VersionTLS13. Defaults to tls.VersionTLS12.
+	MinVersion uint16
+
+	// InsecureSkipVerify disables verification of the server certificate. Use for testing only.
+	InsecureSkipVerify bool
+}
+
+// validate returns the conflicts and invalid values in t.
+func (t *TLSOptions) validate() []error {
+	var errs []error
+
+	hasCA := t.CAFile != "" || len(t.CAPEM) > 0
+	if t.CAFile != "" && len(t.CAPEM) > 0 {
+		errs = append(errs, fmt.Errorf("%w: TLS.CAFile and TLS.CAPEM", ErrConflictingOptions))
+	}
+	if hasCA && t.InsecureSkipVerify {
+		errs = append(errs, fmt.Errorf("%w: TLS.InsecureSkipVerify and a custom CA", ErrConflictingOptions))
+	}
+
+	fromFile := t.CertFile != "" || t.KeyFile != ""
+	fromPEM := len(t.CertPEM) > 0 || len(t.KeyPEM) > 0
+	switch {
+	case fromFile && fromPEM:
+		errs = append(errs, fmt.Errorf("%w: TLS client certificate files and PEM", ErrConflictingOptions))
+	case fromFile && (t.CertFile == "" || t.KeyFile == ""):
+		errs = append(errs, fmt.Errorf("%w: TLS.CertFile and TLS.KeyFile must be set together", ErrInvalidOption))
+	case fromPEM && (len(t.CertPEM) == 0 || len(t.KeyPEM) == 0):
+		errs = append(errs, fmt.Errorf("%w: TLS.CertPEM and TLS.KeyPEM must be set together", ErrInvalidOption))
+	}
+
+	if t.MinVersion != 0 && !slices.Contains(tlsVersions, t.MinVersion) {
+		errs = append(errs, fmt.Errorf("%w: TLS.MinVersion %#x", ErrInvalidOption, t.MinVersion))
+	}
+
+	return errs
+}
+
+// config builds the tls.Config described by t, loading certificates from disk as needed.
+func (t *TLSOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         t.MinVersion,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec // reason: explicit opt-in, documented as testing only
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	caPEM := t.CAPEM
+	if t.CAFile != "" {
+		data, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		caPEM = data
+	}
+	if len(caPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errNoCertificates
+		}
+		cfg.RootCAs = pool
+	}
+
+	var (
+		cert tls.Certificate
+		err  error
+	)
+	switch {
+	case t.CertFile != "":
+		cert, err = tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	case len(t.CertPEM) > 0:
+		cert, err = tls.X509KeyPair(t.CertPEM, t.KeyPEM)
+	default:
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %w", err)
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+
+	return cfg, nil
+}
+
+// Validate reports settings in o that are invalid or cannot be combined.
+// NewClient calls it before creating the client.
+func (o ClientOptions) Validate() error {
+	var errs []error
+
+	customTransport := o.TLS != nil || o.ProxyURL != nil || o.UnixSocket != nil
+	if o.HTTPClient != nil && customTransport {
+		errs = append(errs, fmt.Errorf("%w: HTTPClient and TLS, ProxyURL or UnixSocket", ErrConflictingOptions))
+	}
+	if o.ProxyURL != nil && o.UnixSocket != nil {
+		errs = append(errs, fmt.Errorf("%w: ProxyURL and UnixSocket", ErrConflictingOptions))
+	}
+
+	if o.Timeout != nil && *o.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("%w: negative Timeout", ErrInvalidOption))
+	}
+	if o.ProxyURL != nil {
+		if u, err := url.Parse(*o.ProxyURL); err != nil {
+			errs = append(errs, fmt.Errorf("%w: ProxyURL: %w", ErrInvalidOption, err))
+		} else if !slices.Contains(proxySchemes, u.Scheme) || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%w: ProxyURL %q", ErrInvalidOption, *o.ProxyURL))
+		}
+	}
+	if o.UnixSocket != nil && *o.UnixSocket == "" {
+		errs = append(errs, fmt.Errorf("%w: empty UnixSocket", ErrInvalidOption))
+	}
+	if o.TLS != nil {
+		errs = append(errs, o.TLS.validate()...)
+	}
+	if o.ServerVersion != nil {
+		if _, err := versions.ParseServerVersion(*o.ServerVersion); err != nil {
+			errs = append(errs, fmt.Errorf("%w: ServerVersion: %w", ErrInvalidOption, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// buildHTTPClient returns the HTTP client described by o. A caller-provided HTTPClient is copied,
+// never modified.
+func (o ClientOptions) buildHTTPClient() (*http.Client, error) {
+	if o.HTTPClient != nil {
+		c := *o.HTTPClient
+		if o.Timeout != nil {
+			c.Timeout = *o.Timeout
+		} else if c.Timeout == 0 {
+			c.Timeout = requests.DefaultHTTPClientTimeout
+		}
+		return &c, nil
+	}
+
+	c := &http.Client{Timeout: requests.DefaultHTTPClientTimeout}
+	if o.Timeout != nil {
+		c.Timeout = *o.Timeout
+	}
+
+	if o.TLS == nil && o.ProxyURL == nil && o.UnixSocket == nil {
+		return c, nil
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unexpected default transport type")
+	}
+	transport = transport.Clone()
+
+	if o.TLS != nil {
+		tlsConfig, err := o.TLS.config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if o.ProxyURL != nil {
+		proxyURL, err := url.Parse(*o.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if o.UnixSocket != nil {
+		socket := *o.UnixSocket
+		var dialer net.Dialer
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	c.Transport = transport
+	return c, nil
+}
@@ -0,0 +1,269 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/utils"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usersHandler answers the user list endpoint.
+var usersHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"users":[{"id":"1","name":"alice"}]}`))
+})
+
+// serverCAPEM returns the PEM encoded certificate of a TLS test server.
+func serverCAPEM(ts *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+}
+
+// clientCertPEM generates a self-signed client certificate and key.
+func clientCertPEM(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "headscale-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestClientOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     ClientOptions
+		wantErr error
+	}{
+		{name: "zero value", opt: ClientOptions{}},
+		{
+			name: "full transport",
+			opt: ClientOptions{
+				Timeout:  utils.ToPtr(time.Second),
+				ProxyURL: utils.ToPtr("socks5://proxy:1080"),
+				TLS:      &TLSOptions{CAFile: "ca.pem", CertFile: "c.pem", KeyFile: "k.pem", MinVersion: tls.VersionTLS13},
+			},
+		},
+		{
+			name:    "http client with tls",
+			opt:     ClientOptions{HTTPClient: &http.Client{}, TLS: &TLSOptions{}},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "proxy with unix socket",
+			opt:     ClientOptions{ProxyURL: utils.ToPtr("http://proxy"), UnixSocket: utils.ToPtr("/run/hs.sock")},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "ca file and pem",
+			opt:     ClientOptions{TLS: &TLSOptions{CAFile: "ca.pem", CAPEM: []byte("x")}},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "insecure with custom ca",
+			opt:     ClientOptions{TLS: &TLSOptions{CAPEM: []byte("x"), InsecureSkipVerify: true}},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "cert files and pem",
+			opt:     ClientOptions{TLS: &TLSOptions{CertFile: "c", KeyFile: "k", CertPEM: []byte("c"), KeyPEM: []byte("k")}},
+			wantErr: ErrConflictingOptions,
+		},
+		{
+			name:    "cert without key",
+			opt:     ClientOptions{TLS: &TLSOptions{CertPEM: []byte("c")}},
+			wantErr: ErrInvalidOption,
+		},
+		{
+			name:    "unknown tls version",
+			opt:     ClientOptions{TLS: &TLSOptions{MinVersion: 1}},
+			wantErr: ErrInvalidOption,
+		},
+		{
+			name:    "negative timeout",
+			opt:     ClientOptions{Timeout: utils.ToPtr(-time.Second)},
+			wantErr: ErrInvalidOption,
+		},
+		{
+			name:    "proxy without host",
+			opt:     ClientOptions{ProxyURL: utils.ToPtr("proxy:3128")},
+			wantErr: ErrInvalidOption,
+		},
+		{
+			name:    "invalid server version",
+			opt:     ClientOptions{ServerVersion: utils.ToPtr("latest")},
+			wantErr: ErrInvalidOption,
+		},
+		{
+			name:    "empty unix socket",
+			opt:     ClientOptions{UnixSocket: utils.ToPtr("")},
+			wantErr: ErrInvalidOption,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opt.Validate()
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := NewClient("http://localhost", "key", ClientOptions{HTTPClient: &http.Client{}, UnixSocket: utils.ToPtr("/run/hs.sock")})
+	require.ErrorIs(t, err, ErrConflictingOptions, "NewClient validates its options")
+}
+
+func TestClientOptions_Timeout(t *testing.T) {
+	t.Run("caller's client is not modified", func(t *testing.T) {
+		httpClient := &http.Client{}
+		c, err := ClientOptions{HTTPClient: httpClient}.buildHTTPClient()
+		require.NoError(t, err)
+		assert.Equal(t, requests.DefaultHTTPClientTimeout, c.Timeout)
+		assert.Zero(t, httpClient.Timeout)
+		assert.NotSame(t, httpClient, c)
+	})
+
+	t.Run("client timeout is kept", func(t *testing.T) {
+		c, err := ClientOptions{HTTPClient: &http.Client{Timeout: time.Second}}.buildHTTPClient()
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, c.Timeout)
+	})
+
+	t.Run("option overrides", func(t *testing.T) {
+		c, err := ClientOptions{HTTPClient: &http.Client{Timeout: time.Second}, Timeout: utils.ToPtr(time.Duration(0))}.buildHTTPClient()
+		require.NoError(t, err)
+		assert.Zero(t, c.Timeout)
+
+		c, err = ClientOptions{Timeout: utils.ToPtr(5 * time.Second)}.buildHTTPClient()
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Second, c.Timeout)
+	})
+}
+
+func TestClientOptions_TLS(t *testing.T) {
+	t.Run("custom ca and server name", func(t *testing.T) {
+		ts := httptest.NewTLSServer(usersHandler)
+		defer ts.Close()
+
+		client, err := NewClient(ts.URL, "key", ClientOptions{TLS: &TLSOptions{
+			CAPEM:      serverCAPEM(ts),
+			ServerName: "example.com",
+			MinVersion: tls.VersionTLS13,
+		}})
+		require.NoError(t, err)
+
+		resp, err := client.Users().List(t.Context(), users.UserListFilter{})
+		require.NoError(t, err)
+		assert.Len(t, resp.Users, 1)
+	})
+
+	t.Run("untrusted server", func(t *testing.T) {
+		ts := httptest.NewTLSServer(usersHandler)
+		defer ts.Close()
+
+		client, err := NewClient(ts.URL, "key", ClientOptions{TLS: &TLSOptions{}})
+		require.NoError(t, err)
+
+		_, err = client.Users().List(t.Context(), users.UserListFilter{})
+		require.Error(t, err)
+	})
+
+	t.Run("mutual tls", func(t *testing.T) {
+		var peerCerts int
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peerCerts = len(r.TLS.PeerCertificates)
+			usersHandler(w, r)
+		}))
+		ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+		ts.StartTLS()
+		defer ts.Close()
+
+		certPEM, keyPEM := clientCertPEM(t)
+		certFile := writeFile(t, "client.pem", string(certPEM))
+		keyFile := writeFile(t, "client-key.pem", string(keyPEM))
+
+		client, err := NewClient(ts.URL, "key", ClientOptions{TLS: &TLSOptions{
+			CAFile:   writeFile(t, "ca.pem", string(serverCAPEM(ts))),
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		}})
+		require.NoError(t, err)
+
+		_, err = client.Users().List(t.Context(), users.UserListFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, peerCerts)
+	})
+
+	t.Run("invalid ca", func(t *testing.T) {
+		_, err := NewClient("https://localhost", "key", ClientOptions{TLS: &TLSOptions{CAPEM: []byte("garbage")}})
+		require.ErrorIs(t, err, errNoCertificates)
+	})
+}
+
+func TestClientOptions_Proxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.Host
+		usersHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient("http://headscale.internal", "key", ClientOptions{ProxyURL: utils.ToPtr(proxy.URL)})
+	require.NoError(t, err)
+
+	_, err = client.Users().List(t.Context(), users.UserListFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, "headscale.internal", proxiedHost)
+}
+
+func TestClientOptions_UnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available")
+	}
+
+	socket := filepath.Join(t.TempDir(), "hs.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: usersHandler, ReadHeaderTimeout: time.Second}
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Close()
+
+	client, err := NewClient("http://headscale", "key", ClientOptions{UnixSocket: &socket})
+	require.NoError(t, err)
+
+	resp, err := client.Users().List(t.Context(), users.UserListFilter{})
+	require.NoError(t, err)
+	assert.Len(t, resp.Users, 1)
+}
@@ -0,0 +1,85 @@
+// Package debug provides a client for the Headscale debug endpoints, which create fake nodes for
+// load testing and policy simulation. Use it against throwaway servers only.
+package debug
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/nodes"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+const (
+	// registrationIDLength is the length of the registration IDs of Headscale v0.25.0 and newer.
+	registrationIDLength = 24
+
+	// machineKeyLength is the length of a machine key in bytes.
+	machineKeyLength = 32
+)
+
+// DebugResourceInterface is an interface for the Headscale debug endpoints.
+type DebugResourceInterface interface {
+	CreateNode(ctx context.Context, req CreateNodeRequest) (nodes.NodeResponse, error)
+	Generate(ctx context.Context, opt GenerateOptions) (*Fleet, error)
+}
+
+// DebugResource is a struct that implements the DebugResourceInterface.
+type DebugResource struct {
+	r requests.RequestInterface
+}
+
+// NewDebugResource creates a new DebugResource.
+func NewDebugResource(r requests.RequestInterface) *DebugResource {
+	return &DebugResource{r: r}
+}
+
+// CreateNodeRequest represents a request to create a fake node.
+type CreateNodeRequest struct {
+	// User is the name of the user owning the node.
+	User string `json:"user"`
+
+	// Key is the key the node is registered with, as returned by NewRegistrationKey.
+	Key string `json:"key"`
+
+	Name string `json:"name"`
+
+	// Routes are the routes the node advertises.
+	Routes []string `json:"routes"`
+}
+
+// CreateNode creates a fake node waiting for registration, like a node that has opened its register
+// URL. Register it with NodeResource.Register and the same user and key; until then the returned
+// node has no ID.
+func (d *DebugResource) CreateNode(ctx context.Context, req CreateNodeRequest) (nodes.NodeResponse, error) {
+	var node nodes.NodeResponse
+
+	url := d.r.BuildURL("debug", "node")
+	httpReq, err := d.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{
+		Body: req,
+	})
+	if err != nil {
+		return node, err
+	}
+
+	err = d.r.Do(ctx, httpReq, &node)
+	return node, err
+}
+
+// NewRegistrationKey returns a random key for a fake node in the form the server registers nodes
+// by: a registration ID on Headscale v0.25.0 and newer or an unknown version, a machine key before.
+func NewRegistrationKey(v versions.ServerVersion) string {
+	if v.Supports(versions.CapabilityRegistrationID) {
+		b := make([]byte, registrationIDLength)
+		_, _ = rand.Read(b)
+		return base64.RawURLEncoding.EncodeToString(b)[:registrationIDLength]
+	}
+
+	b := make([]byte, machineKeyLength)
+	_, _ = rand.Read(b)
+	return nodes.MachineKeyPrefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,25 @@
+package debug
+
+import (
+	"context"
+
+	"github.com/hibare/headscale-client-go/v1/nodes"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDebugResource is a mock implementation of DebugResourceInterface for testing.
+type MockDebugResource struct {
+	mock.Mock
+}
+
+// CreateNode returns a mock fake node.
+func (m *MockDebugResource) CreateNode(ctx context.Context, req CreateNodeRequest) (nodes.NodeResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(nodes.NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// Generate returns a mock fleet of fake nodes.
+func (m *MockDebugResource) Generate(ctx context.Context, opt GenerateOptions) (*Fleet, error) {
+	args := m.Called(ctx, opt)
+	return args.Get(0).(*Fleet), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
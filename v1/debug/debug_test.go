@@ -0,0 +1,35 @@
+package debug
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/nodes"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugResource_CreateNode(t *testing.T) {
+	fixture := testutil.TestFixture[nodes.NodeResponse]{
+		Endpoint:    []any{"debug", "node"},
+		Method:      http.MethodPost,
+		SuccessResp: nodes.NodeResponse{Node: nodes.Node{Name: "debug-1"}},
+	}
+
+	testutil.RunResourceTest(t, fixture, func(ctx context.Context, mockReq *requests.MockRequest) (nodes.NodeResponse, error) {
+		d := &DebugResource{r: mockReq}
+		return d.CreateNode(ctx, CreateNodeRequest{User: "alice", Key: NewRegistrationKey(versions.ServerVersion{}), Name: "debug-1"})
+	})
+}
+
+func TestNewRegistrationKey(t *testing.T) {
+	id := NewRegistrationKey(versions.ServerVersion{Minor: 25})
+	assert.Regexp(t, regexp.MustCompile(`^[A-Za-z0-9_-]{24}$`), id)
+	assert.NotEqual(t, id, NewRegistrationKey(versions.ServerVersion{Minor: 25}))
+
+	assert.Regexp(t, regexp.MustCompile(`^mkey:[0-9a-f]{64}$`), NewRegistrationKey(versions.ServerVersion{Minor: 24}))
+}
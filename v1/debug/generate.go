@@ -0,0 +1,168 @@
+package debug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/hibare/headscale-client-go/iterate"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/nodes"
+)
+
+const (
+	// DefaultNamePrefix is the prefix of the names of generated nodes unless GenerateOptions.NamePrefix is set.
+	DefaultNamePrefix = "debug"
+
+	// nameSuffixLength is the number of random letters ending the names of generated nodes.
+	nameSuffixLength = 4
+
+	// octetValues is the number of values of an IPv4 address octet.
+	octetValues = 256
+)
+
+// ErrNoUsers is returned by Generate when no user is given.
+var ErrNoUsers = errors.New("no users to own the generated nodes")
+
+// GenerateOptions configures Generate.
+type GenerateOptions struct {
+	// Count is the number of nodes to create.
+	Count int
+
+	// Users are the names of the users owning the nodes, assigned in turn. The users must exist.
+	Users []string
+
+	// NamePrefix starts the node names, which continue with the node number and random letters,
+	// e.g. "debug-7-qxmo". Defaults to DefaultNamePrefix.
+	NamePrefix string
+
+	// Tags are assigned at random, each to about half of the nodes. They must be owned in the
+	// tagOwners section of the policy.
+	Tags []string
+
+	// SubnetRoutes is the number of random /24 routes within 10.0.0.0/8 each node advertises.
+	SubnetRoutes int
+
+	// ExitNodes is the number of nodes, from the first, that also advertise the exit routes.
+	ExitNodes int
+
+	// Seed makes names, tags and routes reproducible; keys are always random. Zero picks a random seed.
+	Seed uint64
+}
+
+// Fleet is a set of generated nodes.
+type Fleet struct {
+	// Nodes are the registered nodes, in creation order.
+	Nodes []nodes.Node
+
+	r nodes.NodeResourceInterface
+}
+
+// Generate creates opt.Count fake nodes with CreateNode, registers them and applies their tags.
+// If a node fails, Generate stops and returns the error together with the fleet of the nodes
+// registered so far, so that they can be torn down.
+func (d *DebugResource) Generate(ctx context.Context, opt GenerateOptions) (*Fleet, error) {
+	nodeResource := nodes.NewNodeResource(d.r)
+	fleet := &Fleet{r: nodeResource}
+
+	if len(opt.Users) == 0 {
+		return fleet, ErrNoUsers
+	}
+	prefix := opt.NamePrefix
+	if prefix == "" {
+		prefix = DefaultNamePrefix
+	}
+	seed := opt.Seed
+	if seed == 0 {
+		seed = rand.Uint64() //nolint:gosec // reason: seeds test data only, keys use crypto/rand
+	}
+	rng := rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // reason: reproducible test data, keys use crypto/rand
+
+	for i := range opt.Count {
+		req := CreateNodeRequest{
+			User:   opt.Users[i%len(opt.Users)],
+			Key:    NewRegistrationKey(requests.ServerVersion(d.r)),
+			Name:   fmt.Sprintf("%s-%d-%s", prefix, i+1, randomLetters(rng, nameSuffixLength)),
+			Routes: randomRoutes(rng, opt.SubnetRoutes),
+		}
+		if i < opt.ExitNodes {
+			req.Routes = append(req.Routes, nodes.ExitRouteIPv4, nodes.ExitRouteIPv6)
+		}
+
+		var tags []string
+		for _, t := range opt.Tags {
+			if rng.IntN(2) == 0 {
+				tags = append(tags, t)
+			}
+		}
+
+		node, err := d.generateNode(ctx, nodeResource, req, tags)
+		if node.ID != "" {
+			fleet.Nodes = append(fleet.Nodes, node)
+		}
+		if err != nil {
+			return fleet, fmt.Errorf("generate node %d of %d: %w", i+1, opt.Count, err)
+		}
+	}
+	return fleet, nil
+}
+
+// generateNode creates, registers and tags one node.
+func (d *DebugResource) generateNode(ctx context.Context, r nodes.NodeResourceInterface, req CreateNodeRequest, tags []string) (nodes.Node, error) {
+	if _, err := d.CreateNode(ctx, req); err != nil {
+		return nodes.Node{}, err
+	}
+
+	registered, err := r.Register(ctx, req.User, req.Key)
+	if err != nil {
+		return nodes.Node{}, err
+	}
+	if len(tags) == 0 {
+		return registered.Node, nil
+	}
+
+	tagged, err := r.SetTags(ctx, registered.Node.ID, tags)
+	if err != nil {
+		// The node exists; return it so that it is torn down with the others.
+		return registered.Node, err
+	}
+	return tagged.Node, nil
+}
+
+// Teardown deletes the nodes of the fleet. Nodes that could not be deleted stay in the fleet, so
+// Teardown can be called again; the error joins their errors.
+func (f *Fleet) Teardown(ctx context.Context) error {
+	results, err := f.r.Bulk(ctx, iterate.FromSlice(f.Nodes), nodes.DeleteOperation(), nodes.BulkOptions{})
+
+	var remaining []nodes.Node
+	var errs []error
+	for _, res := range results {
+		if res.Status != nodes.BulkSucceeded {
+			remaining = append(remaining, res.Node)
+		}
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("delete node %s: %w", res.Node.ID, res.Err))
+		}
+	}
+	f.Nodes = remaining
+	return errors.Join(append(errs, err)...)
+}
+
+// randomLetters returns n random lowercase letters.
+func randomLetters(rng *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('a' + rng.IntN('z'-'a'+1))
+	}
+	return string(b)
+}
+
+// randomRoutes returns n random /24 routes within 10.0.0.0/8.
+func randomRoutes(rng *rand.Rand, n int) []string {
+	routes := make([]string, n)
+	for i := range routes {
+		routes[i] = fmt.Sprintf("10.%d.%d.0/24", rng.IntN(octetValues), rng.IntN(octetValues))
+	}
+	return routes
+}
@@ -0,0 +1,119 @@
+package debug
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/nodes"
+	"github.com/hibare/headscale-client-go/v1/policy"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer mocks the endpoints used by Generate and Teardown for up to max nodes. Registered
+// nodes get the IDs 1 to max in order.
+type fakeServer struct {
+	*requests.MockRequest
+
+	created  []CreateNodeRequest
+	tagged   map[string][]string
+	deleted  []string
+	register int
+}
+
+func newFakeServer(maxNodes int) *fakeServer {
+	s := &fakeServer{MockRequest: new(requests.MockRequest), tagged: map[string][]string{}}
+	s.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+
+	testutil.MockEndpoint(s.MockRequest, http.MethodPost, []any{"debug", "node"}, func(opt requests.RequestOptions) {
+		s.created = append(s.created, opt.Body.(CreateNodeRequest)) //nolint:errcheck // reason: type assertion in test
+	}, nil)
+	testutil.MockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "register"}, nil, func(v any) {
+		s.register++
+		req := s.created[s.register-1]
+		*v.(*nodes.NodeResponse) = nodes.NodeResponse{Node: nodes.Node{ //nolint:errcheck // reason: type assertion in test
+			ID: fmt.Sprint(s.register), GivenName: req.Name, AvailableRoutes: req.Routes,
+		}}
+	})
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"policy"}, nil, func(v any) {
+		*v.(*policy.Policy) = policy.Policy{Policy: `{"tagOwners": {"tag:web": [], "tag:db": []}}`} //nolint:errcheck // reason: type assertion in test
+	})
+
+	for i := 1; i <= maxNodes; i++ {
+		id := fmt.Sprint(i)
+		testutil.MockEndpoint(s.MockRequest, http.MethodPost, []any{"node", id, "tags"}, func(opt requests.RequestOptions) {
+			s.tagged[id] = opt.Body.(nodes.SetTagsRequest).Tags //nolint:errcheck // reason: type assertion in test
+		}, func(v any) {
+			*v.(*nodes.NodeResponse) = nodes.NodeResponse{Node: nodes.Node{ID: id, Tags: s.tagged[id]}} //nolint:errcheck // reason: type assertion in test
+		})
+		testutil.MockEndpoint(s.MockRequest, http.MethodDelete, []any{"node", id}, func(requests.RequestOptions) {
+			s.deleted = append(s.deleted, id)
+		}, nil)
+	}
+	return s
+}
+
+func TestDebugResource_Generate(t *testing.T) {
+	s := newFakeServer(6)
+	d := &DebugResource{r: s}
+
+	opt := GenerateOptions{
+		Count:        6,
+		Users:        []string{"alice", "bob"},
+		NamePrefix:   "load",
+		Tags:         []string{"tag:web", "tag:db"},
+		SubnetRoutes: 2,
+		ExitNodes:    1,
+		Seed:         42,
+	}
+	fleet, err := d.Generate(t.Context(), opt)
+	require.NoError(t, err)
+	require.Len(t, fleet.Nodes, 6)
+	require.Len(t, s.created, 6)
+
+	keys := map[string]bool{}
+	for i, req := range s.created {
+		assert.Equal(t, opt.Users[i%2], req.User)
+		assert.Regexp(t, fmt.Sprintf(`^load-%d-[a-z]{4}$`, i+1), req.Name)
+		keys[req.Key] = true
+
+		wantRoutes := 2
+		if i == 0 {
+			wantRoutes = 4
+			assert.Equal(t, []string{nodes.ExitRouteIPv4, nodes.ExitRouteIPv6}, req.Routes[2:])
+		}
+		assert.Len(t, req.Routes, wantRoutes)
+	}
+	assert.Len(t, keys, 6, "keys are unique")
+	assert.NotEmpty(t, s.tagged, "some nodes are tagged")
+
+	// The same seed gives the same names and routes.
+	again, err := (&DebugResource{r: newFakeServer(6)}).Generate(t.Context(), opt)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.Nodes[0].GivenName, again.Nodes[0].GivenName)
+	assert.Equal(t, fleet.Nodes[0].AvailableRoutes, again.Nodes[0].AvailableRoutes)
+
+	require.NoError(t, fleet.Teardown(t.Context()))
+	assert.ElementsMatch(t, []string{"1", "2", "3", "4", "5", "6"}, s.deleted)
+	assert.Empty(t, fleet.Nodes)
+}
+
+func TestDebugResource_Generate_Errors(t *testing.T) {
+	d := &DebugResource{r: newFakeServer(1)}
+	_, err := d.Generate(t.Context(), GenerateOptions{Count: 1})
+	require.ErrorIs(t, err, ErrNoUsers)
+
+	s := newFakeServer(2)
+	d = &DebugResource{r: s}
+	fleet, err := d.Generate(t.Context(), GenerateOptions{Count: 2, Users: []string{"alice"}, Tags: []string{"tag:cache"}, Seed: 1})
+	require.ErrorIs(t, err, nodes.ErrTagNotOwned)
+	require.NotEmpty(t, fleet.Nodes)
+	require.Len(t, fleet.Nodes, len(s.created), "the node that failed tagging is kept for teardown")
+
+	require.NoError(t, fleet.Teardown(t.Context()))
+	assert.Len(t, s.deleted, len(s.created))
+}
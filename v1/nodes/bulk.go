@@ -0,0 +1,344 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+)
+
+// DefaultBulkConcurrency is the number of operations Bulk runs at once unless BulkOptions.Concurrency is set.
+const DefaultBulkConcurrency = 4
+
+// ErrSkipped is wrapped by the errors Operation.Apply returns to report a node as skipped.
+var ErrSkipped = errors.New("skipped")
+
+// BulkStatus is the outcome of an operation on one node.
+type BulkStatus int
+
+const (
+	// BulkPending means the operation did not run on the node: it is planned in a dry run, or the
+	// run stopped before reaching the node.
+	BulkPending BulkStatus = iota
+
+	// BulkSucceeded means the operation was applied.
+	BulkSucceeded
+
+	// BulkSkipped means the node needed no change.
+	BulkSkipped
+
+	// BulkFailed means the operation returned an error.
+	BulkFailed
+)
+
+func (s BulkStatus) String() string {
+	switch s {
+	case BulkPending:
+		return "pending"
+	case BulkSucceeded:
+		return "succeeded"
+	case BulkSkipped:
+		return "skipped"
+	case BulkFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("BulkStatus(%d)", int(s))
+	}
+}
+
+// Operation is an action Bulk applies to each selected node.
+type Operation struct {
+	// Name describes the action in plans, e.g. "expire".
+	Name string
+
+	// Check is optional. It returns why node needs no change, e.g. "already expired", or "" to
+	// apply the operation. It runs before the operation, including in dry runs.
+	Check func(node Node) string
+
+	// Apply performs the action and returns the node afterwards. It returns an error wrapping
+	// ErrSkipped if the node turns out to need no change.
+	Apply func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error)
+}
+
+// BulkOptions configures Bulk.
+type BulkOptions struct {
+	// Concurrency is the maximum number of operations running at once. Defaults to DefaultBulkConcurrency.
+	Concurrency int
+
+	// Interval is the minimum time between starting two operations, to stay below the rate limits
+	// of the server or a proxy in front of it. Zero starts them as fast as workers are free.
+	Interval time.Duration
+
+	// StopOnError stops starting operations after the first failure. Operations already running
+	// are canceled, and nodes not reached are left pending.
+	StopOnError bool
+
+	// DryRun checks each node but applies nothing. The nodes to change are left pending.
+	DryRun bool
+
+	// Plan receives one line per selected node describing what is or would be done, if set.
+	Plan io.Writer
+}
+
+// BulkResult is the outcome of an operation on one node.
+type BulkResult struct {
+	// Node is the node after the operation, or as selected if it did not succeed.
+	Node   Node
+	Status BulkStatus
+
+	// Reason says why a skipped node needed no change.
+	Reason string
+
+	// Err is the error of a failed operation.
+	Err error
+}
+
+// Bulk applies op to the nodes yielded by nodes, e.g. by Query or All, and reports the outcome per
+// node in selection order. By default each node is processed even if others fail. The error is set
+// if the nodes could not be selected, ctx was canceled, or an operation failed with StopOnError set.
+func (n *NodeResource) Bulk(ctx context.Context, nodes iter.Seq2[Node, error], op Operation, opt BulkOptions) ([]BulkResult, error) {
+	selected, err := iterate.Collect(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkResult, len(selected))
+	var todo []int
+	for i, node := range selected {
+		results[i].Node = node
+		if op.Check != nil {
+			if reason := op.Check(node); reason != "" {
+				results[i].Status = BulkSkipped
+				results[i].Reason = reason
+				continue
+			}
+		}
+		todo = append(todo, i)
+	}
+
+	if !opt.DryRun {
+		err = n.runBulk(ctx, op, opt, results, todo)
+	}
+
+	if opt.Plan != nil {
+		writePlan(opt.Plan, op, results, opt.DryRun)
+	}
+	return results, err
+}
+
+// runBulk applies op to the nodes of results at the indexes todo, with opt.Concurrency workers.
+func (n *NodeResource) runBulk(ctx context.Context, op Operation, opt BulkOptions, results []BulkResult, todo []int) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	workers := opt.Concurrency
+	if workers <= 0 {
+		workers = DefaultBulkConcurrency
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(todo)) {
+		wg.Go(func() {
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				// Each index is sent once, so workers never write the same result.
+				node, err := op.Apply(ctx, n, results[i].Node)
+				switch {
+				case errors.Is(err, ErrSkipped):
+					results[i].Status = BulkSkipped
+					results[i].Reason = err.Error()
+				case err != nil:
+					results[i].Status = BulkFailed
+					results[i].Err = err
+					if opt.StopOnError {
+						cancel(fmt.Errorf("%s node %s: %w", op.Name, results[i].Node.ID, err))
+					}
+				default:
+					results[i].Status = BulkSucceeded
+					results[i].Node = node
+				}
+			}
+		})
+	}
+
+	var throttle *time.Ticker
+	if opt.Interval > 0 {
+		throttle = time.NewTicker(opt.Interval)
+		defer throttle.Stop()
+	}
+
+feed:
+	for k, i := range todo {
+		if throttle != nil && k > 0 {
+			select {
+			case <-throttle.C:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return context.Cause(ctx)
+}
+
+// writePlan writes one line per result to w.
+func writePlan(w io.Writer, op Operation, results []BulkResult, dryRun bool) {
+	for _, r := range results {
+		node := nodeLabel(r.Node)
+		switch {
+		case r.Status == BulkSkipped:
+			_, _ = fmt.Fprintf(w, "skip %s: %s\n", node, r.Reason)
+		case r.Status == BulkPending && dryRun:
+			_, _ = fmt.Fprintf(w, "%s %s\n", op.Name, node)
+		case r.Status == BulkFailed:
+			_, _ = fmt.Fprintf(w, "%s %s: %v\n", op.Name, node, r.Err)
+		default:
+			_, _ = fmt.Fprintf(w, "%s %s: %s\n", op.Name, node, r.Status)
+		}
+	}
+}
+
+// nodeLabel identifies node in plans, e.g. "node 7 (laptop)".
+func nodeLabel(node Node) string {
+	name := node.GivenName
+	if name == "" {
+		name = node.Name
+	}
+	return fmt.Sprintf("node %s (%s)", node.ID, name)
+}
+
+// ExpireOperation expires the selected nodes, skipping expired ones.
+func ExpireOperation() Operation {
+	return Operation{
+		Name: "expire",
+		Check: func(node Node) string {
+			if Expired().Match(node) {
+				return "already expired"
+			}
+			return ""
+		},
+		Apply: func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error) {
+			return node, r.Expire(ctx, node.ID)
+		},
+	}
+}
+
+// ExtendExpiryOperation postpones the expiry of the selected nodes by d with ExtendExpiry, skipping
+// nodes that never expire.
+func ExtendExpiryOperation(d time.Duration) Operation {
+	return Operation{
+		Name: "extend expiry of",
+		Check: func(node Node) string {
+			if node.NeverExpires() {
+				return "never expires"
+			}
+			return ""
+		},
+		Apply: func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error) {
+			resp, err := r.ExtendExpiry(ctx, node.ID, d)
+			return resp.Node, err
+		},
+	}
+}
+
+// DeleteOperation deletes the selected nodes.
+func DeleteOperation() Operation {
+	return Operation{
+		Name: "delete",
+		Apply: func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error) {
+			return node, r.Delete(ctx, node.ID)
+		},
+	}
+}
+
+// RenameOperation renames each selected node to name(node), skipping nodes named so already.
+func RenameOperation(name func(node Node) string) Operation {
+	return Operation{
+		Name: "rename",
+		Check: func(node Node) string {
+			if name(node) == node.GivenName {
+				return "already named " + node.GivenName
+			}
+			return ""
+		},
+		Apply: func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error) {
+			resp, err := r.Rename(ctx, node.ID, name(node))
+			return resp.Node, err
+		},
+	}
+}
+
+// AddTagsOperation adds tags to the selected nodes with AddTags, skipping nodes that have them all.
+func AddTagsOperation(tags ...string) Operation {
+	return Operation{
+		Name: "add tags to",
+		Check: func(node Node) string {
+			if len(difference(tags, node.Tags)) == 0 {
+				return "already tagged"
+			}
+			return ""
+		},
+		Apply: func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error) {
+			return tagChangeNode(r.AddTags(ctx, node.ID, tags))
+		},
+	}
+}
+
+// RemoveTagsOperation removes tags from the selected nodes with RemoveTags, skipping nodes that
+// have none of them.
+func RemoveTagsOperation(tags ...string) Operation {
+	return Operation{
+		Name: "remove tags from",
+		Check: func(node Node) string {
+			if len(difference(tags, node.Tags)) == len(uniqueTags(tags)) {
+				return "none of the tags set"
+			}
+			return ""
+		},
+		Apply: func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error) {
+			return tagChangeNode(r.RemoveTags(ctx, node.ID, tags))
+		},
+	}
+}
+
+// tagChangeNode returns the node of change, or an error wrapping ErrSkipped if nothing changed.
+func tagChangeNode(change TagChange, err error) (Node, error) {
+	if err == nil && !change.Changed() {
+		return change.Node, fmt.Errorf("%w: tags unchanged", ErrSkipped)
+	}
+	return change.Node, err
+}
+
+// ApproveRoutesOperation sets the approved routes of the selected nodes to routes, skipping nodes
+// with exactly those routes approved.
+func ApproveRoutesOperation(routes ...string) Operation {
+	return Operation{
+		Name: "approve routes of",
+		Check: func(node Node) string {
+			if sameElements(routes, node.ApprovedRoutes) {
+				return "routes already approved"
+			}
+			return ""
+		},
+		Apply: func(ctx context.Context, r NodeResourceInterface, node Node) (Node, error) {
+			resp, err := r.ApproveRoutes(ctx, node.ID, routes)
+			return resp.Node, err
+		},
+	}
+}
@@ -0,0 +1,192 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBroken = errors.New("broken")
+
+// failingOperation fails on the nodes with the IDs fail and succeeds elsewhere, recording the IDs applied.
+func failingOperation(applied *sync.Map, fail ...string) Operation {
+	return Operation{
+		Name: "poke",
+		Apply: func(_ context.Context, _ NodeResourceInterface, node Node) (Node, error) {
+			applied.Store(node.ID, true)
+			for _, id := range fail {
+				if node.ID == id {
+					return node, errBroken
+				}
+			}
+			node.GivenName = "poked"
+			return node, nil
+		},
+	}
+}
+
+func nodeIDs(ids ...string) iter.Seq2[Node, error] {
+	nodes := make([]Node, len(ids))
+	for i, id := range ids {
+		nodes[i] = Node{ID: id, GivenName: "node-" + id}
+	}
+	return iterate.FromSlice(nodes)
+}
+
+func statuses(results []BulkResult) []BulkStatus {
+	out := make([]BulkStatus, len(results))
+	for i, r := range results {
+		out[i] = r.Status
+	}
+	return out
+}
+
+func TestNodeResource_Bulk_ContinueOnError(t *testing.T) {
+	n := &NodeResource{}
+	var applied sync.Map
+
+	results, err := n.Bulk(t.Context(), nodeIDs("1", "2", "3"), failingOperation(&applied, "2"), BulkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []BulkStatus{BulkSucceeded, BulkFailed, BulkSucceeded}, statuses(results))
+	assert.Equal(t, "poked", results[0].Node.GivenName)
+	assert.Equal(t, "node-2", results[1].Node.GivenName, "failed nodes are reported as selected")
+	require.ErrorIs(t, results[1].Err, errBroken)
+}
+
+func TestNodeResource_Bulk_StopOnError(t *testing.T) {
+	n := &NodeResource{}
+	var applied sync.Map
+
+	results, err := n.Bulk(t.Context(), nodeIDs("1", "2", "3", "4"), failingOperation(&applied, "2"),
+		BulkOptions{Concurrency: 1, StopOnError: true})
+	require.ErrorIs(t, err, errBroken)
+	assert.Contains(t, err.Error(), "poke node 2")
+	assert.Equal(t, []BulkStatus{BulkSucceeded, BulkFailed, BulkPending, BulkPending}, statuses(results))
+	_, ok := applied.Load("4")
+	assert.False(t, ok)
+}
+
+func TestNodeResource_Bulk_DryRun(t *testing.T) {
+	n := &NodeResource{}
+	nodes := iterate.FromSlice([]Node{
+		{ID: "1", GivenName: "laptop"},
+		{ID: "2", Name: "phone", Expiry: time.Now().Add(-time.Hour)},
+	})
+	var plan bytes.Buffer
+
+	op := ExpireOperation()
+	op.Apply = func(context.Context, NodeResourceInterface, Node) (Node, error) {
+		t.Fatal("dry run applied the operation")
+		return Node{}, nil
+	}
+
+	results, err := n.Bulk(t.Context(), nodes, op, BulkOptions{DryRun: true, Plan: &plan})
+	require.NoError(t, err)
+	assert.Equal(t, []BulkStatus{BulkPending, BulkSkipped}, statuses(results))
+	assert.Equal(t, "expire node 1 (laptop)\nskip node 2 (phone): already expired\n", plan.String())
+}
+
+func TestNodeResource_Bulk_Concurrency(t *testing.T) {
+	n := &NodeResource{}
+	var running, peak atomic.Int32
+
+	op := Operation{
+		Name: "wait",
+		Apply: func(_ context.Context, _ NodeResourceInterface, node Node) (Node, error) {
+			now := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return node, nil
+		},
+	}
+
+	results, err := n.Bulk(t.Context(), nodeIDs("1", "2", "3", "4", "5", "6", "7", "8"), op, BulkOptions{Concurrency: 2})
+	require.NoError(t, err)
+	assert.Len(t, results, 8)
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+}
+
+func TestNodeResource_Bulk_Interval(t *testing.T) {
+	n := &NodeResource{}
+	var applied sync.Map
+	const interval = 10 * time.Millisecond
+
+	start := time.Now()
+	_, err := n.Bulk(t.Context(), nodeIDs("1", "2", "3"), failingOperation(&applied), BulkOptions{Interval: interval})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 2*interval)
+}
+
+func TestNodeResource_Bulk_Canceled(t *testing.T) {
+	n := &NodeResource{}
+	var applied sync.Map
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	results, err := n.Bulk(ctx, nodeIDs("1", "2"), failingOperation(&applied), BulkOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []BulkStatus{BulkPending, BulkPending}, statuses(results))
+}
+
+func TestNodeResource_Bulk_SelectionError(t *testing.T) {
+	n := &NodeResource{}
+	var applied sync.Map
+	nodes := iterate.Fetch(func() ([]Node, error) { return nil, errBroken })
+
+	results, err := n.Bulk(t.Context(), nodes, failingOperation(&applied), BulkOptions{})
+	require.ErrorIs(t, err, errBroken)
+	assert.Nil(t, results)
+}
+
+func TestBulkOperations(t *testing.T) {
+	m := new(requests.MockRequest)
+	m.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+	n := &NodeResource{r: m}
+
+	var expired []string
+	mockEndpoint(m, http.MethodPost, []any{"node", "1", "expire"}, func(requests.RequestOptions) { expired = append(expired, "1") }, nil)
+	mockEndpoint(m, http.MethodPost, []any{"node", "1", "rename", "web-1"}, nil, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: Node{ID: "1", GivenName: "web-1"}} //nolint:errcheck // reason: type assertion in test
+	})
+
+	nodes := iterate.FromSlice([]Node{{ID: "1", GivenName: "web"}, {ID: "2", GivenName: "web-2", Expiry: time.Unix(1, 0)}})
+
+	results, err := n.Bulk(t.Context(), nodes, ExpireOperation(), BulkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []BulkStatus{BulkSucceeded, BulkSkipped}, statuses(results))
+	assert.Equal(t, []string{"1"}, expired)
+
+	rename := RenameOperation(func(node Node) string { return "web-" + node.ID })
+	results, err = n.Bulk(t.Context(), nodes, rename, BulkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []BulkStatus{BulkSucceeded, BulkSkipped}, statuses(results))
+	assert.Equal(t, "web-1", results[0].Node.GivenName)
+	assert.Equal(t, "already named web-2", results[1].Reason)
+
+	assert.Equal(t, "already tagged", AddTagsOperation("tag:web").Check(Node{Tags: []string{"tag:web", "tag:db"}}))
+	assert.Empty(t, AddTagsOperation("tag:web", "tag:db").Check(Node{Tags: []string{"tag:web"}}))
+	assert.Equal(t, "none of the tags set", RemoveTagsOperation("tag:db").Check(Node{Tags: []string{"tag:web"}}))
+	assert.Empty(t, RemoveTagsOperation("tag:db", "tag:web").Check(Node{Tags: []string{"tag:web"}}))
+	assert.Equal(t, "never expires", ExtendExpiryOperation(day).Check(Node{}))
+	assert.Empty(t, ExtendExpiryOperation(day).Check(Node{Expiry: time.Unix(1, 0)}))
+	assert.Equal(t, "routes already approved",
+		ApproveRoutesOperation("10.0.0.0/8", "::/0").Check(Node{ApprovedRoutes: []string{"::/0", "10.0.0.0/8"}}))
+}
@@ -0,0 +1,148 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrNotExitNode is returned by ApproveExitNode for nodes that do not advertise both exit routes.
+var ErrNotExitNode = errors.New("node does not advertise both exit routes")
+
+// exitRoutes are the exit routes of both address families, which are approved and revoked together.
+var exitRoutes = []string{ExitRouteIPv4, ExitRouteIPv6}
+
+// ExitRouteState tells which exit routes of a node are approved.
+type ExitRouteState int
+
+const (
+	// ExitRoutesNone means no exit route is approved.
+	ExitRoutesNone ExitRouteState = iota
+
+	// ExitRoutesPartial means the exit route of only one address family is approved, so clients
+	// using the node as exit node route only that family through it.
+	ExitRoutesPartial
+
+	// ExitRoutesApproved means the exit routes of both address families are approved.
+	ExitRoutesApproved
+)
+
+func (s ExitRouteState) String() string {
+	switch s {
+	case ExitRoutesNone:
+		return "none"
+	case ExitRoutesPartial:
+		return "partial"
+	case ExitRoutesApproved:
+		return "approved"
+	default:
+		return fmt.Sprintf("ExitRouteState(%d)", int(s))
+	}
+}
+
+// AdvertisesExitNode returns true if the node advertises the exit routes of both address families.
+func (n *Node) AdvertisesExitNode() bool {
+	return hasAll(n.AvailableRoutes, exitRoutes)
+}
+
+// ExitRouteState returns which exit routes of the node are approved.
+func (n *Node) ExitRouteState() ExitRouteState {
+	switch {
+	case hasAll(n.ApprovedRoutes, exitRoutes):
+		return ExitRoutesApproved
+	case n.IsExitNode():
+		return ExitRoutesPartial
+	default:
+		return ExitRoutesNone
+	}
+}
+
+// ExitNodeStatus describes a node that advertises or has approved exit routes.
+type ExitNodeStatus struct {
+	Node  Node
+	State ExitRouteState
+
+	// Healthy is true if the node is online, its key has not expired and both exit routes are approved.
+	Healthy bool
+}
+
+// Warning describes a problem with the exit routes of the node, or returns "" if there is none.
+func (e ExitNodeStatus) Warning() string {
+	if e.State != ExitRoutesPartial {
+		return ""
+	}
+
+	var approved, missing []string
+	for _, route := range exitRoutes {
+		if slices.Contains(e.Node.ApprovedRoutes, route) {
+			approved = append(approved, route)
+		} else {
+			missing = append(missing, route)
+		}
+	}
+	return fmt.Sprintf("%s: only %v approved, %v is not routed", nodeLabel(e.Node), approved, missing)
+}
+
+// ExitNodes returns the nodes matching q that advertise both exit routes or have any exit route
+// approved, e.g. User("alice") or Tag("exit") for the exit nodes of a user or tag.
+func (n *NodeResource) ExitNodes(ctx context.Context, q Query) ([]ExitNodeStatus, error) {
+	var exits []ExitNodeStatus
+	for node, err := range n.Query(ctx, q) {
+		if err != nil {
+			return nil, err
+		}
+		if !node.AdvertisesExitNode() && !node.IsExitNode() {
+			continue
+		}
+
+		state := node.ExitRouteState()
+		exits = append(exits, ExitNodeStatus{
+			Node:    node,
+			State:   state,
+			Healthy: node.Online && !Expired().Match(node) && state == ExitRoutesApproved,
+		})
+	}
+	return exits, nil
+}
+
+// ApproveExitNode approves the exit routes of both address families for a node, keeping its
+// approved subnet routes. The node must advertise both; otherwise an error wrapping ErrNotExitNode
+// is returned and nothing is changed. Nothing is sent if both are approved already.
+func (n *NodeResource) ApproveExitNode(ctx context.Context, id string) (NodeResponse, error) {
+	current, err := n.Get(ctx, id)
+	if err != nil {
+		return current, err
+	}
+	if !current.Node.AdvertisesExitNode() {
+		return current, fmt.Errorf("%w: node %s advertises %v", ErrNotExitNode, id, current.Node.AvailableRoutes)
+	}
+
+	missing := difference(exitRoutes, current.Node.ApprovedRoutes)
+	if len(missing) == 0 {
+		return current, nil
+	}
+	return n.ApproveRoutes(ctx, id, append(slices.Clone(current.Node.ApprovedRoutes), missing...))
+}
+
+// RevokeExitNode revokes the exit routes of both address families for a node, keeping its
+// approved subnet routes. Nothing is sent if neither is approved.
+func (n *NodeResource) RevokeExitNode(ctx context.Context, id string) (NodeResponse, error) {
+	current, err := n.Get(ctx, id)
+	if err != nil {
+		return current, err
+	}
+	if !current.Node.IsExitNode() {
+		return current, nil
+	}
+
+	routes := slices.DeleteFunc(slices.Clone(current.Node.ApprovedRoutes), func(r string) bool {
+		return slices.Contains(exitRoutes, r)
+	})
+	return n.ApproveRoutes(ctx, id, routes)
+}
+
+// hasAll reports whether routes contains every route of want.
+func hasAll(routes, want []string) bool {
+	return len(difference(want, routes)) == 0
+}
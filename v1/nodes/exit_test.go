@@ -0,0 +1,113 @@
+package nodes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exitServer mocks reading node 1, which is current, and approving its routes.
+type exitServer struct {
+	*requests.MockRequest
+
+	// sent are the routes sent to the approve endpoint, nil if nothing was sent.
+	sent []string
+}
+
+func newExitServer(current Node) *exitServer {
+	s := &exitServer{MockRequest: new(requests.MockRequest)}
+	s.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+
+	record := func(opt requests.RequestOptions) {
+		s.sent = opt.Body.(ApproveRoutesRequest).Routes //nolint:errcheck // reason: type assertion in test
+	}
+	mockEndpoint(s.MockRequest, http.MethodGet, []any{"node", "1"}, nil, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: current} //nolint:errcheck // reason: type assertion in test
+	})
+	mockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "1", "approve_routes"}, record, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: Node{ID: "1", ApprovedRoutes: s.sent}} //nolint:errcheck // reason: type assertion in test
+	})
+	return s
+}
+
+var exitAdvertised = []string{"10.0.0.0/24", ExitRouteIPv4, ExitRouteIPv6}
+
+func TestNodeResource_ApproveExitNode(t *testing.T) {
+	s := newExitServer(Node{ID: "1", AvailableRoutes: exitAdvertised, ApprovedRoutes: []string{"10.0.0.0/24", ExitRouteIPv4}})
+	n := &NodeResource{r: s}
+
+	resp, err := n.ApproveExitNode(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/24", ExitRouteIPv4, ExitRouteIPv6}, s.sent, "subnet routes are kept")
+	assert.Equal(t, ExitRoutesApproved, resp.Node.ExitRouteState())
+
+	s = newExitServer(Node{ID: "1", AvailableRoutes: exitAdvertised, ApprovedRoutes: []string{ExitRouteIPv6, ExitRouteIPv4}})
+	n = &NodeResource{r: s}
+	_, err = n.ApproveExitNode(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Nil(t, s.sent, "nothing is sent when both routes are approved")
+
+	s = newExitServer(Node{ID: "1", AvailableRoutes: []string{ExitRouteIPv4}})
+	n = &NodeResource{r: s}
+	_, err = n.ApproveExitNode(t.Context(), "1")
+	require.ErrorIs(t, err, ErrNotExitNode)
+	assert.Nil(t, s.sent)
+}
+
+func TestNodeResource_RevokeExitNode(t *testing.T) {
+	s := newExitServer(Node{ID: "1", ApprovedRoutes: []string{ExitRouteIPv6, "10.0.0.0/24"}})
+	n := &NodeResource{r: s}
+
+	resp, err := n.RevokeExitNode(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/24"}, s.sent)
+	assert.Equal(t, ExitRoutesNone, resp.Node.ExitRouteState())
+
+	s = newExitServer(Node{ID: "1", ApprovedRoutes: []string{ExitRouteIPv4}})
+	n = &NodeResource{r: s}
+	_, err = n.RevokeExitNode(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, s.sent, "an empty list is sent, not null")
+
+	s = newExitServer(Node{ID: "1", ApprovedRoutes: []string{"10.0.0.0/24"}})
+	n = &NodeResource{r: s}
+	_, err = n.RevokeExitNode(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Nil(t, s.sent)
+}
+
+func TestNodeResource_ExitNodes(t *testing.T) {
+	listed := []Node{
+		{ID: "1", GivenName: "gw", Online: true, AvailableRoutes: exitAdvertised, ApprovedRoutes: exitRoutes},
+		{ID: "2", GivenName: "half", Online: true, AvailableRoutes: exitAdvertised, ApprovedRoutes: []string{ExitRouteIPv4}},
+		{ID: "3", GivenName: "idle", AvailableRoutes: exitAdvertised, ApprovedRoutes: exitRoutes},
+		{ID: "4", GivenName: "old", Online: true, ApprovedRoutes: exitRoutes, Expiry: time.Unix(1, 0)},
+		{ID: "5", GivenName: "subnet", Online: true, AvailableRoutes: []string{"10.0.0.0/24"}},
+	}
+	m := new(requests.MockRequest)
+	m.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+	mockEndpoint(m, http.MethodGet, []any{"node"}, nil, func(v any) {
+		*v.(*NodesResponse) = NodesResponse{Nodes: listed} //nolint:errcheck // reason: type assertion in test
+	})
+	n := &NodeResource{r: m}
+
+	exits, err := n.ExitNodes(t.Context(), Query{})
+	require.NoError(t, err)
+	require.Len(t, exits, 4)
+
+	var healthy []string
+	for _, e := range exits {
+		if e.Healthy {
+			healthy = append(healthy, e.Node.ID)
+		}
+	}
+	assert.Equal(t, []string{"1"}, healthy)
+	assert.Equal(t, ExitRoutesPartial, exits[1].State)
+	assert.Equal(t, "node 2 (half): only [0.0.0.0/0] approved, [::/0] is not routed", exits[1].Warning())
+	assert.Empty(t, exits[0].Warning())
+}
@@ -0,0 +1,397 @@
+package nodes
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+const (
+	// DefaultExpiryWindow is the default time before expiry at which a node counts as expiring soon.
+	DefaultExpiryWindow = 7 * day
+
+	// DefaultExpiryCheckInterval is the default interval between checks in ExpiryMonitor.Run.
+	DefaultExpiryCheckInterval = time.Hour
+)
+
+// ErrNeverExpires is returned by ExtendExpiry for nodes that never expire.
+var ErrNeverExpires = errors.New("node never expires")
+
+// ExpiryState tells whether and when the key of a node expires.
+type ExpiryState int
+
+const (
+	// ExpiryNever means the node never expires.
+	ExpiryNever ExpiryState = iota
+
+	// ExpiryValid means the node expires later than the expiry window.
+	ExpiryValid
+
+	// ExpiringSoon means the node expires within the expiry window.
+	ExpiringSoon
+
+	// ExpiryExpired means the node has expired.
+	ExpiryExpired
+)
+
+func (s ExpiryState) String() string {
+	switch s {
+	case ExpiryNever:
+		return "never"
+	case ExpiryValid:
+		return "valid"
+	case ExpiringSoon:
+		return "expiring soon"
+	case ExpiryExpired:
+		return "expired"
+	default:
+		return fmt.Sprintf("ExpiryState(%d)", int(s))
+	}
+}
+
+// NeverExpires returns true if the node has no expiry, which the server reports as a zero Expiry.
+func (n *Node) NeverExpires() bool {
+	return n.Expiry.IsZero()
+}
+
+// HasExpired returns true if the node has expired at the time now.
+func (n *Node) HasExpired(now time.Time) bool {
+	return !n.NeverExpires() && !n.Expiry.After(now)
+}
+
+// ExpiresWithin returns true if the node has not expired at the time now but expires within d.
+func (n *Node) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !n.NeverExpires() && !n.HasExpired(now) && n.Expiry.Sub(now) <= d
+}
+
+// ExpiryState returns the expiry state of the node at the time now, counting nodes that expire
+// within window as expiring soon.
+func (n *Node) ExpiryState(now time.Time, window time.Duration) ExpiryState {
+	switch {
+	case n.NeverExpires():
+		return ExpiryNever
+	case n.HasExpired(now):
+		return ExpiryExpired
+	case n.ExpiresWithin(now, window):
+		return ExpiringSoon
+	default:
+		return ExpiryValid
+	}
+}
+
+// ExpiringWithin matches nodes that have not expired but expire within d.
+func ExpiringWithin(d time.Duration) Query {
+	return And(Not(Expired()), CompareTime(ExpiryField, LessOrEqual, d))
+}
+
+// SetExpiry sets the expiry of a node. A time in the past expires the node immediately, like Expire.
+//
+// Releases before v0.28.0 ignore the expiry and expire the node immediately, so the server version
+// must be known: SetExpiry returns an error wrapping versions.ErrUnknownServerVersion otherwise.
+func (n *NodeResource) SetExpiry(ctx context.Context, id string, expiry time.Time) (NodeResponse, error) {
+	var node NodeResponse
+
+	if err := requests.ServerVersion(n.r).RequireKnown(versions.CapabilityNodeExpiry); err != nil {
+		return node, err
+	}
+
+	url := n.r.BuildURL("node", id, "expire")
+	req, err := n.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{
+		QueryParams: map[string]any{"expiry": expiry.UTC().Format(time.RFC3339Nano)},
+	})
+	if err != nil {
+		return node, err
+	}
+
+	err = n.r.Do(ctx, req, &node)
+	return node, err
+}
+
+// ExtendExpiry postpones the expiry of a node by d, counting from now if it has expired already.
+// Nodes that never expire are left alone with an error wrapping ErrNeverExpires, as extending
+// them would make them expire.
+func (n *NodeResource) ExtendExpiry(ctx context.Context, id string, d time.Duration) (NodeResponse, error) {
+	current, err := n.Get(ctx, id)
+	if err != nil {
+		return current, err
+	}
+	if current.Node.NeverExpires() {
+		return current, fmt.Errorf("%w: %s", ErrNeverExpires, nodeLabel(current.Node))
+	}
+
+	from := time.Now()
+	if current.Node.Expiry.After(from) {
+		from = current.Node.Expiry
+	}
+	return n.SetExpiry(ctx, id, from.Add(d))
+}
+
+// Expiring returns the nodes matching q that have not expired but expire within window, soonest first.
+func (n *NodeResource) Expiring(ctx context.Context, q Query, window time.Duration) ([]Node, error) {
+	var expiring []Node
+	for node, err := range n.Query(ctx, And(q, ExpiringWithin(window))) {
+		if err != nil {
+			return nil, err
+		}
+		expiring = append(expiring, node)
+	}
+	slices.SortStableFunc(expiring, func(a, b Node) int { return a.Expiry.Compare(b.Expiry) })
+	return expiring, nil
+}
+
+// NodeExpiry is a node of an ExpiryReport.
+type NodeExpiry struct {
+	Node  Node
+	State ExpiryState
+
+	// DaysLeft is the number of whole days until the node expires; for expired nodes, the negated
+	// number of whole days since.
+	DaysLeft int
+}
+
+func (e NodeExpiry) String() string {
+	label := nodeLabel(e.Node)
+	switch {
+	case e.State == ExpiryExpired && e.DaysLeft == 0:
+		return label + " expired today"
+	case e.State == ExpiryExpired:
+		return fmt.Sprintf("%s expired %s ago", label, days(-e.DaysLeft))
+	case e.DaysLeft == 0:
+		return label + " expires today"
+	default:
+		return fmt.Sprintf("%s expires in %s", label, days(e.DaysLeft))
+	}
+}
+
+// UserExpiry holds the expired and expiring nodes of a user.
+type UserExpiry struct {
+	User  users.User
+	Nodes []NodeExpiry
+}
+
+// ExpiryReport lists the expired nodes and the nodes expiring within a window, grouped by user.
+type ExpiryReport struct {
+	Time   time.Time
+	Window time.Duration
+
+	// Users are sorted by name; their nodes expire soonest first.
+	Users []UserExpiry
+}
+
+// NewExpiryReport returns the report of the given nodes at the time now. Nodes that never expire
+// or expire later than window are left out.
+func NewExpiryReport(nodes []Node, now time.Time, window time.Duration) ExpiryReport {
+	report := ExpiryReport{Time: now, Window: window}
+
+	byUser := map[string]int{}
+	for _, node := range nodes {
+		state := node.ExpiryState(now, window)
+		if state != ExpiringSoon && state != ExpiryExpired {
+			continue
+		}
+
+		key := cmp.Or(node.User.ID, node.User.Name)
+		i, ok := byUser[key]
+		if !ok {
+			i = len(report.Users)
+			byUser[key] = i
+			report.Users = append(report.Users, UserExpiry{User: node.User})
+		}
+		report.Users[i].Nodes = append(report.Users[i].Nodes, NodeExpiry{
+			Node:     node,
+			State:    state,
+			DaysLeft: int(node.Expiry.Sub(now) / day),
+		})
+	}
+
+	slices.SortFunc(report.Users, func(a, b UserExpiry) int { return cmp.Compare(a.User.Name, b.User.Name) })
+	for _, u := range report.Users {
+		slices.SortStableFunc(u.Nodes, func(a, b NodeExpiry) int { return a.Node.Expiry.Compare(b.Node.Expiry) })
+	}
+	return report
+}
+
+// ExpiryReport returns the report of the nodes matching q, counting nodes that expire within
+// window as expiring soon.
+func (n *NodeResource) ExpiryReport(ctx context.Context, q Query, window time.Duration) (ExpiryReport, error) {
+	var nodes []Node
+	for node, err := range n.Query(ctx, q) {
+		if err != nil {
+			return ExpiryReport{}, err
+		}
+		nodes = append(nodes, node)
+	}
+	return NewExpiryReport(nodes, time.Now(), window), nil
+}
+
+// days formats a number of days, e.g. "1 day" or "3 days".
+func days(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+// ExpiryEventType identifies an ExpiryEvent.
+type ExpiryEventType string
+
+const (
+	// EventNodeExpiring is emitted once when a node starts expiring within the window.
+	EventNodeExpiring ExpiryEventType = "node_expiring"
+
+	// EventNodeExpired is emitted once when a node has expired.
+	EventNodeExpired ExpiryEventType = "node_expired"
+
+	// EventExpiryCheckFailed is emitted when the nodes could not be listed. Err holds the cause.
+	EventExpiryCheckFailed ExpiryEventType = "expiry_check_failed"
+)
+
+// ExpiryEvent is a notification of an ExpiryMonitor.
+type ExpiryEvent struct {
+	Type ExpiryEventType
+
+	// Node is the node the event is about, with its expiry in Node.Expiry.
+	Node Node
+
+	// DaysLeft is the number of whole days until the node expires, as in NodeExpiry.
+	DaysLeft int
+
+	Err  error
+	Time time.Time
+}
+
+// ExpiryMonitorOptions contains options for creating an ExpiryMonitor.
+type ExpiryMonitorOptions struct {
+	// Query selects the monitored nodes. The zero Query monitors all nodes.
+	Query Query
+
+	// Window is how long before expiry a node is reported. Defaults to DefaultExpiryWindow.
+	Window time.Duration
+
+	// CheckInterval is the interval between checks in Run. Defaults to DefaultExpiryCheckInterval.
+	CheckInterval time.Duration
+
+	// OnEvent is called for every event. Optional.
+	OnEvent func(ExpiryEvent)
+}
+
+// expiryNotice records the expiry a node was reported with.
+type expiryNotice struct {
+	expiry time.Time
+	state  ExpiryState
+}
+
+// ExpiryMonitor notifies ahead of node expiry. Each node is reported once when it starts expiring
+// within the window and once when it has expired; changing its expiry, e.g. with ExtendExpiry,
+// makes it reported again. It is safe for concurrent use.
+type ExpiryMonitor struct {
+	nodes NodeResourceInterface
+	opt   ExpiryMonitorOptions
+	now   func() time.Time
+
+	mu       sync.Mutex
+	notified map[string]expiryNotice
+}
+
+// NewExpiryMonitor creates a new ExpiryMonitor listing nodes through r.
+func NewExpiryMonitor(r NodeResourceInterface, opt ExpiryMonitorOptions) *ExpiryMonitor {
+	if opt.Window <= 0 {
+		opt.Window = DefaultExpiryWindow
+	}
+	if opt.CheckInterval <= 0 {
+		opt.CheckInterval = DefaultExpiryCheckInterval
+	}
+
+	return &ExpiryMonitor{
+		nodes:    r,
+		opt:      opt,
+		now:      time.Now,
+		notified: map[string]expiryNotice{},
+	}
+}
+
+// Check lists the monitored nodes and returns the events for those not reported yet in their
+// current state, after passing them to OnEvent. OnEvent is called without holding the monitor's
+// lock, so it may call Check.
+func (m *ExpiryMonitor) Check(ctx context.Context) ([]ExpiryEvent, error) {
+	now := m.now()
+	var nodes []Node
+	for node, err := range m.nodes.Query(ctx, m.opt.Query) {
+		if err != nil {
+			m.emit(ExpiryEvent{Type: EventExpiryCheckFailed, Err: err, Time: now})
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	events := m.diff(nodes, now)
+	for _, e := range events {
+		m.emit(e)
+	}
+	return events, nil
+}
+
+// diff records the expiry states of nodes and returns the events for nodes not reported yet in
+// their current state.
+func (m *ExpiryMonitor) diff(nodes []Node, now time.Time) []ExpiryEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []ExpiryEvent
+	notified := make(map[string]expiryNotice, len(m.notified))
+	for _, node := range nodes {
+		state := node.ExpiryState(now, m.opt.Window)
+		if state != ExpiringSoon && state != ExpiryExpired {
+			continue
+		}
+
+		notice := expiryNotice{expiry: node.Expiry, state: state}
+		notified[node.ID] = notice
+		if prev, ok := m.notified[node.ID]; ok && prev.state == notice.state && prev.expiry.Equal(notice.expiry) {
+			continue
+		}
+
+		e := ExpiryEvent{Type: EventNodeExpiring, Node: node, DaysLeft: int(node.Expiry.Sub(now) / day), Time: now}
+		if state == ExpiryExpired {
+			e.Type = EventNodeExpired
+		}
+		events = append(events, e)
+	}
+
+	// Forget nodes that are gone or no longer expiring, so they are reported again if they return.
+	m.notified = notified
+	return events
+}
+
+// Run calls Check immediately and then every CheckInterval until ctx is cancelled. Failures are
+// reported through OnEvent and do not stop Run.
+func (m *ExpiryMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.opt.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		_, _ = m.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// emit sends e to the OnEvent callback, if any.
+func (m *ExpiryMonitor) emit(e ExpiryEvent) {
+	if m.opt.OnEvent != nil {
+		m.opt.OnEvent(e)
+	}
+}
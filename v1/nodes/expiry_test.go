@@ -0,0 +1,235 @@
+package nodes
+
+import (
+	"errors"
+	"iter"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNode_ExpiryState(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		expiry time.Time
+		want   ExpiryState
+	}{
+		{name: "never", want: ExpiryNever},
+		{name: "expired", expiry: now.Add(-time.Minute), want: ExpiryExpired},
+		{name: "expiring now", expiry: now, want: ExpiryExpired},
+		{name: "expiring soon", expiry: now.Add(7 * day), want: ExpiringSoon},
+		{name: "valid", expiry: now.Add(8 * day), want: ExpiryValid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := Node{Expiry: tt.expiry}
+			assert.Equal(t, tt.want, node.ExpiryState(now, 7*day))
+			assert.Equal(t, tt.want == ExpiryNever, node.NeverExpires())
+			assert.Equal(t, tt.want == ExpiryExpired, node.HasExpired(now))
+			assert.Equal(t, tt.want == ExpiringSoon, node.ExpiresWithin(now, 7*day))
+		})
+	}
+}
+
+// expiryServer mocks reading node 1, which is current, and setting its expiry.
+type expiryServer struct {
+	*requests.MockRequest
+
+	// sent is the expiry sent to the expire endpoint, "" if nothing was sent.
+	sent string
+}
+
+func newExpiryServer(version versions.ServerVersion, current Node) *expiryServer {
+	s := &expiryServer{MockRequest: new(requests.MockRequest)}
+	s.On("ServerVersion").Return(version).Maybe()
+
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"node", "1"}, nil, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: current} //nolint:errcheck // reason: type assertion in test
+	})
+	record := func(opt requests.RequestOptions) { s.sent = opt.QueryParams["expiry"].(string) } //nolint:errcheck // reason: type assertion in test
+	testutil.MockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "1", "expire"}, record, func(v any) {
+		expiry, _ := time.Parse(time.RFC3339Nano, s.sent)
+		*v.(*NodeResponse) = NodeResponse{Node: Node{ID: "1", Expiry: expiry}} //nolint:errcheck // reason: type assertion in test
+	})
+	return s
+}
+
+func TestNodeResource_SetExpiry(t *testing.T) {
+	expiry := time.Date(2026, 6, 1, 9, 30, 0, 0, time.FixedZone("CEST", int((2*time.Hour).Seconds())))
+
+	s := newExpiryServer(versions.ServerVersion{Minor: 28}, Node{})
+	resp, err := (&NodeResource{r: s}).SetExpiry(t.Context(), "1", expiry)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-06-01T07:30:00Z", s.sent)
+	assert.True(t, resp.Node.Expiry.Equal(expiry))
+
+	s = newExpiryServer(versions.ServerVersion{Minor: 27}, Node{})
+	_, err = (&NodeResource{r: s}).SetExpiry(t.Context(), "1", expiry)
+	require.ErrorIs(t, err, versions.ErrUnsupportedByServer)
+	assert.Empty(t, s.sent)
+
+	s = newExpiryServer(versions.ServerVersion{}, Node{})
+	_, err = (&NodeResource{r: s}).SetExpiry(t.Context(), "1", expiry)
+	require.ErrorIs(t, err, versions.ErrUnknownServerVersion, "older releases would expire the node now")
+	assert.Empty(t, s.sent)
+}
+
+func TestNodeResource_ExtendExpiry(t *testing.T) {
+	future := time.Now().Add(3 * day).Truncate(time.Second)
+
+	s := newExpiryServer(versions.ServerVersion{Minor: 28}, Node{ID: "1", Expiry: future})
+	resp, err := (&NodeResource{r: s}).ExtendExpiry(t.Context(), "1", 30*day)
+	require.NoError(t, err)
+	assert.True(t, resp.Node.Expiry.Equal(future.Add(30*day)), "extended from the current expiry")
+
+	s = newExpiryServer(versions.ServerVersion{Minor: 28}, Node{ID: "1", Expiry: time.Unix(1, 0)})
+	resp, err = (&NodeResource{r: s}).ExtendExpiry(t.Context(), "1", 30*day)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(30*day), resp.Node.Expiry, time.Minute, "extended from now")
+
+	s = newExpiryServer(versions.ServerVersion{Minor: 28}, Node{ID: "1"})
+	_, err = (&NodeResource{r: s}).ExtendExpiry(t.Context(), "1", 30*day)
+	require.ErrorIs(t, err, ErrNeverExpires)
+	assert.Empty(t, s.sent)
+}
+
+// listServer mocks listing nodes, returning the current value of *listed.
+func listServer(listed *[]Node) *requests.MockRequest {
+	m := new(requests.MockRequest)
+	m.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+	testutil.MockEndpoint(m, http.MethodGet, []any{"node"}, nil, func(v any) {
+		*v.(*NodesResponse) = NodesResponse{Nodes: *listed} //nolint:errcheck // reason: type assertion in test
+	})
+	return m
+}
+
+func TestNodeResource_ExpiryReport(t *testing.T) {
+	alice := users.User{ID: "1", Name: "alice"}
+	bob := users.User{ID: "2", Name: "bob"}
+	now := time.Now()
+	listed := []Node{
+		{ID: "1", GivenName: "laptop", User: bob, Expiry: now.Add(3*day + time.Hour)},
+		{ID: "2", GivenName: "phone", User: alice, Expiry: now.Add(-2*day - time.Hour)},
+		{ID: "3", GivenName: "server", User: alice},
+		{ID: "4", GivenName: "tablet", User: alice, Expiry: now.Add(time.Hour)},
+		{ID: "5", GivenName: "desktop", User: bob, Expiry: now.Add(30 * day)},
+	}
+	n := &NodeResource{r: listServer(&listed)}
+
+	expiring, err := n.Expiring(t.Context(), Query{}, 7*day)
+	require.NoError(t, err)
+	require.Len(t, expiring, 2)
+	assert.Equal(t, "4", expiring[0].ID, "soonest first")
+	assert.Equal(t, "1", expiring[1].ID)
+
+	report, err := n.ExpiryReport(t.Context(), Query{}, 7*day)
+	require.NoError(t, err)
+	require.Len(t, report.Users, 2)
+	assert.Equal(t, "alice", report.Users[0].User.Name)
+	assert.Equal(t, "bob", report.Users[1].User.Name)
+
+	var lines []string
+	for _, u := range report.Users {
+		for _, e := range u.Nodes {
+			lines = append(lines, e.String())
+		}
+	}
+	assert.Equal(t, []string{
+		"node 2 (phone) expired 2 days ago",
+		"node 4 (tablet) expires today",
+		"node 1 (laptop) expires in 3 days",
+	}, lines)
+}
+
+func TestExpiryMonitor(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	listed := []Node{
+		{ID: "1", GivenName: "laptop", Expiry: now.Add(2 * day)},
+		{ID: "2", GivenName: "phone", Expiry: now.Add(30 * day)},
+		{ID: "3", GivenName: "server"},
+	}
+	var emitted []ExpiryEvent
+	m := NewExpiryMonitor(&NodeResource{r: listServer(&listed)}, ExpiryMonitorOptions{
+		OnEvent: func(e ExpiryEvent) { emitted = append(emitted, e) },
+	})
+	m.now = func() time.Time { return now }
+
+	events, err := m.Check(t.Context())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventNodeExpiring, events[0].Type)
+	assert.Equal(t, "1", events[0].Node.ID)
+	assert.Equal(t, 2, events[0].DaysLeft)
+	assert.Equal(t, events, emitted)
+
+	events, err = m.Check(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, events, "nodes are reported once")
+
+	now = now.Add(3 * day)
+	events, err = m.Check(t.Context())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventNodeExpired, events[0].Type)
+
+	listed[0].Expiry = now.Add(day)
+	events, err = m.Check(t.Context())
+	require.NoError(t, err)
+	require.Len(t, events, 1, "a changed expiry is reported again")
+	assert.Equal(t, EventNodeExpiring, events[0].Type)
+}
+
+func TestExpiryMonitor_CheckFailed(t *testing.T) {
+	r := new(MockNodeResource)
+	r.On("Query", mock.Anything, mock.Anything).Return(iter.Seq2[Node, error](func(yield func(Node, error) bool) {
+		yield(Node{}, errors.New("boom"))
+	}))
+
+	var emitted []ExpiryEvent
+	m := NewExpiryMonitor(r, ExpiryMonitorOptions{OnEvent: func(e ExpiryEvent) { emitted = append(emitted, e) }})
+	_, err := m.Check(t.Context())
+	require.Error(t, err)
+	require.Len(t, emitted, 1)
+	assert.Equal(t, EventExpiryCheckFailed, emitted[0].Type)
+	assert.Equal(t, err, emitted[0].Err)
+}
+
+func TestExpiryMonitor_CheckFromCallback(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	listed := []Node{{ID: "1", GivenName: "laptop", Expiry: now.Add(2 * day)}}
+
+	var m *ExpiryMonitor
+	var nested []ExpiryEvent
+	m = NewExpiryMonitor(&NodeResource{r: listServer(&listed)}, ExpiryMonitorOptions{
+		OnEvent: func(ExpiryEvent) {
+			var err error
+			nested, err = m.Check(t.Context())
+			assert.NoError(t, err)
+		},
+	})
+	m.now = func() time.Time { return now }
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = m.Check(t.Context())
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Check called from OnEvent deadlocked")
+	}
+	assert.Empty(t, nested, "the node was recorded before OnEvent was called")
+}
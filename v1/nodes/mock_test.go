@@ -0,0 +1,37 @@
+package nodes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	// optionsArgIndex is the index of the options argument in BuildRequest(ctx, method, url, opt).
+	optionsArgIndex = 3
+
+	// responseArgIndex is the index of the response argument in Do(ctx, req, v).
+	responseArgIndex = 2
+)
+
+// mockEndpoint makes m answer method requests to the endpoint built from parts. record receives
+// the options of each request built, respond fills in the response; either may be nil.
+func mockEndpoint(m *requests.MockRequest, method string, parts []any, record func(opt requests.RequestOptions), respond func(v any)) {
+	u := &url.URL{Path: fmt.Sprintf("/%v", parts)}
+	req := &http.Request{Method: method, URL: u}
+
+	m.On("BuildURL", parts...).Return(u).Maybe()
+	m.On("BuildRequest", mock.Anything, method, u, mock.Anything).Run(func(args mock.Arguments) {
+		if record != nil {
+			record(args.Get(optionsArgIndex).(requests.RequestOptions)) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+		}
+	}).Return(req, nil).Maybe()
+	m.On("Do", mock.Anything, req, mock.Anything).Run(func(args mock.Arguments) {
+		if respond != nil {
+			respond(args.Get(responseArgIndex))
+		}
+	}).Return(nil).Maybe()
+}
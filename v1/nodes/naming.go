@@ -0,0 +1,343 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+const (
+	// minNameLength is the shortest node name Headscale v0.28.0 and newer accept.
+	minNameLength = 2
+
+	// maxNameLength is the longest DNS label.
+	maxNameLength = 63
+)
+
+var (
+	// ErrInvalidName is returned for node names that are not valid DNS labels: names must be 1 to 63
+	// lowercase letters, digits and hyphens, and must not start or end with a hyphen. Headscale
+	// v0.28.0 and newer also reject names shorter than 2 characters.
+	ErrInvalidName = errors.New("invalid node name")
+
+	// ErrNameCollision is wrapped by NameCollisionError.
+	ErrNameCollision = errors.New("node name collision")
+)
+
+// NameCollisionError is returned when a node cannot take a name because other nodes have or would
+// get it.
+type NameCollisionError struct {
+	Name string
+
+	// Nodes are the other nodes with the name.
+	Nodes []Node
+}
+
+func (e *NameCollisionError) Error() string {
+	labels := make([]string, len(e.Nodes))
+	for i, node := range e.Nodes {
+		labels[i] = nodeLabel(node)
+	}
+	return fmt.Sprintf("%s %q: also %s", ErrNameCollision, e.Name, strings.Join(labels, ", "))
+}
+
+func (e *NameCollisionError) Unwrap() error {
+	return ErrNameCollision
+}
+
+// ValidateName returns an error wrapping ErrInvalidName if name is not a valid DNS label. Rename
+// checks names with it before sending them, and also rejects names shorter than 2 characters
+// when the server is known to run Headscale v0.28.0 or newer.
+func ValidateName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: must not be empty", ErrInvalidName)
+	case len(name) > maxNameLength:
+		return fmt.Errorf("%w %q: must be at most %d characters", ErrInvalidName, name, maxNameLength)
+	case strings.ToLower(name) != name:
+		return fmt.Errorf("%w %q: must be lowercase", ErrInvalidName, name)
+	case strings.ContainsFunc(name, func(r rune) bool { return !isNameRune(r) }):
+		return fmt.Errorf("%w %q: must contain only letters, digits and hyphens", ErrInvalidName, name)
+	case strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-"):
+		return fmt.Errorf("%w %q: must not start or end with a hyphen", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// validateName is ValidateName with the limits of the server's Headscale release. An unknown
+// release is left to decide.
+func (n *NodeResource) validateName(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if v := requests.ServerVersion(n.r); !v.IsZero() && v.Supports(versions.CapabilityNodeNameMinLength) && len(name) < minNameLength {
+		return fmt.Errorf("%w %q: Headscale %s requires at least %d characters", ErrInvalidName, name, v, minNameLength)
+	}
+	return nil
+}
+
+// NormalizeName turns s into a valid node name: it lowercases s, replaces each run of other
+// characters than letters and digits with a hyphen, trims hyphens from both ends and truncates the
+// result. It returns an error wrapping ErrInvalidName if too little of s is left.
+func NormalizeName(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case isNameRune(r) && r != '-':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+
+	name := b.String()
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	name = strings.TrimRight(name, "-")
+	if err := ValidateName(name); err != nil {
+		return "", fmt.Errorf("normalize %q: %w", s, err)
+	}
+	return name, nil
+}
+
+// isNameRune reports whether r may appear in a node name.
+func isNameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-'
+}
+
+// NameData is the data a NameTemplate renders.
+type NameData struct {
+	Node Node
+	User users.User
+
+	// Hostname is the hostname the node reported, Node.Name.
+	Hostname string
+
+	ID string
+}
+
+// nameFuncs are the functions available in name templates.
+var nameFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"replace":    strings.ReplaceAll,
+	"trimPrefix": strings.TrimPrefix,
+	"trimSuffix": strings.TrimSuffix,
+	// before returns s up to sep, e.g. the local part of an email address.
+	"before": func(s, sep string) string {
+		before, _, _ := strings.Cut(s, sep)
+		return before
+	},
+}
+
+// NameTemplate renders node names from a text/template over NameData, e.g.
+// "{{.User.Name}}-{{.Hostname}}". The functions lower, replace, trimPrefix, trimSuffix and before
+// are available, e.g. {{before .User.Email "@"}}.
+type NameTemplate struct {
+	text string
+	t    *template.Template
+}
+
+// ParseNameTemplate parses a NameTemplate.
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	t, err := template.New("name").Funcs(nameFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &NameTemplate{text: text, t: t}, nil
+}
+
+// Render returns the name of node, normalized with NormalizeName.
+func (t *NameTemplate) Render(node Node) (string, error) {
+	var b strings.Builder
+	err := t.t.Execute(&b, NameData{Node: node, User: node.User, Hostname: node.Name, ID: node.ID})
+	if err != nil {
+		return "", err
+	}
+	return NormalizeName(b.String())
+}
+
+func (t *NameTemplate) String() string {
+	return t.text
+}
+
+// PlannedRename is the new name of a node in a RenamePlan.
+type PlannedRename struct {
+	Node Node
+
+	// Name is the new name, equal to Node.GivenName if the node is named so already.
+	Name string
+
+	// Err says why the node cannot be renamed, e.g. an invalid name or a NameCollisionError.
+	Err error
+}
+
+// RenamePlan is a set of renames checked for invalid names and collisions.
+type RenamePlan struct {
+	// Renames are in the order ApplyRenames processes them: nodes to rename first, ordered so that
+	// a node giving up a name is renamed before the node taking it, then the others.
+	Renames []PlannedRename
+}
+
+// Conflicts returns the renames that cannot be applied.
+func (p RenamePlan) Conflicts() []PlannedRename {
+	var conflicts []PlannedRename
+	for _, r := range p.Renames {
+		if r.Err != nil {
+			conflicts = append(conflicts, r)
+		}
+	}
+	return conflicts
+}
+
+// String returns one line per node, e.g. "rename node 7 (laptop) to alice-laptop".
+func (p RenamePlan) String() string {
+	var b strings.Builder
+	for _, r := range p.Renames {
+		switch {
+		case r.Err != nil:
+			_, _ = fmt.Fprintf(&b, "skip %s: %v\n", nodeLabel(r.Node), r.Err)
+		case r.Name == r.Node.GivenName:
+			_, _ = fmt.Fprintf(&b, "skip %s: already named %s\n", nodeLabel(r.Node), r.Name)
+		default:
+			_, _ = fmt.Fprintf(&b, "rename %s to %s\n", nodeLabel(r.Node), r.Name)
+		}
+	}
+	return b.String()
+}
+
+// PlanRenames names each node matching q with name, e.g. the Render method of a NameTemplate, and
+// checks the names against each other and the names of all other nodes. Nothing is renamed.
+func (n *NodeResource) PlanRenames(ctx context.Context, q Query, name func(node Node) (string, error)) (RenamePlan, error) {
+	all, err := iterate.Collect(n.All(ctx, NodeListFilter{}))
+	if err != nil {
+		return RenamePlan{}, err
+	}
+
+	now := time.Now()
+	var planned []PlannedRename
+	for _, node := range all {
+		if !q.MatchAt(node, now) {
+			continue
+		}
+		to, err := name(node)
+		if err == nil {
+			err = n.validateName(to)
+		}
+		planned = append(planned, PlannedRename{Node: node, Name: to, Err: err})
+	}
+
+	return RenamePlan{Renames: orderRenames(planned, all)}, nil
+}
+
+// orderRenames sets the errors of renames colliding with each other or with nodes keeping their
+// name, and returns the renames in an order that frees each name before it is taken.
+func orderRenames(planned []PlannedRename, all []Node) []PlannedRename {
+	renaming := func(r PlannedRename) bool { return r.Err == nil && r.Name != r.Node.GivenName }
+
+	// Nodes taking the same name collide with each other.
+	byTarget := map[string][]int{}
+	for i, r := range planned {
+		if renaming(r) {
+			byTarget[r.Name] = append(byTarget[r.Name], i)
+		}
+	}
+	for name, idx := range byTarget {
+		if len(idx) < 2 {
+			continue
+		}
+		for _, i := range idx {
+			var others []Node
+			for _, j := range idx {
+				if j != i {
+					others = append(others, planned[j].Node)
+				}
+			}
+			planned[i].Err = &NameCollisionError{Name: name, Nodes: others}
+		}
+	}
+
+	// A node taking the name of a node being renamed waits for it; names of other nodes are taken.
+	freedBy := map[string]int{}
+	for i, r := range planned {
+		if renaming(r) {
+			freedBy[r.Node.GivenName] = i
+		}
+	}
+	holders := map[string]Node{}
+	for _, node := range all {
+		holders[node.GivenName] = node
+	}
+	waitsFor := make([]int, len(planned))
+	for i, r := range planned {
+		waitsFor[i] = -1
+		if !renaming(r) {
+			continue
+		}
+		holder, taken := holders[r.Name]
+		if !taken || holder.ID == r.Node.ID {
+			continue
+		}
+		if j, ok := freedBy[r.Name]; ok {
+			waitsFor[i] = j
+			continue
+		}
+		planned[i].Err = &NameCollisionError{Name: r.Name, Nodes: []Node{holder}}
+	}
+
+	// Renames waiting for a name that is never freed, e.g. in a cycle, collide with its holder.
+	ordered := make([]PlannedRename, 0, len(planned))
+	done := make([]bool, len(planned))
+	for progress := true; progress; {
+		progress = false
+		for i, r := range planned {
+			if done[i] || !renaming(r) || waitsFor[i] >= 0 && !done[waitsFor[i]] {
+				continue
+			}
+			ordered = append(ordered, r)
+			done[i], progress = true, true
+		}
+	}
+	for i, r := range planned {
+		if done[i] {
+			continue
+		}
+		if renaming(r) {
+			r.Err = &NameCollisionError{Name: r.Name, Nodes: []Node{holders[r.Name]}}
+		}
+		ordered = append(ordered, r)
+	}
+	return ordered
+}
+
+// ApplyRenames renames the nodes of plan one at a time, in plan order, with Bulk. Nodes that cannot
+// be renamed are skipped with the reason; check plan.Conflicts first to fix them. opt.Concurrency
+// is ignored, as parallel renames could take a name before it is freed.
+func (n *NodeResource) ApplyRenames(ctx context.Context, plan RenamePlan, opt BulkOptions) ([]BulkResult, error) {
+	byID := make(map[string]PlannedRename, len(plan.Renames))
+	selected := make([]Node, len(plan.Renames))
+	for i, r := range plan.Renames {
+		byID[r.Node.ID] = r
+		selected[i] = r.Node
+	}
+
+	op := RenameOperation(func(node Node) string { return byID[node.ID].Name })
+	check := op.Check
+	op.Check = func(node Node) string {
+		if err := byID[node.ID].Err; err != nil {
+			return err.Error()
+		}
+		return check(node)
+	}
+
+	opt.Concurrency = 1
+	return n.Bulk(ctx, iterate.FromSlice(selected), op, opt)
+}
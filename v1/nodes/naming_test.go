@@ -0,0 +1,187 @@
+package nodes
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateName(t *testing.T) {
+	valid := []string{"web-1", "db", "a", "a1b2", strings.Repeat("a", 63)}
+	for _, name := range valid {
+		require.NoError(t, ValidateName(name), name)
+	}
+
+	invalid := []string{"", strings.Repeat("a", 64), "Web-1", "web_1", "web.example", "web/1", "-web", "web-", "wéb"}
+	for _, name := range invalid {
+		require.ErrorIs(t, ValidateName(name), ErrInvalidName, name)
+	}
+}
+
+func TestNodeResource_validateName(t *testing.T) {
+	for _, tt := range []struct {
+		version versions.ServerVersion
+		wantErr bool
+	}{
+		{version: versions.ServerVersion{Minor: 28}, wantErr: true},
+		{version: versions.ServerVersion{Minor: 27}},
+		{version: versions.ServerVersion{}},
+	} {
+		m := new(requests.MockRequest)
+		m.On("ServerVersion").Return(tt.version)
+		n := &NodeResource{r: m}
+
+		require.ErrorIs(t, n.validateName("-web"), ErrInvalidName, tt.version.String())
+		require.NoError(t, n.validateName("db"), tt.version.String())
+		if tt.wantErr {
+			require.ErrorIs(t, n.validateName("a"), ErrInvalidName, tt.version.String())
+		} else {
+			require.NoError(t, n.validateName("a"), tt.version.String())
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "web-1", want: "web-1"},
+		{in: "Alice's MacBook Pro", want: "alice-s-macbook-pro"},
+		{in: "--db__primary.example.com--", want: "db-primary-example-com"},
+		{in: strings.Repeat("ab", 31) + "-cd", want: strings.Repeat("ab", 31)},
+		{in: "X", want: "x"},
+		{in: "__", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := NormalizeName(tt.in)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidName)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNameTemplate(t *testing.T) {
+	node := Node{ID: "7", Name: "MacBook-Pro.local", User: users.User{Name: "Alice Smith", Email: "alice@example.com"}}
+
+	tmpl, err := ParseNameTemplate("{{.User.Name}}-{{.Hostname}}")
+	require.NoError(t, err)
+	name, err := tmpl.Render(node)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-smith-macbook-pro-local", name)
+
+	tmpl, err = ParseNameTemplate(`{{before .User.Email "@"}}-{{trimSuffix .Hostname ".local"}}-{{.ID}}`)
+	require.NoError(t, err)
+	name, err = tmpl.Render(node)
+	require.NoError(t, err)
+	assert.Equal(t, "alice-macbook-pro-7", name)
+
+	_, err = ParseNameTemplate("{{.User.Name")
+	require.Error(t, err)
+
+	tmpl, err = ParseNameTemplate("{{.Owner}}")
+	require.NoError(t, err)
+	_, err = tmpl.Render(node)
+	require.Error(t, err)
+}
+
+func TestNodeResource_Rename_Invalid(t *testing.T) {
+	m := new(requests.MockRequest)
+	_, err := (&NodeResource{r: m}).Rename(t.Context(), "1", "Web Server")
+	require.ErrorIs(t, err, ErrInvalidName)
+	m.AssertNotCalled(t, "BuildURL")
+}
+
+// renameFleet has renames that free names for each other, collide with kept names, with each
+// other, with nodes outside the selection and in a cycle.
+var renameFleet = []Node{
+	{ID: "1", GivenName: "laptop", Name: "laptop", User: users.User{Name: "alice"}},
+	{ID: "2", GivenName: "alice-laptop", Name: "old", User: users.User{Name: "alice"}},
+	{ID: "3", GivenName: "alice-phone", Name: "phone", User: users.User{Name: "alice"}},
+	{ID: "4", GivenName: "p1", Name: "phone", User: users.User{Name: "alice"}},
+	{ID: "5", GivenName: "x", Name: "srv", User: users.User{Name: "bob"}},
+	{ID: "6", GivenName: "y", Name: "srv", User: users.User{Name: "bob"}},
+	{ID: "7", GivenName: "bob-web", Name: "web", User: users.User{Name: "carol"}},
+	{ID: "8", GivenName: "w", Name: "web", User: users.User{Name: "bob"}},
+	{ID: "9", GivenName: "dave-a", Name: "b", User: users.User{Name: "dave"}},
+	{ID: "10", GivenName: "dave-b", Name: "a", User: users.User{Name: "dave"}},
+}
+
+func planFleet(t *testing.T, m *requests.MockRequest) RenamePlan {
+	t.Helper()
+	testutil.MockEndpoint(m, http.MethodGet, []any{"node"}, nil, func(v any) {
+		*v.(*NodesResponse) = NodesResponse{Nodes: renameFleet} //nolint:errcheck // reason: type assertion in test
+	})
+
+	tmpl, err := ParseNameTemplate("{{.User.Name}}-{{.Hostname}}")
+	require.NoError(t, err)
+	plan, err := (&NodeResource{r: m}).PlanRenames(t.Context(), Not(User("carol")), tmpl.Render)
+	require.NoError(t, err)
+	return plan
+}
+
+func TestNodeResource_PlanRenames(t *testing.T) {
+	m := new(requests.MockRequest)
+	m.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+	plan := planFleet(t, m)
+
+	var order []string
+	for _, r := range plan.Renames {
+		order = append(order, r.Node.ID)
+	}
+	assert.Equal(t, []string{"2", "1", "3", "4", "5", "6", "8", "9", "10"}, order, "node 2 frees its name for node 1")
+
+	var conflicts []string
+	for _, r := range plan.Conflicts() {
+		require.ErrorIs(t, r.Err, ErrNameCollision, r.Node.ID)
+		conflicts = append(conflicts, r.Node.ID)
+	}
+	assert.Equal(t, []string{"4", "5", "6", "8", "9", "10"}, conflicts)
+
+	assert.Equal(t, `rename node 2 (alice-laptop) to alice-old
+rename node 1 (laptop) to alice-laptop
+skip node 3 (alice-phone): already named alice-phone
+skip node 4 (p1): node name collision "alice-phone": also node 3 (alice-phone)
+skip node 5 (x): node name collision "bob-srv": also node 6 (y)
+skip node 6 (y): node name collision "bob-srv": also node 5 (x)
+skip node 8 (w): node name collision "bob-web": also node 7 (bob-web)
+skip node 9 (dave-a): node name collision "dave-b": also node 10 (dave-b)
+skip node 10 (dave-b): node name collision "dave-a": also node 9 (dave-a)
+`, plan.String())
+}
+
+func TestNodeResource_ApplyRenames(t *testing.T) {
+	m := new(requests.MockRequest)
+	m.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+	plan := planFleet(t, m)
+
+	var renamed []string
+	for _, r := range plan.Renames {
+		record := func(requests.RequestOptions) { renamed = append(renamed, r.Node.ID) }
+		testutil.MockEndpoint(m, http.MethodPost, []any{"node", r.Node.ID, "rename", r.Name}, record, func(v any) {
+			*v.(*NodeResponse) = NodeResponse{Node: Node{ID: r.Node.ID, GivenName: r.Name}} //nolint:errcheck // reason: type assertion in test
+		})
+	}
+
+	results, err := (&NodeResource{r: m}).ApplyRenames(t.Context(), plan, BulkOptions{Concurrency: 8})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "1"}, renamed, "renamed one at a time in plan order")
+	assert.Equal(t, []BulkStatus{BulkSucceeded, BulkSucceeded, BulkSkipped, BulkSkipped, BulkSkipped,
+		BulkSkipped, BulkSkipped, BulkSkipped, BulkSkipped}, statuses(results))
+	assert.Equal(t, "alice-laptop", results[1].Node.GivenName)
+	assert.Contains(t, results[3].Reason, "node name collision")
+}
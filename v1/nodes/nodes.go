@@ -3,26 +3,48 @@ package nodes
 
 import (
 	"context"
+	"encoding/json"
+	"iter"
 	"net/http"
 	"slices"
 	"time"
 
+	"github.com/hibare/headscale-client-go/compat"
+	"github.com/hibare/headscale-client-go/iterate"
 	"github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/v1/preauthkeys"
 	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
 )
 
 // NodeResourceInterface is an interface for managing nodes in Headscale.
 type NodeResourceInterface interface {
 	List(ctx context.Context, filter NodeListFilter) (NodesResponse, error)
+	All(ctx context.Context, filter NodeListFilter) iter.Seq2[Node, error]
+	Query(ctx context.Context, q Query) iter.Seq2[Node, error]
 	Get(ctx context.Context, id string) (NodeResponse, error)
 	Register(ctx context.Context, user, key string) (NodeResponse, error)
+	RegisterFrom(ctx context.Context, text string, opt RegisterOptions) (Registration, error)
 	Delete(ctx context.Context, id string) error
 	Expire(ctx context.Context, id string) error
+	SetExpiry(ctx context.Context, id string, expiry time.Time) (NodeResponse, error)
+	ExtendExpiry(ctx context.Context, id string, d time.Duration) (NodeResponse, error)
+	Expiring(ctx context.Context, q Query, window time.Duration) ([]Node, error)
+	ExpiryReport(ctx context.Context, q Query, window time.Duration) (ExpiryReport, error)
 	Rename(ctx context.Context, id, name string) (NodeResponse, error)
+	PlanRenames(ctx context.Context, q Query, name func(node Node) (string, error)) (RenamePlan, error)
+	ApplyRenames(ctx context.Context, plan RenamePlan, opt BulkOptions) ([]BulkResult, error)
 	ApproveRoutes(ctx context.Context, id string, routes []string) (NodeResponse, error)
-	AddTags(ctx context.Context, id string, tags []string) (NodeResponse, error)
+	SetTags(ctx context.Context, id string, tags []string) (NodeResponse, error)
+	AddTags(ctx context.Context, id string, tags []string) (TagChange, error)
+	RemoveTags(ctx context.Context, id string, tags []string) (TagChange, error)
 	BackfillIPs(ctx context.Context, confirm bool) (BackfillIPsResponse, error)
+	Reassign(ctx context.Context, id, user string) (NodeResponse, error)
+	ReassignAll(ctx context.Context, from, to string) ([]ReassignResult, error)
+	ExitNodes(ctx context.Context, q Query) ([]ExitNodeStatus, error)
+	ApproveExitNode(ctx context.Context, id string) (NodeResponse, error)
+	RevokeExitNode(ctx context.Context, id string) (NodeResponse, error)
+	Bulk(ctx context.Context, nodes iter.Seq2[Node, error], op Operation, opt BulkOptions) ([]BulkResult, error)
 }
 
 // NodeResource is a struct that provides methods to interact with the nodes API of Headscale.
@@ -37,12 +59,12 @@ func NewNodeResource(r requests.RequestInterface) *NodeResource {
 
 // Node represents a node in Headscale.
 type Node struct {
-	ID              string                  `json:"id"`
+	ID              string                  `json:"id" schema:"required"`
 	MachineKey      string                  `json:"machineKey"`
 	NodeKey         string                  `json:"nodeKey"`
 	DiscoKey        string                  `json:"discoKey"`
 	IPAddresses     []string                `json:"ipAddresses"`
-	Name            string                  `json:"name"`
+	Name            string                  `json:"name" schema:"required"`
 	User            users.User              `json:"user"`
 	LastSeen        time.Time               `json:"lastSeen"`
 	Expiry          time.Time               `json:"expiry"`
@@ -55,6 +77,54 @@ type Node struct {
 	ApprovedRoutes  []string                `json:"approvedRoutes"`
 	AvailableRoutes []string                `json:"availableRoutes"`
 	SubnetRoutes    []string                `json:"subnetRoutes"`
+
+	// Extra holds response members without a field, e.g. those added by newer Headscale releases.
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// forcedTags and validTags are the tags set through the API and the tags advertised by the node,
+	// which releases before v0.28.0 report apart and Tags holds together.
+	forcedTags []string
+	validTags  []string
+}
+
+// UnmarshalJSON decodes a Node, keeping unknown members in Extra.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	type node Node
+	extra, err := compat.UnmarshalExtra(data, (*node)(n))
+	if err != nil {
+		return err
+	}
+
+	if n.forcedTags, err = takeTags(extra, compat.ForcedTagsMember); err != nil {
+		return err
+	}
+	if n.validTags, err = takeTags(extra, compat.ValidTagsMember); err != nil {
+		return err
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	n.Extra = extra
+	return nil
+}
+
+// takeTags decodes and removes the tag list stored under member in extra.
+func takeTags(extra map[string]json.RawMessage, member string) ([]string, error) {
+	raw, ok := extra[member]
+	if !ok {
+		return nil, nil
+	}
+	delete(extra, member)
+
+	var tags []string
+	err := json.Unmarshal(raw, &tags)
+	return tags, err
+}
+
+// MarshalJSON encodes a Node, including the members in Extra.
+func (n Node) MarshalJSON() ([]byte, error) {
+	type node Node
+	return compat.MarshalExtra(node(n), n.Extra)
 }
 
 // IsExitNode returns true if the node is an exit node.
@@ -94,8 +164,26 @@ func (n *NodeResource) List(ctx context.Context, filter NodeListFilter) (NodesRe
 		return nodes, err
 	}
 
-	err = n.r.Do(ctx, req, &nodes)
-	return nodes, err
+	if err := n.r.Do(ctx, req, &nodes); err != nil {
+		return nodes, err
+	}
+
+	if n.usesRoutesAPI() {
+		routes, err := n.listRoutes(ctx, "")
+		if err != nil {
+			return nodes, err
+		}
+		setRoutes(nodes.Nodes, routes)
+	}
+	return nodes, nil
+}
+
+// All returns an iterator over the nodes matching filter. The list is fetched when iteration starts.
+func (n *NodeResource) All(ctx context.Context, filter NodeListFilter) iter.Seq2[Node, error] {
+	return iterate.Fetch(func() ([]Node, error) {
+		resp, err := n.List(ctx, filter)
+		return resp.Nodes, err
+	})
 }
 
 // Get retrieves a node by its ID from the Headscale.
@@ -108,8 +196,20 @@ func (n *NodeResource) Get(ctx context.Context, id string) (NodeResponse, error)
 		return node, err
 	}
 
-	err = n.r.Do(ctx, req, &node)
-	return node, err
+	if err := n.r.Do(ctx, req, &node); err != nil {
+		return node, err
+	}
+
+	if n.usesRoutesAPI() {
+		routes, err := n.listRoutes(ctx, id)
+		if err != nil {
+			return node, err
+		}
+		nodes := []Node{node.Node}
+		setRoutes(nodes, routes)
+		node.Node = nodes[0]
+	}
+	return node, nil
 }
 
 // Register registers a new node with the Headscale.
@@ -148,9 +248,19 @@ type ApproveRoutesRequest struct {
 }
 
 // ApproveRoutes approves routes for a node in the Headscale.
+//
+// On releases before v0.26.0 the routes are enabled through the routes API, which only approves
+// routes the node advertises, and the others are disabled.
 func (n *NodeResource) ApproveRoutes(ctx context.Context, id string, routes []string) (NodeResponse, error) {
 	var node NodeResponse
 
+	if n.usesRoutesAPI() {
+		return n.approveLegacyRoutes(ctx, id, routes)
+	}
+	if err := requests.ServerVersion(n.r).Require(versions.CapabilityApproveRoutes); err != nil {
+		return node, err
+	}
+
 	url := n.r.BuildURL("node", id, "approve_routes")
 	req, err := n.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{
 		Body: ApproveRoutesRequest{Routes: routes},
@@ -174,33 +284,18 @@ func (n *NodeResource) Expire(ctx context.Context, id string) error {
 	return n.r.Do(ctx, req, nil)
 }
 
-// Rename renames a node in the Headscale.
+// Rename renames a node in the Headscale. Names that are not valid DNS labels, and names shorter
+// than 2 characters on Headscale v0.28.0 and newer, are rejected with an error wrapping
+// ErrInvalidName before anything is sent. Earlier releases of this package sent any name.
 func (n *NodeResource) Rename(ctx context.Context, id, name string) (NodeResponse, error) {
 	var node NodeResponse
 
-	url := n.r.BuildURL("node", id, "rename", name)
-	req, err := n.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{})
-	if err != nil {
+	if err := n.validateName(name); err != nil {
 		return node, err
 	}
 
-	err = n.r.Do(ctx, req, &node)
-	return node, err
-}
-
-// AddTagsRequest represents a request to add tags to a node.
-type AddTagsRequest struct {
-	Tags []string `json:"tags"`
-}
-
-// AddTags adds tags to a node in the Headscale.
-func (n *NodeResource) AddTags(ctx context.Context, id string, tags []string) (NodeResponse, error) {
-	var node NodeResponse
-
-	url := n.r.BuildURL("node", id, "tags")
-	req, err := n.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{
-		Body: AddTagsRequest{Tags: tags},
-	})
+	url := n.r.BuildURL("node", id, "rename", name)
+	req, err := n.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{})
 	if err != nil {
 		return node, err
 	}
@@ -2,6 +2,8 @@ package nodes
 
 import (
 	"context"
+	"iter"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -17,6 +19,18 @@ func (m *MockNodeResource) List(ctx context.Context, filter NodeListFilter) (Nod
 	return args.Get(0).(NodesResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// All returns a mock iterator over nodes matching filter.
+func (m *MockNodeResource) All(ctx context.Context, filter NodeListFilter) iter.Seq2[Node, error] {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(iter.Seq2[Node, error]) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// Query returns a mock iterator over nodes matching q.
+func (m *MockNodeResource) Query(ctx context.Context, q Query) iter.Seq2[Node, error] {
+	args := m.Called(ctx, q)
+	return args.Get(0).(iter.Seq2[Node, error]) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // Get returns a mock node from the Headscale.
 func (m *MockNodeResource) Get(ctx context.Context, id string) (NodeResponse, error) {
 	args := m.Called(ctx, id)
@@ -41,26 +55,111 @@ func (m *MockNodeResource) Expire(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
+// SetExpiry sets the expiry of a mock node.
+func (m *MockNodeResource) SetExpiry(ctx context.Context, id string, expiry time.Time) (NodeResponse, error) {
+	args := m.Called(ctx, id, expiry)
+	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// ExtendExpiry postpones the expiry of a mock node.
+func (m *MockNodeResource) ExtendExpiry(ctx context.Context, id string, d time.Duration) (NodeResponse, error) {
+	args := m.Called(ctx, id, d)
+	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// Expiring returns the mock nodes expiring within window.
+func (m *MockNodeResource) Expiring(ctx context.Context, q Query, window time.Duration) ([]Node, error) {
+	args := m.Called(ctx, q, window)
+	return args.Get(0).([]Node), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// ExpiryReport returns the expiry report of mock nodes.
+func (m *MockNodeResource) ExpiryReport(ctx context.Context, q Query, window time.Duration) (ExpiryReport, error) {
+	args := m.Called(ctx, q, window)
+	return args.Get(0).(ExpiryReport), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // Rename renames a mock node from the Headscale.
 func (m *MockNodeResource) Rename(ctx context.Context, id, name string) (NodeResponse, error) {
 	args := m.Called(ctx, id, name)
 	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// PlanRenames plans renaming mock nodes.
+func (m *MockNodeResource) PlanRenames(ctx context.Context, q Query, name func(node Node) (string, error)) (RenamePlan, error) {
+	args := m.Called(ctx, q, name)
+	return args.Get(0).(RenamePlan), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// ApplyRenames renames mock nodes according to plan.
+func (m *MockNodeResource) ApplyRenames(ctx context.Context, plan RenamePlan, opt BulkOptions) ([]BulkResult, error) {
+	args := m.Called(ctx, plan, opt)
+	return args.Get(0).([]BulkResult), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // ApproveRoutes approves routes for a mock node in the Headscale.
 func (m *MockNodeResource) ApproveRoutes(ctx context.Context, id string, routes []string) (NodeResponse, error) {
 	args := m.Called(ctx, id, routes)
 	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
-// AddTags adds tags to a mock node from the Headscale.
-func (m *MockNodeResource) AddTags(ctx context.Context, id string, tags []string) (NodeResponse, error) {
+// SetTags replaces the tags of a mock node in the Headscale.
+func (m *MockNodeResource) SetTags(ctx context.Context, id string, tags []string) (NodeResponse, error) {
 	args := m.Called(ctx, id, tags)
 	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// AddTags adds tags to a mock node in the Headscale.
+func (m *MockNodeResource) AddTags(ctx context.Context, id string, tags []string) (TagChange, error) {
+	args := m.Called(ctx, id, tags)
+	return args.Get(0).(TagChange), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// RemoveTags removes tags from a mock node in the Headscale.
+func (m *MockNodeResource) RemoveTags(ctx context.Context, id string, tags []string) (TagChange, error) {
+	args := m.Called(ctx, id, tags)
+	return args.Get(0).(TagChange), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // BackfillIPs backfills the IP address for a mock node from the Headscale.
 func (m *MockNodeResource) BackfillIPs(ctx context.Context, confirm bool) (BackfillIPsResponse, error) {
 	args := m.Called(ctx, confirm)
 	return args.Get(0).(BackfillIPsResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
+
+// Reassign moves a mock node to another user.
+func (m *MockNodeResource) Reassign(ctx context.Context, id, user string) (NodeResponse, error) {
+	args := m.Called(ctx, id, user)
+	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// ReassignAll moves all mock nodes of a user to another user.
+func (m *MockNodeResource) ReassignAll(ctx context.Context, from, to string) ([]ReassignResult, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]ReassignResult), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+func (m *MockNodeResource) Bulk(ctx context.Context, nodes iter.Seq2[Node, error], op Operation, opt BulkOptions) ([]BulkResult, error) {
+	args := m.Called(ctx, nodes, op, opt)
+	return args.Get(0).([]BulkResult), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+func (m *MockNodeResource) ExitNodes(ctx context.Context, q Query) ([]ExitNodeStatus, error) {
+	args := m.Called(ctx, q)
+	return args.Get(0).([]ExitNodeStatus), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+func (m *MockNodeResource) ApproveExitNode(ctx context.Context, id string) (NodeResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+func (m *MockNodeResource) RevokeExitNode(ctx context.Context, id string) (NodeResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(NodeResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+func (m *MockNodeResource) RegisterFrom(ctx context.Context, text string, opt RegisterOptions) (Registration, error) {
+	args := m.Called(ctx, text, opt)
+	return args.Get(0).(Registration), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
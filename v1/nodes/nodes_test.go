@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/hibare/headscale-client-go/iterate"
 	"github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/v1/testutil"
 	"github.com/stretchr/testify/assert"
@@ -23,6 +24,20 @@ func TestNodeResource_List(t *testing.T) {
 	})
 }
 
+func TestNodeResource_All(t *testing.T) {
+	fixture := testutil.TestFixture[NodesResponse]{
+		Endpoint:    "node",
+		Method:      http.MethodGet,
+		SuccessResp: NodesResponse{Nodes: []Node{{ID: "1", Name: "testnode"}, {ID: "2", Name: "othernode"}}},
+	}
+
+	testutil.RunResourceTest(t, fixture, func(ctx context.Context, mockReq *requests.MockRequest) (NodesResponse, error) {
+		n := &NodeResource{r: mockReq}
+		items, err := iterate.Collect(n.All(ctx, NodeListFilter{User: "testuser"}))
+		return NodesResponse{Nodes: items}, err
+	})
+}
+
 func TestIsExitNode(t *testing.T) {
 	tests := []struct {
 		Name             string
@@ -153,9 +168,9 @@ func TestNodeResource_Rename(t *testing.T) {
 	})
 }
 
-func TestNodeResource_AddTags(t *testing.T) {
+func TestNodeResource_setTags(t *testing.T) {
 	id := "1"
-	tags := []string{"tag1", "tag2"}
+	tags := []string{"tag:one", "tag:two"}
 	fixture := testutil.TestFixture[NodeResponse]{
 		Endpoint:    []any{"node", id, "tags"},
 		Method:      http.MethodPost,
@@ -164,7 +179,7 @@ func TestNodeResource_AddTags(t *testing.T) {
 
 	testutil.RunResourceTest(t, fixture, func(ctx context.Context, mockReq *requests.MockRequest) (NodeResponse, error) {
 		n := &NodeResource{r: mockReq}
-		return n.AddTags(ctx, id, tags)
+		return n.setTags(ctx, id, tags)
 	})
 }
 
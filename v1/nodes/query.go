@@ -0,0 +1,522 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"net/netip"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+)
+
+// ErrInvalidQuery is wrapped by QueryError.
+var ErrInvalidQuery = errors.New("invalid node query")
+
+// QueryError describes a syntax error in a query expression.
+type QueryError struct {
+	// Query is the expression passed to ParseQuery.
+	Query string
+
+	// Offset is the byte offset of the error in Query.
+	Offset int
+
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at offset %d: %s", ErrInvalidQuery, e.Offset, e.Msg)
+}
+
+func (e *QueryError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// Query selects nodes by their properties. Build one from the functions of this package, e.g.
+// And(Online(), Tag("web")), or parse an expression such as "online && tag:web" with ParseQuery.
+// The zero Query matches every node.
+type Query struct {
+	m matcher
+}
+
+// matcher is a node predicate of a Query.
+type matcher interface {
+	match(n *Node, now time.Time) bool
+	String() string
+}
+
+// Match reports whether n matches q at the current time.
+func (q Query) Match(n Node) bool {
+	return q.MatchAt(n, time.Now())
+}
+
+// MatchAt reports whether n matches q at the time now.
+func (q Query) MatchAt(n Node, now time.Time) bool {
+	return q.m == nil || q.m.match(&n, now)
+}
+
+// String returns q in the expression syntax of ParseQuery.
+func (q Query) String() string {
+	if q.m == nil {
+		return ""
+	}
+	return q.m.String()
+}
+
+// ListFilter returns the part of q that Headscale evaluates itself. Headscale filters by user only,
+// so the user of a top-level user:<name> term is pushed down; everything else is matched client-side.
+func (q Query) ListFilter() NodeListFilter {
+	terms := []matcher{q.m}
+	if all, ok := q.m.(allOf); ok {
+		terms = all
+	}
+
+	for _, m := range terms {
+		if t, ok := m.(term); ok && t.name == termUser {
+			return NodeListFilter{User: t.value}
+		}
+	}
+	return NodeListFilter{}
+}
+
+// Query returns an iterator over the nodes matching q. The list is fetched when iteration starts.
+func (n *NodeResource) Query(ctx context.Context, q Query) iter.Seq2[Node, error] {
+	return iterate.Filter(n.All(ctx, q.ListFilter()), q.Match)
+}
+
+// Names of the terms of the expression syntax.
+const (
+	termOnline   = "online"
+	termExpired  = "expired"
+	termExit     = "exit"
+	termTag      = "tag"
+	termUser     = "user"
+	termName     = "name"
+	termIP       = "ip"
+	termRoute    = "route"
+	termApproved = "approved"
+	termMethod   = "method"
+	termKey      = "key"
+	termNone     = "none"
+)
+
+// tagPrefix is the prefix of ACL tags.
+const tagPrefix = "tag:"
+
+// term is a single condition, written name or name:value.
+type term struct {
+	name  string
+	value string
+	fn    func(n *Node, now time.Time) bool
+}
+
+func (t term) match(n *Node, now time.Time) bool { return t.fn(n, now) }
+
+func (t term) String() string {
+	if t.value == "" {
+		return t.name
+	}
+	return t.name + ":" + t.value
+}
+
+// Online matches nodes connected to Headscale.
+func Online() Query {
+	return Query{m: term{name: termOnline, fn: func(n *Node, _ time.Time) bool { return n.Online }}}
+}
+
+// Expired matches nodes whose key has expired.
+func Expired() Query {
+	return Query{m: term{name: termExpired, fn: func(n *Node, now time.Time) bool {
+		return !n.Expiry.IsZero() && !n.Expiry.After(now)
+	}}}
+}
+
+// ExitNode matches nodes with an approved exit route.
+func ExitNode() Query {
+	return Query{m: term{name: termExit, fn: func(n *Node, _ time.Time) bool { return n.IsExitNode() }}}
+}
+
+// Tag matches nodes carrying tag. The "tag:" prefix is optional.
+func Tag(tag string) Query {
+	tag = strings.TrimPrefix(tag, tagPrefix)
+	return Query{m: term{name: termTag, value: tag, fn: func(n *Node, _ time.Time) bool {
+		return slices.Contains(n.Tags, tagPrefix+tag)
+	}}}
+}
+
+// User matches nodes owned by the user with the given name. It is evaluated by Headscale when
+// it is the whole query or part of a top-level And.
+func User(name string) Query {
+	return Query{m: term{name: termUser, value: name, fn: func(n *Node, _ time.Time) bool { return n.User.Name == name }}}
+}
+
+// GivenName matches nodes whose given name matches the glob pattern, as understood by path.Match.
+// A malformed pattern matches no node.
+func GivenName(pattern string) Query {
+	return Query{m: term{name: termName, value: pattern, fn: func(n *Node, _ time.Time) bool {
+		ok, err := path.Match(pattern, n.GivenName)
+		return ok && err == nil
+	}}}
+}
+
+// InPrefix matches nodes with an IP address within p.
+func InPrefix(p netip.Prefix) Query {
+	return Query{m: term{name: termIP, value: formatPrefix(p), fn: func(n *Node, _ time.Time) bool {
+		return slices.ContainsFunc(n.IPAddresses, func(s string) bool {
+			addr, err := netip.ParseAddr(s)
+			return err == nil && p.Contains(addr)
+		})
+	}}}
+}
+
+// AdvertisesRoute matches nodes advertising a route that covers p.
+func AdvertisesRoute(p netip.Prefix) Query {
+	return Query{m: term{name: termRoute, value: formatPrefix(p), fn: func(n *Node, _ time.Time) bool {
+		return coversPrefix(n.AvailableRoutes, p)
+	}}}
+}
+
+// ApprovedRoute matches nodes with an approved route that covers p.
+func ApprovedRoute(p netip.Prefix) Query {
+	return Query{m: term{name: termApproved, value: formatPrefix(p), fn: func(n *Node, _ time.Time) bool {
+		return coversPrefix(n.ApprovedRoutes, p)
+	}}}
+}
+
+// RegisteredBy matches nodes registered with method, e.g. "authkey", "cli" or "oidc". Case and the
+// REGISTER_METHOD_ prefix reported by Headscale are ignored.
+func RegisteredBy(method string) Query {
+	method = normalizeMethod(method)
+	return Query{m: term{name: termMethod, value: method, fn: func(n *Node, _ time.Time) bool {
+		return normalizeMethod(n.RegisterMethod) == method
+	}}}
+}
+
+// KeyProperty is a property of the pre-auth key a node registered with.
+type KeyProperty string
+
+const (
+	// KeyAny matches nodes registered with any pre-auth key.
+	KeyAny KeyProperty = ""
+
+	// KeyReusable matches nodes registered with a reusable key.
+	KeyReusable KeyProperty = "reusable"
+
+	// KeyEphemeral matches nodes registered with an ephemeral key.
+	KeyEphemeral KeyProperty = "ephemeral"
+
+	// KeyUsed matches nodes registered with a key marked as used.
+	KeyUsed KeyProperty = "used"
+
+	// KeyExpired matches nodes registered with a key that has expired since.
+	KeyExpired KeyProperty = "expired"
+)
+
+var keyProperties = []KeyProperty{KeyAny, KeyReusable, KeyEphemeral, KeyUsed, KeyExpired}
+
+// RegisteredWithKey matches nodes registered with a pre-auth key that has prop.
+func RegisteredWithKey(prop KeyProperty) Query {
+	return Query{m: term{name: termKey, value: string(prop), fn: func(n *Node, now time.Time) bool {
+		k := n.PreAuthKey
+		if k == nil {
+			return false
+		}
+
+		switch prop {
+		case KeyAny:
+			return true
+		case KeyReusable:
+			return k.Reusable
+		case KeyEphemeral:
+			return k.Ephemeral
+		case KeyUsed:
+			return k.Used
+		case KeyExpired:
+			return !k.Expiration.IsZero() && !k.Expiration.After(now)
+		default:
+			return false
+		}
+	}}}
+}
+
+// TimeField is a node timestamp compared by CompareTime.
+type TimeField string
+
+const (
+	// LastSeenField compares the time elapsed since the node was last seen.
+	LastSeenField TimeField = "lastSeen"
+
+	// CreatedAtField compares the time elapsed since the node was created.
+	CreatedAtField TimeField = "createdAt"
+
+	// ExpiryField compares the time remaining until the node expires.
+	ExpiryField TimeField = "expiry"
+)
+
+var timeFields = []TimeField{LastSeenField, CreatedAtField, ExpiryField}
+
+// CompareOp is a comparison operator of CompareTime.
+type CompareOp string
+
+// Comparison operators.
+const (
+	Less           CompareOp = "<"
+	LessOrEqual    CompareOp = "<="
+	Greater        CompareOp = ">"
+	GreaterOrEqual CompareOp = ">="
+)
+
+// forever stands for the duration since a node that was never seen, or until a node that never expires.
+const forever = time.Duration(math.MaxInt64)
+
+// timeTerm is a condition written field op duration, e.g. lastSeen < 7d.
+type timeTerm struct {
+	field TimeField
+	op    CompareOp
+	d     time.Duration
+}
+
+// CompareTime matches nodes whose field compares to d with op, e.g. CompareTime(LastSeenField, Less, time.Hour)
+// matches nodes seen within the last hour. Unset timestamps compare as infinitely far away.
+func CompareTime(field TimeField, op CompareOp, d time.Duration) Query {
+	return Query{m: timeTerm{field: field, op: op, d: d}}
+}
+
+func (t timeTerm) match(n *Node, now time.Time) bool {
+	var got time.Duration
+	switch t.field {
+	case LastSeenField:
+		got = since(n.LastSeen, now)
+	case CreatedAtField:
+		got = since(n.CreatedAt, now)
+	case ExpiryField:
+		got = forever
+		if !n.Expiry.IsZero() {
+			got = n.Expiry.Sub(now)
+		}
+	default:
+		return false
+	}
+
+	switch t.op {
+	case Less:
+		return got < t.d
+	case LessOrEqual:
+		return got <= t.d
+	case Greater:
+		return got > t.d
+	case GreaterOrEqual:
+		return got >= t.d
+	default:
+		return false
+	}
+}
+
+func (t timeTerm) String() string {
+	return fmt.Sprintf("%s %s %s", t.field, t.op, formatDuration(t.d))
+}
+
+func since(t, now time.Time) time.Duration {
+	if t.IsZero() {
+		return forever
+	}
+	return now.Sub(t)
+}
+
+// allOf matches nodes matching all of its operands.
+type allOf []matcher
+
+func (a allOf) match(n *Node, now time.Time) bool {
+	for _, m := range a {
+		if !m.match(n, now) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a allOf) String() string { return joinOperands(a, " && ") }
+
+// anyOf matches nodes matching any of its operands.
+type anyOf []matcher
+
+func (a anyOf) match(n *Node, now time.Time) bool {
+	for _, m := range a {
+		if m.match(n, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the operands joined with ||, or "none" for no operands, which match no node.
+func (a anyOf) String() string {
+	if len(a) == 0 {
+		return termNone
+	}
+	return joinOperands(a, " || ")
+}
+
+// notOf matches nodes not matching its operand.
+type notOf struct {
+	m matcher
+}
+
+func (o notOf) match(n *Node, now time.Time) bool { return !o.m.match(n, now) }
+
+func (o notOf) String() string {
+	if _, ok := o.m.(term); ok {
+		return "!" + o.m.String()
+	}
+	if a, ok := o.m.(anyOf); ok && len(a) == 0 {
+		return "!" + o.m.String()
+	}
+	return "!(" + o.m.String() + ")"
+}
+
+// joinOperands formats operands separated by sep, parenthesizing nested lists.
+func joinOperands(operands []matcher, sep string) string {
+	parts := make([]string, len(operands))
+	for i, m := range operands {
+		switch m := m.(type) {
+		case allOf:
+			parts[i] = "(" + m.String() + ")"
+		case anyOf:
+			if len(m) == 0 {
+				parts[i] = m.String()
+			} else {
+				parts[i] = "(" + m.String() + ")"
+			}
+		default:
+			parts[i] = m.String()
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// And matches nodes matching all of qs. Zero queries are ignored.
+func And(qs ...Query) Query {
+	var out allOf
+	for _, q := range qs {
+		switch m := q.m.(type) {
+		case nil:
+		case allOf:
+			out = append(out, m...)
+		default:
+			out = append(out, m)
+		}
+	}
+
+	switch len(out) {
+	case 0:
+		return Query{}
+	case 1:
+		return Query{m: out[0]}
+	default:
+		return Query{m: out}
+	}
+}
+
+// Or matches nodes matching any of qs. It matches every node if one of qs is the zero Query.
+func Or(qs ...Query) Query {
+	var out anyOf
+	for _, q := range qs {
+		switch m := q.m.(type) {
+		case nil:
+			return Query{}
+		case anyOf:
+			out = append(out, m...)
+		default:
+			out = append(out, m)
+		}
+	}
+
+	if len(out) == 1 {
+		return Query{m: out[0]}
+	}
+	return Query{m: out}
+}
+
+// Not matches nodes not matching q. Not of the zero Query matches no node and is written "none".
+func Not(q Query) Query {
+	if n, ok := q.m.(notOf); ok {
+		return Query{m: n.m}
+	}
+	if q.m == nil {
+		return Query{m: anyOf{}}
+	}
+	return Query{m: notOf{m: q.m}}
+}
+
+func coversPrefix(routes []string, p netip.Prefix) bool {
+	return slices.ContainsFunc(routes, func(s string) bool {
+		r, err := netip.ParsePrefix(s)
+		return err == nil && r.Bits() <= p.Bits() && r.Contains(p.Addr())
+	})
+}
+
+// parsePrefix parses a CIDR prefix, or a single address as a prefix covering only that address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// formatPrefix formats p, omitting the length of single-address prefixes.
+func formatPrefix(p netip.Prefix) string {
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+	return p.String()
+}
+
+func normalizeMethod(method string) string {
+	method = strings.TrimPrefix(strings.ToUpper(method), "REGISTER_METHOD_")
+	return strings.ToLower(strings.ReplaceAll(method, "_", ""))
+}
+
+// Units accepted by parseDuration in addition to those of time.ParseDuration.
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// parseDuration parses a duration such as "90s", "1h30m", "7d" or "2w". The day and week units
+// take a whole number only.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": week} {
+		if count, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.ParseUint(count, 10, 64)
+			if err != nil || n > uint64(forever/unit) {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func formatDuration(d time.Duration) string {
+	if d > 0 && d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	return d.String()
+}
@@ -0,0 +1,293 @@
+package nodes
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ParseQuery parses a query expression, as accepted by a --filter command line flag. Terms are
+// combined with && (and), || (or) and ! (not), and grouped with parentheses; && binds tighter than ||.
+//
+//	online && tag:web && lastSeen < 7d
+//	(tag:db || tag:cache) && !expired
+//	user:alice && ip:100.64.0.0/24
+//
+// The terms are:
+//
+//	online, expired, exit         node is online, has expired, is an exit node
+//	tag:<tag>                     node carries tag:<tag>
+//	user:<name>                   node is owned by user <name>
+//	name:<glob>                   given name matches <glob>, e.g. name:web-*
+//	ip:<addr|cidr>                node has an IP address within <cidr>
+//	route:<addr|cidr>             node advertises a route covering <cidr>
+//	approved:<addr|cidr>          node has an approved route covering <cidr>
+//	method:<method>               node registered via authkey, cli or oidc
+//	key[:<property>]              node registered with a pre-auth key, optionally reusable, ephemeral, used or expired
+//	none                          no node, the negation of the empty expression
+//	lastSeen|createdAt <op> <d>   time elapsed since the timestamp compared with <, <=, > or >=
+//	expiry <op> <d>               time remaining until expiry compared likewise
+//
+// Durations are written as for time.ParseDuration, or in whole days (7d) or weeks (2w). An empty
+// expression returns the zero Query. Syntax errors are returned as *QueryError.
+func ParseQuery(expr string) (Query, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return Query{}, err
+	}
+
+	p := &parser{expr: expr, toks: toks}
+	if p.peek().kind == tokEOF {
+		return Query{}, nil
+	}
+
+	q, err := p.parseOr()
+	if err != nil {
+		return Query{}, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return Query{}, p.errorf(t, "unexpected %q", t.text)
+	}
+	return q, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokCompare
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// isOperatorRune reports whether r ends a word.
+func isOperatorRune(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("()!&|<>=", r)
+}
+
+func lex(expr string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(expr); {
+		rest := expr[i:]
+		switch {
+		case unicode.IsSpace(rune(expr[i])):
+			i++
+			continue
+		case strings.HasPrefix(rest, "&&"):
+			toks = append(toks, token{kind: tokAnd, text: "&&", pos: i})
+		case strings.HasPrefix(rest, "||"):
+			toks = append(toks, token{kind: tokOr, text: "||", pos: i})
+		case strings.HasPrefix(rest, "<="), strings.HasPrefix(rest, ">="):
+			toks = append(toks, token{kind: tokCompare, text: rest[:2], pos: i})
+		case rest[0] == '<' || rest[0] == '>':
+			toks = append(toks, token{kind: tokCompare, text: rest[:1], pos: i})
+		case rest[0] == '!':
+			toks = append(toks, token{kind: tokNot, text: "!", pos: i})
+		case rest[0] == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+		case rest[0] == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+		case isOperatorRune(rune(rest[0])):
+			return nil, &QueryError{Query: expr, Offset: i, Msg: fmt.Sprintf("unexpected %q", rest[:1])}
+		default:
+			end := strings.IndexFunc(rest, isOperatorRune)
+			if end < 0 {
+				end = len(rest)
+			}
+			toks = append(toks, token{kind: tokWord, text: rest[:end], pos: i})
+		}
+		i += len(toks[len(toks)-1].text)
+	}
+	return append(toks, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+type parser struct {
+	expr string
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) *QueryError {
+	return &QueryError{Query: p.expr, Offset: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (Query, error) {
+	q, err := p.parseAnd()
+	if err != nil {
+		return Query{}, err
+	}
+
+	operands := []Query{q}
+	for p.peek().kind == tokOr {
+		p.next()
+		q, err := p.parseAnd()
+		if err != nil {
+			return Query{}, err
+		}
+		operands = append(operands, q)
+	}
+	return Or(operands...), nil
+}
+
+func (p *parser) parseAnd() (Query, error) {
+	q, err := p.parseUnary()
+	if err != nil {
+		return Query{}, err
+	}
+
+	operands := []Query{q}
+	for p.peek().kind == tokAnd {
+		p.next()
+		q, err := p.parseUnary()
+		if err != nil {
+			return Query{}, err
+		}
+		operands = append(operands, q)
+	}
+	return And(operands...), nil
+}
+
+func (p *parser) parseUnary() (Query, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNot:
+		q, err := p.parseUnary()
+		if err != nil {
+			return Query{}, err
+		}
+		return Not(q), nil
+	case tokLParen:
+		q, err := p.parseOr()
+		if err != nil {
+			return Query{}, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return Query{}, p.errorf(closing, "missing )")
+		}
+		return q, nil
+	case tokWord:
+		if p.peek().kind == tokCompare {
+			return p.parseComparison(t)
+		}
+		return p.parseTerm(t)
+	case tokEOF:
+		return Query{}, p.errorf(t, "unexpected end of query")
+	default:
+		return Query{}, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+// parseComparison parses field op duration, with field already read.
+func (p *parser) parseComparison(field token) (Query, error) {
+	i := slices.IndexFunc(timeFields, func(f TimeField) bool { return strings.EqualFold(string(f), field.text) })
+	if i < 0 {
+		return Query{}, p.errorf(field, "%q cannot be compared, want one of %s", field.text, joinTimeFields())
+	}
+
+	op := p.next()
+	value := p.next()
+	if value.kind != tokWord {
+		return Query{}, p.errorf(value, "missing duration after %s %s", field.text, op.text)
+	}
+
+	d, err := parseDuration(value.text)
+	if err != nil {
+		return Query{}, p.errorf(value, "%v", err)
+	}
+	return CompareTime(timeFields[i], CompareOp(op.text), d), nil
+}
+
+// parseTerm parses a term written name or name:value.
+func (p *parser) parseTerm(t token) (Query, error) {
+	name, value, hasValue := strings.Cut(t.text, ":")
+	if slices.ContainsFunc(timeFields, func(f TimeField) bool { return strings.EqualFold(string(f), name) }) {
+		return Query{}, p.errorf(t, "%s must be compared with <, <=, > or >=", name)
+	}
+	name = strings.ToLower(name)
+
+	switch name {
+	case termOnline, termExpired, termExit, termNone:
+		if hasValue {
+			return Query{}, p.errorf(t, "%s takes no value", name)
+		}
+	case termTag, termUser, termName, termIP, termRoute, termApproved, termMethod, termKey:
+		if hasValue && value == "" {
+			return Query{}, p.errorf(t, "missing value after %s:", name)
+		}
+	}
+
+	switch name {
+	case termOnline:
+		return Online(), nil
+	case termExpired:
+		return Expired(), nil
+	case termExit:
+		return ExitNode(), nil
+	case termNone:
+		return Not(Query{}), nil
+	case termTag:
+		return Tag(value), nil
+	case termUser:
+		return User(value), nil
+	case termMethod:
+		return RegisteredBy(value), nil
+	case termName:
+		if _, err := path.Match(value, ""); err != nil {
+			return Query{}, p.errorf(t, "invalid pattern %q", value)
+		}
+		return GivenName(value), nil
+	case termIP, termRoute, termApproved:
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return Query{}, p.errorf(t, "invalid address or CIDR %q", value)
+		}
+		switch name {
+		case termIP:
+			return InPrefix(prefix), nil
+		case termRoute:
+			return AdvertisesRoute(prefix), nil
+		default:
+			return ApprovedRoute(prefix), nil
+		}
+	case termKey:
+		prop := KeyProperty(strings.ToLower(value))
+		if !slices.Contains(keyProperties, prop) {
+			return Query{}, p.errorf(t, "unknown key property %q", value)
+		}
+		return RegisteredWithKey(prop), nil
+	default:
+		return Query{}, p.errorf(t, "unknown term %q", name)
+	}
+}
+
+func joinTimeFields() string {
+	names := make([]string, len(timeFields))
+	for i, f := range timeFields {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
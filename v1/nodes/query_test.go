@@ -0,0 +1,215 @@
+package nodes
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/preauthkeys"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var queryNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func queryNodes() []Node {
+	return []Node{
+		{
+			ID:              "1",
+			GivenName:       "web-1",
+			User:            users.User{Name: "alice"},
+			Online:          true,
+			Tags:            []string{"tag:web"},
+			IPAddresses:     []string{"100.64.0.1", "fd7a:115c:a1e0::1"},
+			LastSeen:        queryNow.Add(-time.Hour),
+			RegisterMethod:  "REGISTER_METHOD_AUTH_KEY",
+			PreAuthKey:      &preauthkeys.PreAuthKey{ID: "1", Reusable: true},
+			AvailableRoutes: []string{"10.0.0.0/16"},
+		},
+		{
+			ID:             "2",
+			GivenName:      "db-1",
+			User:           users.User{Name: "bob"},
+			Tags:           []string{"tag:db"},
+			IPAddresses:    []string{"100.64.1.2"},
+			LastSeen:       queryNow.Add(-10 * 24 * time.Hour),
+			Expiry:         queryNow.Add(-time.Minute),
+			RegisterMethod: "REGISTER_METHOD_OIDC",
+			ApprovedRoutes: []string{ExitRouteIPv4, ExitRouteIPv6},
+		},
+		{
+			ID:             "3",
+			GivenName:      "web-2",
+			User:           users.User{Name: "alice"},
+			Tags:           []string{"tag:web", "tag:db"},
+			Expiry:         queryNow.Add(2 * time.Hour),
+			RegisterMethod: "REGISTER_METHOD_CLI",
+			PreAuthKey:     &preauthkeys.PreAuthKey{ID: "2", Ephemeral: true, Expiration: queryNow.Add(-time.Hour)},
+		},
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "", want: []string{"1", "2", "3"}},
+		{expr: "online", want: []string{"1"}},
+		{expr: "!online", want: []string{"2", "3"}},
+		{expr: "expired", want: []string{"2"}},
+		{expr: "exit", want: []string{"2"}},
+		{expr: "tag:web", want: []string{"1", "3"}},
+		{expr: "tag:web && tag:db", want: []string{"3"}},
+		{expr: "tag:db || online", want: []string{"1", "2", "3"}},
+		{expr: "user:alice && !(online || expiry < 1h)", want: []string{"3"}},
+		{expr: "name:web-*", want: []string{"1", "3"}},
+		{expr: "ip:100.64.0.0/24", want: []string{"1"}},
+		{expr: "ip:fd7a:115c:a1e0::1", want: []string{"1"}},
+		{expr: "route:10.0.5.0/24", want: []string{"1"}},
+		{expr: "route:10.0.0.0/8", want: nil},
+		{expr: "approved:0.0.0.0/0", want: []string{"2"}},
+		{expr: "method:authkey || method:CLI", want: []string{"1", "3"}},
+		{expr: "key", want: []string{"1", "3"}},
+		{expr: "key:reusable", want: []string{"1"}},
+		{expr: "key:expired", want: []string{"3"}},
+		{expr: "lastSeen < 7d", want: []string{"1"}},
+		{expr: "lastSeen >= 1w", want: []string{"2", "3"}},
+		{expr: "expiry <= 3h", want: []string{"2", "3"}},
+		{expr: "createdAt > 90s", want: []string{"1", "2", "3"}},
+		{expr: "online && tag:web && lastSeen < 7d", want: []string{"1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := ParseQuery(tt.expr)
+			require.NoError(t, err)
+
+			var got []string
+			for _, n := range queryNodes() {
+				if q.MatchAt(n, queryNow) {
+					got = append(got, n.ID)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		offset int
+		msg    string
+	}{
+		{expr: "online &&", offset: 9, msg: "unexpected end of query"},
+		{expr: "online & tag:web", offset: 7, msg: `unexpected "&"`},
+		{expr: "(online", offset: 7, msg: "missing )"},
+		{expr: "online)", offset: 6, msg: `unexpected ")"`},
+		{expr: "online:yes", offset: 0, msg: "online takes no value"},
+		{expr: "tag:", offset: 0, msg: "missing value after tag:"},
+		{expr: "online && key:", offset: 10, msg: "missing value after key:"},
+		{expr: "none:x", offset: 0, msg: "none takes no value"},
+		{expr: "color:red", offset: 0, msg: `unknown term "color"`},
+		{expr: "color", offset: 0, msg: `unknown term "color"`},
+		{expr: "ip:10.0.0.0/33", offset: 0, msg: `invalid address or CIDR "10.0.0.0/33"`},
+		{expr: "name:[", offset: 0, msg: `invalid pattern "["`},
+		{expr: "key:shared", offset: 0, msg: `unknown key property "shared"`},
+		{expr: "lastSeen", offset: 0, msg: "lastSeen must be compared with <, <=, > or >="},
+		{expr: "online < 1h", offset: 0, msg: `"online" cannot be compared, want one of lastSeen, createdAt, expiry`},
+		{expr: "lastSeen < soon", offset: 11, msg: `invalid duration "soon"`},
+		{expr: "lastSeen <", offset: 10, msg: "missing duration after lastSeen <"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseQuery(tt.expr)
+			require.ErrorIs(t, err, ErrInvalidQuery)
+
+			var qErr *QueryError
+			require.ErrorAs(t, err, &qErr)
+			assert.Equal(t, tt.offset, qErr.Offset)
+			assert.Equal(t, tt.msg, qErr.Msg)
+		})
+	}
+}
+
+func TestQuery_String(t *testing.T) {
+	q := And(
+		Online(),
+		Or(Tag("tag:web"), Tag("db")),
+		Not(Expired()),
+		Not(CompareTime(LastSeenField, Greater, 7*24*time.Hour)),
+		InPrefix(netip.MustParsePrefix("100.64.0.0/10")),
+		ApprovedRoute(netip.MustParsePrefix("10.0.0.1/32")),
+		RegisteredBy("REGISTER_METHOD_AUTH_KEY"),
+		RegisteredWithKey(KeyAny),
+	)
+	want := "online && (tag:web || tag:db) && !expired && !(lastSeen > 7d) && ip:100.64.0.0/10 && " +
+		"approved:10.0.0.1 && method:authkey && key"
+	assert.Equal(t, want, q.String())
+
+	parsed, err := ParseQuery(q.String())
+	require.NoError(t, err)
+	assert.Equal(t, want, parsed.String())
+}
+
+func TestQuery_StringNone(t *testing.T) {
+	none := Not(Query{})
+	node := Node{ID: "1", Online: true}
+
+	for _, q := range []Query{none, And(Online(), none), Not(none), Or(Tag("web"), none)} {
+		t.Run(q.String(), func(t *testing.T) {
+			parsed, err := ParseQuery(q.String())
+			require.NoError(t, err)
+			assert.Equal(t, q.String(), parsed.String())
+			assert.Equal(t, q.Match(node), parsed.Match(node))
+		})
+	}
+
+	assert.Equal(t, "none", none.String())
+	assert.False(t, none.Match(node))
+	assert.Equal(t, "online && none", And(Online(), none).String())
+	assert.Equal(t, "!none", Not(none).String())
+}
+
+func TestQuery_ListFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want NodeListFilter
+	}{
+		{expr: "user:alice", want: NodeListFilter{User: "alice"}},
+		{expr: "online && user:alice", want: NodeListFilter{User: "alice"}},
+		{expr: "online || user:alice", want: NodeListFilter{}},
+		{expr: "!user:alice", want: NodeListFilter{}},
+		{expr: "", want: NodeListFilter{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := ParseQuery(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q.ListFilter())
+		})
+	}
+}
+
+func TestNodeResource_Query(t *testing.T) {
+	fixture := testutil.TestFixture[NodesResponse]{
+		Endpoint:    "node",
+		Method:      http.MethodGet,
+		SuccessResp: NodesResponse{Nodes: []Node{{ID: "1", Online: true, User: users.User{Name: "alice"}}}},
+	}
+
+	testutil.RunResourceTest(t, fixture, func(ctx context.Context, mockReq *requests.MockRequest) (NodesResponse, error) {
+		n := &NodeResource{r: mockReq}
+		items, err := iterate.Collect(n.Query(ctx, And(User("alice"), Online())))
+		return NodesResponse{Nodes: items}, err
+	})
+}
@@ -0,0 +1,111 @@
+package nodes
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+// ReassignRequest represents a request to move a node to another user.
+type ReassignRequest struct {
+	// User is the user's ID, or its name before Headscale v0.26.0.
+	User string `json:"user"`
+}
+
+// ReassignResult is the outcome of moving one node with ReassignAll.
+type ReassignResult struct {
+	// Node is the node after the move, or before it if Err is set.
+	Node Node
+	Err  error
+}
+
+// Reassign moves a node to another user, given by ID or name. The user is looked up with
+// UserResource.Lookup only if the server needs the other form: a numeric user is taken as an ID
+// and anything else as a name.
+func (n *NodeResource) Reassign(ctx context.Context, id, user string) (NodeResponse, error) {
+	if err := requests.ServerVersion(n.r).Require(versions.CapabilityNodeReassign); err != nil {
+		return NodeResponse{}, err
+	}
+
+	target := users.User{ID: user, Name: user}
+	if isUserID(user) != requests.ServerVersion(n.r).Supports(versions.CapabilityNodeReassignByID) {
+		var err error
+		if target, err = users.NewUserResource(n.r).Lookup(ctx, user); err != nil {
+			return NodeResponse{}, err
+		}
+	}
+	return n.reassign(ctx, id, target)
+}
+
+// ReassignAll moves every node of the user from to the user to, both given by ID or name. Each
+// node is moved even if moving another fails; the outcome is reported per node. The error is set
+// only if the users or nodes could not be listed.
+func (n *NodeResource) ReassignAll(ctx context.Context, from, to string) ([]ReassignResult, error) {
+	if err := requests.ServerVersion(n.r).Require(versions.CapabilityNodeReassign); err != nil {
+		return nil, err
+	}
+
+	userResource := users.NewUserResource(n.r)
+	source, err := userResource.Lookup(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := userResource.Lookup(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := n.List(ctx, NodeListFilter{User: source.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ReassignResult
+	for _, node := range nodes.Nodes {
+		// Names are not unique across OIDC providers, so the listing may include other users' nodes.
+		if node.User.ID != source.ID {
+			continue
+		}
+
+		moved, err := n.reassign(ctx, node.ID, target)
+		if err != nil {
+			results = append(results, ReassignResult{Node: node, Err: err})
+			continue
+		}
+		results = append(results, ReassignResult{Node: moved.Node})
+	}
+	return results, nil
+}
+
+// reassign sends the move request in the form the server expects.
+func (n *NodeResource) reassign(ctx context.Context, id string, target users.User) (NodeResponse, error) {
+	var node NodeResponse
+
+	var opt requests.RequestOptions
+	switch v := requests.ServerVersion(n.r); {
+	case v.Supports(versions.CapabilityNodeReassignByID):
+		opt.Body = ReassignRequest{User: target.ID}
+	case v.Supports(versions.CapabilityNodeReassignBody):
+		opt.Body = ReassignRequest{User: target.Name}
+	default:
+		opt.QueryParams = map[string]any{"user": target.Name}
+	}
+
+	url := n.r.BuildURL("node", id, "user")
+	req, err := n.r.BuildRequest(ctx, http.MethodPost, url, opt)
+	if err != nil {
+		return node, err
+	}
+
+	err = n.r.Do(ctx, req, &node)
+	return node, err
+}
+
+func isUserID(user string) bool {
+	_, err := strconv.ParseUint(user, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,133 @@
+package nodes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/iterate"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	alice = users.User{ID: "1", Name: "alice"}
+	team  = users.User{ID: "2", Name: "team"}
+)
+
+// reassignServer mocks the user, node list and node user endpoints used by Reassign and ReassignAll.
+type reassignServer struct {
+	*requests.MockRequest
+
+	// sent are the options of the move requests, by node ID.
+	sent map[string]requests.RequestOptions
+}
+
+func newReassignServer(version versions.ServerVersion, nodes []Node) *reassignServer {
+	s := &reassignServer{MockRequest: new(requests.MockRequest), sent: map[string]requests.RequestOptions{}}
+	s.On("ServerVersion").Return(version).Maybe()
+
+	mockEndpoint(s.MockRequest, http.MethodGet, []any{"user"}, nil, func(v any) {
+		*v.(*users.UsersResponse) = users.UsersResponse{Users: []users.User{alice, team}} //nolint:errcheck // reason: type assertion in test
+	})
+	mockEndpoint(s.MockRequest, http.MethodGet, []any{"node"}, nil, func(v any) {
+		*v.(*NodesResponse) = NodesResponse{Nodes: nodes} //nolint:errcheck // reason: type assertion in test
+	})
+	for _, n := range nodes {
+		record := func(opt requests.RequestOptions) { s.sent[n.ID] = opt }
+		mockEndpoint(s.MockRequest, http.MethodPost, []any{"node", n.ID, "user"}, record, func(v any) {
+			*v.(*NodeResponse) = NodeResponse{Node: Node{ID: n.ID, User: team}} //nolint:errcheck // reason: type assertion in test
+		})
+	}
+	return s
+}
+
+func TestNodeResource_Reassign(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  versions.ServerVersion
+		user     string
+		want     requests.RequestOptions
+		lookedUp bool
+	}{
+		{
+			name: "by id", version: versions.ServerVersion{Minor: 26}, user: "2",
+			want: requests.RequestOptions{Body: ReassignRequest{User: "2"}},
+		},
+		{
+			name: "name resolved to id", version: versions.ServerVersion{Minor: 27}, user: "team",
+			want:     requests.RequestOptions{Body: ReassignRequest{User: "2"}},
+			lookedUp: true,
+		},
+		{
+			name: "by name in body", version: versions.ServerVersion{Minor: 25}, user: "team",
+			want: requests.RequestOptions{Body: ReassignRequest{User: "team"}},
+		},
+		{
+			name: "id resolved to name in query", version: versions.ServerVersion{Minor: 23}, user: "2",
+			want:     requests.RequestOptions{QueryParams: map[string]any{"user": "team"}},
+			lookedUp: true,
+		},
+		{
+			name: "unknown version", user: "2",
+			want: requests.RequestOptions{Body: ReassignRequest{User: "2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newReassignServer(tt.version, []Node{{ID: "7", User: alice}})
+			n := &NodeResource{r: s}
+
+			resp, err := n.Reassign(t.Context(), "7", tt.user)
+			require.NoError(t, err)
+			assert.Equal(t, team, resp.Node.User)
+			assert.Equal(t, tt.want, s.sent["7"])
+			if tt.lookedUp {
+				s.AssertCalled(t, "BuildURL", "user")
+			} else {
+				s.AssertNotCalled(t, "BuildURL", "user")
+			}
+		})
+	}
+}
+
+func TestNodeResource_Reassign_Errors(t *testing.T) {
+	s := newReassignServer(versions.ServerVersion{Minor: 28}, []Node{{ID: "7"}})
+	n := &NodeResource{r: s}
+	_, err := n.Reassign(t.Context(), "7", "2")
+	require.ErrorIs(t, err, versions.ErrUnsupportedByServer)
+
+	s = newReassignServer(versions.ServerVersion{Minor: 26}, []Node{{ID: "7"}})
+	n = &NodeResource{r: s}
+	_, err = n.Reassign(t.Context(), "7", "bob")
+	require.ErrorIs(t, err, iterate.ErrNotFound)
+	assert.Empty(t, s.sent)
+}
+
+func TestNodeResource_ReassignAll(t *testing.T) {
+	nodes := []Node{
+		{ID: "7", User: alice},
+		{ID: "8", User: users.User{ID: "3", Name: "alice"}},
+		{ID: "9", User: alice},
+	}
+	s := newReassignServer(versions.ServerVersion{Minor: 27}, nodes)
+	n := &NodeResource{r: s}
+
+	results, err := n.ReassignAll(t.Context(), "alice", "team")
+	require.NoError(t, err)
+	require.Len(t, results, 2, "nodes of another user named alice are left alone")
+	for _, r := range results {
+		require.NoError(t, r.Err)
+		assert.Equal(t, team, r.Node.User)
+	}
+	assert.Equal(t, map[string]requests.RequestOptions{
+		"7": {Body: ReassignRequest{User: "2"}},
+		"9": {Body: ReassignRequest{User: "2"}},
+	}, s.sent)
+
+	_, err = n.ReassignAll(t.Context(), "alice", "nobody")
+	require.ErrorIs(t, err, iterate.ErrNotFound)
+}
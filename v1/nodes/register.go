@@ -0,0 +1,259 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+// registrationIDLength is the length of the registration IDs in the register URLs of Headscale v0.25.0 and newer.
+const registrationIDLength = 24
+
+var (
+	// ErrNoRegistrationKey is returned when no registration key is found in the text given.
+	ErrNoRegistrationKey = errors.New("no registration key found")
+
+	// ErrInvalidRegistrationKey is returned for keys the server cannot register a node with.
+	ErrInvalidRegistrationKey = errors.New("invalid registration key")
+
+	// ErrAlreadyRegistered is wrapped by AlreadyRegisteredError.
+	ErrAlreadyRegistered = errors.New("key already registered")
+
+	// ErrRegistrationNotPending is returned when the server has no pending registration for the key:
+	// the node was registered with it already, or the registration timed out.
+	ErrRegistrationNotPending = errors.New("registration not pending")
+)
+
+// AlreadyRegisteredError is returned by RegisterFrom when a node holds the registration key.
+type AlreadyRegisteredError struct {
+	Node Node
+}
+
+func (e *AlreadyRegisteredError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrAlreadyRegistered, nodeLabel(e.Node))
+}
+
+func (e *AlreadyRegisteredError) Unwrap() error {
+	return ErrAlreadyRegistered
+}
+
+var (
+	// registerPathPattern matches the key in register URLs such as https://hs.example.com/register/<key>.
+	registerPathPattern = regexp.MustCompile(`/register/([^/?#\s]+)`)
+
+	// keyFlagPattern matches the key in commands such as "headscale nodes register --key <key>".
+	keyFlagPattern = regexp.MustCompile(`--key[=\s]+(\S+)`)
+
+	// prefixedKeyPattern matches machine and node keys anywhere in a text.
+	prefixedKeyPattern = regexp.MustCompile(`(?:mkey|nodekey):[0-9a-fA-F]{64}`)
+
+	// registrationIDPattern matches a whole registration ID.
+	registrationIDPattern = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9_-]{%d}$`, registrationIDLength))
+
+	// machineKeyPattern matches a whole machine key.
+	machineKeyPattern = regexp.MustCompile(`^mkey:[0-9a-f]{64}$`)
+)
+
+// ExtractRegistrationKey returns the registration key in text: a register URL, the output of
+// "tailscale up" or "headscale nodes register", or the bare key. The key is a registration ID on
+// Headscale v0.25.0 and newer and a machine key (mkey:...) before. It returns an error wrapping
+// ErrNoRegistrationKey if text holds none, and one wrapping ErrInvalidRegistrationKey if the key
+// found is malformed.
+func ExtractRegistrationKey(text string) (string, error) {
+	var key string
+	switch {
+	case registerPathPattern.MatchString(text):
+		key = registerPathPattern.FindStringSubmatch(text)[1]
+		if unescaped, err := url.PathUnescape(key); err == nil {
+			key = unescaped
+		}
+	case keyFlagPattern.MatchString(text):
+		key = keyFlagPattern.FindStringSubmatch(text)[1]
+	case prefixedKeyPattern.MatchString(text):
+		key = prefixedKeyPattern.FindString(text)
+	case len(strings.Fields(text)) == 1:
+		key = strings.TrimSpace(text)
+	default:
+		return "", ErrNoRegistrationKey
+	}
+
+	switch {
+	case registrationIDPattern.MatchString(key):
+		return key, nil
+	case machineKeyPattern.MatchString(strings.ToLower(key)):
+		return strings.ToLower(key), nil
+	case strings.HasPrefix(key, NodeKeyPrefix):
+		return "", fmt.Errorf("%w %q: node keys cannot register a node, use the key of its register URL", ErrInvalidRegistrationKey, key)
+	default:
+		return "", fmt.Errorf("%w %q", ErrInvalidRegistrationKey, key)
+	}
+}
+
+// RegisterOptions configures RegisterFrom.
+type RegisterOptions struct {
+	// User owns the node. It is looked up by ID, name or email with UserResource.Lookup.
+	User string
+
+	// Name, Tags and Routes are optional. They set the given name, the tags and the approved routes
+	// of the node after registration.
+	Name   string
+	Tags   []string
+	Routes []string
+}
+
+// Registration is the result of RegisterFrom.
+type Registration struct {
+	// Node is the registered node, after any follow-up steps that succeeded.
+	Node Node
+	User users.User
+	Key  string
+}
+
+// RegisterFrom registers the node whose registration key is in text, as accepted by
+// ExtractRegistrationKey, and applies the name, tags and routes of opt.
+//
+// The key, user, tags and routes are checked before registering. A key that belongs to a node
+// already returns an *AlreadyRegisteredError; a key the server no longer knows, an error wrapping
+// ErrRegistrationNotPending. If a step after registration fails, the error names the
+// step and the result holds the node as registered.
+func (n *NodeResource) RegisterFrom(ctx context.Context, text string, opt RegisterOptions) (Registration, error) {
+	key, err := ExtractRegistrationKey(text)
+	if err != nil {
+		return Registration{}, err
+	}
+	if err := n.checkRegistration(ctx, key, opt); err != nil {
+		return Registration{}, err
+	}
+
+	user, err := users.NewUserResource(n.r).Lookup(ctx, opt.User)
+	if err != nil {
+		return Registration{}, err
+	}
+
+	registered, err := n.Register(ctx, user.Name, key)
+	if err != nil {
+		if registrationNotPending(err) {
+			return Registration{}, fmt.Errorf("%w: %w", ErrRegistrationNotPending, err)
+		}
+		return Registration{}, err
+	}
+
+	result := Registration{Node: registered.Node, User: user, Key: key}
+	id := registered.Node.ID
+
+	if opt.Name != "" && opt.Name != result.Node.GivenName {
+		resp, err := n.Rename(ctx, id, opt.Name)
+		if err != nil {
+			return result, fmt.Errorf("registered node %s, rename: %w", id, err)
+		}
+		result.Node = resp.Node
+	}
+	if len(opt.Tags) > 0 {
+		resp, err := n.setTags(ctx, id, opt.Tags)
+		if err != nil {
+			return result, fmt.Errorf("registered node %s, set tags: %w", id, err)
+		}
+		result.Node = resp.Node
+	}
+	if len(opt.Routes) > 0 {
+		resp, err := n.ApproveRoutes(ctx, id, opt.Routes)
+		if err != nil {
+			return result, fmt.Errorf("registered node %s, approve routes: %w", id, err)
+		}
+		result.Node = resp.Node
+	}
+	return result, nil
+}
+
+// checkRegistration returns an error if registering with key and opt is bound to fail.
+func (n *NodeResource) checkRegistration(ctx context.Context, key string, opt RegisterOptions) error {
+	v := requests.ServerVersion(n.r)
+	if !v.IsZero() {
+		switch isID := registrationIDPattern.MatchString(key); {
+		case isID && !v.Supports(versions.CapabilityRegistrationID):
+			return fmt.Errorf("%w %q: Headscale %s registers nodes by machine key", ErrInvalidRegistrationKey, key, v)
+		case !isID && v.Supports(versions.CapabilityRegistrationID):
+			return fmt.Errorf("%w %q: Headscale %s registers nodes by registration ID", ErrInvalidRegistrationKey, key, v)
+		}
+	}
+
+	if opt.Name != "" {
+		if err := n.validateName(opt.Name); err != nil {
+			return err
+		}
+	}
+	if len(opt.Routes) > 0 {
+		// Older releases only approve advertised routes, and a node advertises none before it is registered.
+		if err := v.Require(versions.CapabilityApproveRoutes); err != nil {
+			return err
+		}
+	}
+	if len(opt.Tags) > 0 {
+		if err := validateTags(opt.Tags); err != nil {
+			return err
+		}
+		if err := n.checkTagOwners(ctx, opt.Tags); err != nil {
+			return err
+		}
+	}
+
+	for node, err := range n.All(ctx, NodeListFilter{}) {
+		if err != nil {
+			return err
+		}
+		if registeredWith(node, key) {
+			return &AlreadyRegisteredError{Node: node}
+		}
+	}
+	return nil
+}
+
+// registeredWith reports whether node holds the registration key key. Machine keys are compared
+// without their mkey: prefix and case, as servers and clients print them either way.
+func registeredWith(node Node, key string) bool {
+	machineKey := strings.TrimPrefix(strings.ToLower(node.MachineKey), MachineKeyPrefix)
+	return machineKey != "" && machineKey == strings.TrimPrefix(key, MachineKeyPrefix)
+}
+
+// grpcStatus is the JSON body of Headscale API errors, a gRPC status.
+type grpcStatus struct {
+	Code    *int   `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	// grpcUnknown and grpcNotFound are the gRPC status codes Unknown and NotFound.
+	grpcUnknown  = 2
+	grpcNotFound = 5
+
+	// notPendingHint is part of the message of the errors releases answer for keys missing from
+	// the registration cache with the code Unknown.
+	notPendingHint = "not found in registration cache"
+)
+
+// registrationNotPending reports whether err is the server's answer to a registration key missing
+// from its registration cache: the gRPC status NotFound. The message is only looked at when the
+// status has no specific code, i.e. the code Unknown or none.
+func registrationNotPending(err error) bool {
+	var apiErr *requests.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	var status grpcStatus
+	if err := json.Unmarshal([]byte(apiErr.Body), &status); err != nil {
+		return false
+	}
+	if status.Code != nil && *status.Code != grpcUnknown {
+		return *status.Code == grpcNotFound
+	}
+	return strings.Contains(status.Message, notPendingHint)
+}
@@ -0,0 +1,204 @@
+package nodes
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/policy"
+	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRegistrationID = "AbCdEfGhIjKlMnOp-_123456"
+	testMachineKey     = "mkey:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestExtractRegistrationKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr error
+	}{
+		{name: "register url", text: "https://hs.example.com/register/" + testRegistrationID, want: testRegistrationID},
+		{name: "oidc register url", text: "https://hs.example.com/oidc/register/" + testRegistrationID + "?x=1", want: testRegistrationID},
+		{
+			name: "tailscale up output",
+			text: "\nTo authenticate, visit:\n\n\thttps://hs.example.com/register/" + testMachineKey + "\n\n",
+			want: testMachineKey,
+		},
+		{name: "headscale command", text: "headscale nodes register --key " + testRegistrationID + " --user USERNAME", want: testRegistrationID},
+		{name: "machine key in text", text: "machine key: " + strings.ToUpper(testMachineKey[5:]) + " or " + testMachineKey, want: testMachineKey},
+		{name: "bare key", text: "  " + testRegistrationID + "\n", want: testRegistrationID},
+		{name: "node key", text: "nodekey:" + testMachineKey[5:], wantErr: ErrInvalidRegistrationKey},
+		{name: "short id", text: "https://hs.example.com/register/abc", wantErr: ErrInvalidRegistrationKey},
+		{name: "no key", text: "please register my laptop", wantErr: ErrNoRegistrationKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractRegistrationKey(tt.text)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// registerServer mocks the endpoints used by RegisterFrom. The registered node is node 9.
+type registerServer struct {
+	*requests.MockRequest
+
+	// query are the query parameters of the register request, nil if it was not sent.
+	query map[string]any
+}
+
+func newRegisterServer(version versions.ServerVersion, existing []Node) *registerServer {
+	s := &registerServer{MockRequest: new(requests.MockRequest)}
+	s.On("ServerVersion").Return(version).Maybe()
+
+	mockEndpoint(s.MockRequest, http.MethodGet, []any{"user"}, nil, func(v any) {
+		*v.(*users.UsersResponse) = users.UsersResponse{Users: []users.User{ //nolint:errcheck // reason: type assertion in test
+			{ID: "1", Name: "alice", Email: "alice@example.com"},
+		}}
+	})
+	mockEndpoint(s.MockRequest, http.MethodGet, []any{"node"}, nil, func(v any) {
+		*v.(*NodesResponse) = NodesResponse{Nodes: existing} //nolint:errcheck // reason: type assertion in test
+	})
+	mockEndpoint(s.MockRequest, http.MethodGet, []any{"routes"}, nil, nil)
+	mockEndpoint(s.MockRequest, http.MethodGet, []any{"policy"}, nil, func(v any) {
+		*v.(*policy.Policy) = policy.Policy{Policy: tagsPolicy} //nolint:errcheck // reason: type assertion in test
+	})
+	record := func(opt requests.RequestOptions) { s.query = opt.QueryParams }
+	mockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "register"}, record, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: Node{ID: "9", GivenName: "laptop"}} //nolint:errcheck // reason: type assertion in test
+	})
+	mockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "9", "rename", "web-1"}, nil, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: Node{ID: "9", GivenName: "web-1"}} //nolint:errcheck // reason: type assertion in test
+	})
+	mockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "9", "tags"}, nil, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: Node{ID: "9", GivenName: "web-1", Tags: []string{"tag:web"}}} //nolint:errcheck // reason: type assertion in test
+	})
+	mockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "9", "approve_routes"}, nil, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: Node{ //nolint:errcheck // reason: type assertion in test
+			ID: "9", GivenName: "web-1", Tags: []string{"tag:web"}, ApprovedRoutes: []string{"10.0.0.0/24"},
+		}}
+	})
+	return s
+}
+
+func TestNodeResource_RegisterFrom(t *testing.T) {
+	s := newRegisterServer(versions.ServerVersion{Minor: 28}, nil)
+	n := &NodeResource{r: s}
+
+	reg, err := n.RegisterFrom(t.Context(), "https://hs.example.com/register/"+testRegistrationID, RegisterOptions{
+		User:   "alice@example.com",
+		Name:   "web-1",
+		Tags:   []string{"tag:web"},
+		Routes: []string{"10.0.0.0/24"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"user": "alice", "key": testRegistrationID}, s.query)
+	assert.Equal(t, "1", reg.User.ID)
+	assert.Equal(t, testRegistrationID, reg.Key)
+	assert.Equal(t, Node{ID: "9", GivenName: "web-1", Tags: []string{"tag:web"}, ApprovedRoutes: []string{"10.0.0.0/24"}}, reg.Node)
+	s.AssertCalled(t, "BuildURL", "node")
+}
+
+func TestNodeResource_RegisterFrom_Checks(t *testing.T) {
+	existing := []Node{{ID: "3", GivenName: "old", MachineKey: testMachineKey}}
+
+	tests := []struct {
+		name    string
+		version versions.ServerVersion
+		text    string
+		opt     RegisterOptions
+		wantErr error
+	}{
+		{name: "machine key registered", version: versions.ServerVersion{Minor: 23}, text: testMachineKey, wantErr: ErrAlreadyRegistered},
+		{name: "machine key on newer server", version: versions.ServerVersion{Minor: 25}, text: testMachineKey, wantErr: ErrInvalidRegistrationKey},
+		{name: "registration id on older server", version: versions.ServerVersion{Minor: 24}, text: testRegistrationID, wantErr: ErrInvalidRegistrationKey},
+		{name: "invalid name", text: testRegistrationID, opt: RegisterOptions{Name: "Web 1"}, wantErr: ErrInvalidName},
+		{name: "tag not owned", text: testRegistrationID, opt: RegisterOptions{Tags: []string{"tag:cache"}}, wantErr: ErrTagNotOwned},
+		{
+			name: "routes unsupported", version: versions.ServerVersion{Minor: 25}, text: testRegistrationID,
+			opt: RegisterOptions{Routes: []string{"10.0.0.0/24"}}, wantErr: versions.ErrUnsupportedByServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRegisterServer(tt.version, existing)
+			n := &NodeResource{r: s}
+			tt.opt.User = "alice"
+
+			_, err := n.RegisterFrom(t.Context(), tt.text, tt.opt)
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, s.query, "nothing is registered")
+		})
+	}
+
+	var already *AlreadyRegisteredError
+	s := newRegisterServer(versions.ServerVersion{}, existing)
+	_, err := (&NodeResource{r: s}).RegisterFrom(t.Context(), testMachineKey, RegisterOptions{User: "alice"})
+	require.ErrorAs(t, err, &already)
+	assert.Equal(t, "3", already.Node.ID)
+
+	// Servers may print machine keys without their prefix or in upper case.
+	bare := []Node{{ID: "4", GivenName: "old", MachineKey: strings.ToUpper(testMachineKey[5:])}}
+	s = newRegisterServer(versions.ServerVersion{}, bare)
+	_, err = (&NodeResource{r: s}).RegisterFrom(t.Context(), "https://hs.example.com/register/"+testMachineKey, RegisterOptions{User: "alice"})
+	require.ErrorAs(t, err, &already)
+	assert.Equal(t, "4", already.Node.ID)
+	assert.Nil(t, s.query, "nothing is registered")
+}
+
+func TestNodeResource_RegisterFrom_NotPending(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		notPending bool
+	}{
+		{name: "not found", body: `{"code":5,"message":"registration ID expired","details":[]}`, notPending: true},
+		{name: "unknown with hint", body: `{"code":2,"message":"node not found in registration cache"}`, notPending: true},
+		{name: "unknown", body: `{"code":2,"message":"database is locked"}`},
+		{name: "other code with hint", body: `{"code":3,"message":"node not found in registration cache"}`},
+		{name: "not json", body: "node not found in registration cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(requests.MockRequest)
+			m.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+			mockEndpoint(m, http.MethodGet, []any{"user"}, nil, func(v any) {
+				*v.(*users.UsersResponse) = users.UsersResponse{Users: []users.User{{ID: "1", Name: "alice"}}} //nolint:errcheck // reason: type assertion in test
+			})
+			mockEndpoint(m, http.MethodGet, []any{"node"}, nil, nil)
+
+			u := &url.URL{Path: "/node/register"}
+			req := &http.Request{}
+			m.On("BuildURL", "node", "register").Return(u)
+			m.On("BuildRequest", mock.Anything, http.MethodPost, u, mock.Anything).Return(req, nil)
+			m.On("Do", mock.Anything, req, mock.Anything).Return(&requests.APIError{
+				StatusCode: http.StatusInternalServerError,
+				Body:       tt.body,
+			})
+
+			_, err := (&NodeResource{r: m}).RegisterFrom(t.Context(), testRegistrationID, RegisterOptions{User: "alice"})
+			var apiErr *requests.APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.notPending, errors.Is(err, ErrRegistrationNotPending))
+		})
+	}
+}
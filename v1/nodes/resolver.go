@@ -0,0 +1,215 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+)
+
+// Key prefixes Headscale reports machine and node keys with.
+const (
+	MachineKeyPrefix = "mkey:"
+	NodeKeyPrefix    = "nodekey:"
+)
+
+// ErrAmbiguousNode is wrapped by AmbiguousError.
+var ErrAmbiguousNode = errors.New("ambiguous node reference")
+
+// AmbiguousError is returned when a reference matches more than one node.
+type AmbiguousError struct {
+	// Ref is the name, address or key looked up.
+	Ref string
+
+	// Candidates are the nodes Ref matches.
+	Candidates []Node
+}
+
+func (e *AmbiguousError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for i, n := range e.Candidates {
+		names[i] = fmt.Sprintf("%s (ID %s)", n.GivenName, n.ID)
+	}
+	return fmt.Sprintf("%s %q: matches %s", ErrAmbiguousNode, e.Ref, strings.Join(names, ", "))
+}
+
+func (e *AmbiguousError) Unwrap() error {
+	return ErrAmbiguousNode
+}
+
+// Resolver finds nodes by ID, given name, IP address or key. Lookups that find nothing return an
+// error wrapping iterate.ErrNotFound.
+//
+// A Resolver with a positive TTL keeps the node list for that long, so that repeated lookups do
+// not list the nodes again. It is safe for concurrent use.
+type Resolver struct {
+	nodes NodeResourceInterface
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	index   *nodeIndex
+	fetched time.Time
+}
+
+// nodeIndex is a node list indexed for lookups.
+type nodeIndex struct {
+	nodes []Node
+	byIP  map[netip.Addr]int
+}
+
+// NewResolver returns a Resolver listing nodes through r. Lists are cached for ttl; zero disables caching.
+func NewResolver(r NodeResourceInterface, ttl time.Duration) *Resolver {
+	return &Resolver{nodes: r, ttl: ttl, now: time.Now}
+}
+
+// Invalidate drops the cached node list.
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.index = nil
+}
+
+// load returns the cached index, listing the nodes if there is none or it is older than the TTL.
+func (r *Resolver) load(ctx context.Context) (*nodeIndex, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.index != nil && r.now().Sub(r.fetched) < r.ttl {
+		return r.index, nil
+	}
+
+	resp, err := r.nodes.List(ctx, NodeListFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	index := &nodeIndex{nodes: resp.Nodes, byIP: make(map[netip.Addr]int)}
+	for i, n := range resp.Nodes {
+		for _, s := range n.IPAddresses {
+			if addr, err := netip.ParseAddr(s); err == nil {
+				index.byIP[addr.Unmap()] = i
+			}
+		}
+	}
+
+	r.index, r.fetched = index, r.now()
+	return index, nil
+}
+
+// Resolve finds the node ref refers to. ref is tried as an IP address, a key with its mkey: or
+// nodekey: prefix, and a node ID, and otherwise looked up with ByName.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (Node, error) {
+	if _, err := netip.ParseAddr(ref); err == nil {
+		return r.ByIP(ctx, ref)
+	}
+	if strings.HasPrefix(ref, MachineKeyPrefix) || strings.HasPrefix(ref, NodeKeyPrefix) {
+		return r.ByKey(ctx, ref)
+	}
+
+	index, err := r.load(ctx)
+	if err != nil {
+		return Node{}, err
+	}
+	for _, n := range index.nodes {
+		if n.ID == ref {
+			return n, nil
+		}
+	}
+	return index.byName(ref)
+}
+
+// ByName finds the node with the given name, or failing that, the only node whose given name starts
+// with name. Names are compared case-insensitively, and a MagicDNS name such as web.tailnet.example.com
+// is looked up by its first label. Several nodes sharing the prefix produce an *AmbiguousError.
+func (r *Resolver) ByName(ctx context.Context, name string) (Node, error) {
+	index, err := r.load(ctx)
+	if err != nil {
+		return Node{}, err
+	}
+	return index.byName(name)
+}
+
+func (x *nodeIndex) byName(ref string) (Node, error) {
+	name, _, _ := strings.Cut(strings.ToLower(ref), ".")
+	if name == "" {
+		return single(ref, nil)
+	}
+
+	var exact, prefixed []Node
+	for _, n := range x.nodes {
+		given := strings.ToLower(n.GivenName)
+		switch {
+		case given == name:
+			exact = append(exact, n)
+		case strings.HasPrefix(given, name):
+			prefixed = append(prefixed, n)
+		}
+	}
+
+	if len(exact) == 0 {
+		exact = prefixed
+	}
+	return single(ref, exact)
+}
+
+// ByIP finds the node with the IP address ip.
+func (r *Resolver) ByIP(ctx context.Context, ip string) (Node, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Node{}, fmt.Errorf("invalid IP address %q: %w", ip, err)
+	}
+
+	index, err := r.load(ctx)
+	if err != nil {
+		return Node{}, err
+	}
+
+	i, ok := index.byIP[addr.Unmap()]
+	if !ok {
+		return single(ip, nil)
+	}
+	return index.nodes[i], nil
+}
+
+// ByKey finds the node with the machine or node key key. With the mkey: or nodekey: prefix only
+// machine or node keys are compared; without a prefix both are.
+func (r *Resolver) ByKey(ctx context.Context, key string) (Node, error) {
+	index, err := r.load(ctx)
+	if err != nil {
+		return Node{}, err
+	}
+
+	machineKey, isMachineKey := strings.CutPrefix(key, MachineKeyPrefix)
+	nodeKey, isNodeKey := strings.CutPrefix(key, NodeKeyPrefix)
+	if machineKey == "" || nodeKey == "" {
+		return single(key, nil)
+	}
+
+	var matches []Node
+	for _, n := range index.nodes {
+		switch {
+		case !isNodeKey && strings.TrimPrefix(n.MachineKey, MachineKeyPrefix) == machineKey,
+			!isMachineKey && strings.TrimPrefix(n.NodeKey, NodeKeyPrefix) == nodeKey:
+			matches = append(matches, n)
+		}
+	}
+	return single(key, matches)
+}
+
+// single returns the only node of matches, or an error if there are none or several.
+func single(ref string, matches []Node) (Node, error) {
+	switch len(matches) {
+	case 0:
+		return Node{}, fmt.Errorf("%w: %q", &iterate.NotFoundError{Type: fmt.Sprintf("%T", Node{})}, ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return Node{}, &AmbiguousError{Ref: ref, Candidates: matches}
+	}
+}
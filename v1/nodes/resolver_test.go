@@ -0,0 +1,133 @@
+package nodes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/iterate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func resolverNodes() []Node {
+	return []Node{
+		{ID: "1", GivenName: "web-1", IPAddresses: []string{"100.64.0.1", "fd7a:115c:a1e0::1"}, MachineKey: "mkey:aaa", NodeKey: "nodekey:bbb"},
+		{ID: "2", GivenName: "web-2", IPAddresses: []string{"100.64.0.2"}, MachineKey: "mkey:ccc", NodeKey: "nodekey:aaa"},
+		{ID: "3", GivenName: "db", IPAddresses: []string{"100.64.0.3"}, MachineKey: "mkey:ddd", NodeKey: "nodekey:eee"},
+		{ID: "4", GivenName: "db-replica", IPAddresses: []string{"100.64.0.4"}, MachineKey: "mkey:fff", NodeKey: "nodekey:ggg"},
+	}
+}
+
+func newTestResolver(ttl time.Duration) (*Resolver, *MockNodeResource) {
+	m := new(MockNodeResource)
+	m.On("List", mock.Anything, NodeListFilter{}).Return(NodesResponse{Nodes: resolverNodes()}, nil)
+	return NewResolver(m, ttl), m
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		ref       string
+		wantID    string
+		ambiguous []string
+	}{
+		{ref: "web-1", wantID: "1"},
+		{ref: "WEB-2.tailnet.example.com", wantID: "2"},
+		{ref: "db", wantID: "3"},
+		{ref: "db-r", wantID: "4"},
+		{ref: "web", ambiguous: []string{"1", "2"}},
+		{ref: "3", wantID: "3"},
+		{ref: "100.64.0.2", wantID: "2"},
+		{ref: "fd7a:115c:a1e0::1", wantID: "1"},
+		{ref: "::ffff:100.64.0.4", wantID: "4"},
+		{ref: "mkey:ccc", wantID: "2"},
+		{ref: "nodekey:aaa", wantID: "2"},
+		{ref: "mkey:aaa", wantID: "1"},
+		{ref: "mail"},
+		{ref: "100.64.0.9"},
+		{ref: "nodekey:"},
+		{ref: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			r, _ := newTestResolver(0)
+			n, err := r.Resolve(t.Context(), tt.ref)
+
+			switch {
+			case tt.wantID != "":
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantID, n.ID)
+			case tt.ambiguous != nil:
+				var ambiguous *AmbiguousError
+				require.ErrorAs(t, err, &ambiguous)
+				require.ErrorIs(t, err, ErrAmbiguousNode)
+				var ids []string
+				for _, c := range ambiguous.Candidates {
+					ids = append(ids, c.ID)
+				}
+				assert.Equal(t, tt.ambiguous, ids)
+			default:
+				require.ErrorIs(t, err, iterate.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestResolver_ByKey(t *testing.T) {
+	r, _ := newTestResolver(0)
+
+	_, err := r.ByKey(t.Context(), "aaa")
+	var ambiguous *AmbiguousError
+	require.ErrorAs(t, err, &ambiguous, "an unprefixed key is compared with machine and node keys")
+	assert.Equal(t, `ambiguous node reference "aaa": matches web-1 (ID 1), web-2 (ID 2)`, err.Error())
+
+	n, err := r.ByKey(t.Context(), "eee")
+	require.NoError(t, err)
+	assert.Equal(t, "3", n.ID)
+}
+
+func TestResolver_ByIP(t *testing.T) {
+	r, m := newTestResolver(0)
+
+	_, err := r.ByIP(t.Context(), "web-1")
+	require.Error(t, err)
+	m.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestResolver_Cache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r, m := newTestResolver(time.Minute)
+	r.now = func() time.Time { return now }
+
+	for _, ref := range []string{"web-1", "100.64.0.2", "mkey:ddd"} {
+		_, err := r.Resolve(t.Context(), ref)
+		require.NoError(t, err)
+	}
+	m.AssertNumberOfCalls(t, "List", 1)
+
+	now = now.Add(time.Minute)
+	_, err := r.ByName(t.Context(), "db")
+	require.NoError(t, err)
+	m.AssertNumberOfCalls(t, "List", 2)
+
+	r.Invalidate()
+	_, err = r.ByName(t.Context(), "db")
+	require.NoError(t, err)
+	m.AssertNumberOfCalls(t, "List", 3)
+}
+
+func TestResolver_ListError(t *testing.T) {
+	errList := errors.New("unavailable")
+	m := new(MockNodeResource)
+	m.On("List", mock.Anything, NodeListFilter{}).Return(NodesResponse{}, errList).Twice()
+
+	r := NewResolver(m, time.Minute)
+	_, err := r.ByName(t.Context(), "web")
+	require.ErrorIs(t, err, errList)
+
+	_, err = r.ByName(t.Context(), "web")
+	require.ErrorIs(t, err, errList, "failures are not cached")
+	m.AssertExpectations(t)
+}
@@ -0,0 +1,120 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+// legacyRoute is a route of the routes API, which Headscale releases before v0.26.0 use instead of
+// the route lists of nodes.
+type legacyRoute struct {
+	ID         string    `json:"id"`
+	Node       Node      `json:"node"`
+	Prefix     string    `json:"prefix"`
+	Advertised bool      `json:"advertised"`
+	Enabled    bool      `json:"enabled"`
+	IsPrimary  bool      `json:"isPrimary"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	DeletedAt  time.Time `json:"deletedAt"`
+}
+
+// legacyRoutesResponse is the response of the routes API.
+type legacyRoutesResponse struct {
+	Routes []legacyRoute `json:"routes"`
+}
+
+// usesRoutesAPI reports whether the server is known to keep routes in the routes API.
+func (n *NodeResource) usesRoutesAPI() bool {
+	v := requests.ServerVersion(n.r)
+	return !v.IsZero() && v.Supports(versions.CapabilityRoutesAPI)
+}
+
+// listRoutes returns the routes of all nodes, or of the node with the given ID.
+func (n *NodeResource) listRoutes(ctx context.Context, id string) ([]legacyRoute, error) {
+	var routes legacyRoutesResponse
+
+	url := n.r.BuildURL("routes")
+	if id != "" {
+		url = n.r.BuildURL("node", id, "routes")
+	}
+	req, err := n.r.BuildRequest(ctx, http.MethodGet, url, requests.RequestOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.r.Do(ctx, req, &routes); err != nil {
+		return nil, fmt.Errorf("list routes: %w", err)
+	}
+	return routes.Routes, nil
+}
+
+// setRoutes fills the route lists of nodes from routes: advertised routes are available, enabled
+// routes are approved, and enabled primary subnet routes are served.
+func setRoutes(nodes []Node, routes []legacyRoute) {
+	byNode := map[string][]legacyRoute{}
+	for _, r := range routes {
+		byNode[r.Node.ID] = append(byNode[r.Node.ID], r)
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		node.AvailableRoutes, node.ApprovedRoutes, node.SubnetRoutes = nil, nil, nil
+		for _, r := range byNode[node.ID] {
+			if r.Advertised {
+				node.AvailableRoutes = append(node.AvailableRoutes, r.Prefix)
+			}
+			if r.Enabled {
+				node.ApprovedRoutes = append(node.ApprovedRoutes, r.Prefix)
+			}
+			if r.Advertised && r.Enabled && r.IsPrimary && !slices.Contains(exitRoutes, r.Prefix) {
+				node.SubnetRoutes = append(node.SubnetRoutes, r.Prefix)
+			}
+		}
+	}
+}
+
+// approveLegacyRoutes enables the routes of a node listed in routes and disables the others, which
+// is ApproveRoutes on releases with the routes API. Only advertised routes can be enabled.
+func (n *NodeResource) approveLegacyRoutes(ctx context.Context, id string, routes []string) (NodeResponse, error) {
+	current, err := n.listRoutes(ctx, id)
+	if err != nil {
+		return NodeResponse{}, err
+	}
+
+	for _, route := range routes {
+		if !slices.ContainsFunc(current, func(r legacyRoute) bool { return r.Prefix == route && r.Advertised }) {
+			return NodeResponse{}, fmt.Errorf("%w: node %s does not advertise %s, and Headscale %s approves advertised routes only",
+				versions.ErrUnsupportedByServer, id, route, requests.ServerVersion(n.r))
+		}
+	}
+
+	// These releases enable and disable both exit routes together.
+	exit := slices.ContainsFunc(routes, func(route string) bool { return slices.Contains(exitRoutes, route) })
+	for _, r := range current {
+		enable := slices.Contains(routes, r.Prefix) || exit && slices.Contains(exitRoutes, r.Prefix)
+		if enable == r.Enabled {
+			continue
+		}
+
+		action := "disable"
+		if enable {
+			action = "enable"
+		}
+		req, err := n.r.BuildRequest(ctx, http.MethodPost, n.r.BuildURL("routes", r.ID, action), requests.RequestOptions{})
+		if err != nil {
+			return NodeResponse{}, err
+		}
+		if err := n.r.Do(ctx, req, nil); err != nil {
+			return NodeResponse{}, fmt.Errorf("%s route %s: %w", action, r.Prefix, err)
+		}
+	}
+
+	return n.Get(ctx, id)
+}
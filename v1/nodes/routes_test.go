@@ -0,0 +1,94 @@
+package nodes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyRoutesServer mocks a v0.25.0 server with node 1 and its routes, recording the routes
+// enabled and disabled.
+type legacyRoutesServer struct {
+	*requests.MockRequest
+
+	routes  []legacyRoute
+	changes []string
+}
+
+func newLegacyRoutesServer() *legacyRoutesServer {
+	node := Node{ID: "1", GivenName: "router"}
+	s := &legacyRoutesServer{
+		MockRequest: new(requests.MockRequest),
+		routes: []legacyRoute{
+			{ID: "11", Node: node, Prefix: "10.0.0.0/24", Advertised: true, Enabled: true, IsPrimary: true},
+			{ID: "12", Node: node, Prefix: "192.168.1.0/24", Advertised: true},
+			{ID: "13", Node: node, Prefix: ExitRouteIPv4, Advertised: true},
+			{ID: "14", Node: node, Prefix: ExitRouteIPv6, Advertised: true},
+		},
+	}
+	s.On("ServerVersion").Return(versions.ServerVersion{Minor: 25}).Maybe()
+
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"node"}, nil, func(v any) {
+		*v.(*NodesResponse) = NodesResponse{Nodes: []Node{node, {ID: "2"}}} //nolint:errcheck // reason: type assertion in test
+	})
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"node", "1"}, nil, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: node} //nolint:errcheck // reason: type assertion in test
+	})
+	respondRoutes := func(v any) {
+		*v.(*legacyRoutesResponse) = legacyRoutesResponse{Routes: s.routes} //nolint:errcheck // reason: type assertion in test
+	}
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"routes"}, nil, respondRoutes)
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"node", "1", "routes"}, nil, respondRoutes)
+
+	for i, r := range s.routes {
+		for _, action := range []string{"enable", "disable"} {
+			testutil.MockEndpoint(s.MockRequest, http.MethodPost, []any{"routes", r.ID, action}, nil, func(any) {
+				s.routes[i].Enabled = action == "enable"
+				s.changes = append(s.changes, action+" "+r.Prefix)
+			})
+		}
+	}
+	return s
+}
+
+func TestNodeResource_LegacyRoutes(t *testing.T) {
+	s := newLegacyRoutesServer()
+	n := &NodeResource{r: s}
+
+	list, err := n.List(t.Context(), NodeListFilter{})
+	require.NoError(t, err)
+	require.Len(t, list.Nodes, 2)
+	assert.Equal(t, []string{"10.0.0.0/24", "192.168.1.0/24", ExitRouteIPv4, ExitRouteIPv6}, list.Nodes[0].AvailableRoutes)
+	assert.Equal(t, []string{"10.0.0.0/24"}, list.Nodes[0].ApprovedRoutes)
+	assert.Equal(t, []string{"10.0.0.0/24"}, list.Nodes[0].SubnetRoutes)
+	assert.Empty(t, list.Nodes[1].AvailableRoutes)
+
+	resp, err := n.Get(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, list.Nodes[0], resp.Node)
+}
+
+func TestNodeResource_ApproveLegacyRoutes(t *testing.T) {
+	t.Run("enables and disables routes", func(t *testing.T) {
+		s := newLegacyRoutesServer()
+		resp, err := (&NodeResource{r: s}).ApproveRoutes(t.Context(), "1", []string{"192.168.1.0/24", ExitRouteIPv4})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"disable 10.0.0.0/24", "enable 192.168.1.0/24", "enable " + ExitRouteIPv4, "enable " + ExitRouteIPv6,
+		}, s.changes, "exit routes are approved together")
+		assert.Equal(t, []string{"192.168.1.0/24", ExitRouteIPv4, ExitRouteIPv6}, resp.Node.ApprovedRoutes)
+		assert.True(t, resp.Node.IsExitNode())
+	})
+
+	t.Run("route not advertised", func(t *testing.T) {
+		s := newLegacyRoutesServer()
+		_, err := (&NodeResource{r: s}).ApproveRoutes(t.Context(), "1", []string{"10.0.0.0/24", "172.16.0.0/12"})
+		require.ErrorIs(t, err, versions.ErrUnsupportedByServer)
+		assert.Empty(t, s.changes)
+	})
+}
@@ -0,0 +1,305 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/policy"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+var (
+	// ErrInvalidTag is returned for tags Headscale would reject: tags must start with "tag:",
+	// be lowercase and contain no whitespace.
+	ErrInvalidTag = errors.New("invalid tag")
+
+	// ErrTagNotOwned is returned when a tag being added has no entry in the tagOwners section of the policy.
+	ErrTagNotOwned = errors.New("tag not in policy tagOwners")
+
+	// ErrLastTag is returned when removing the last tag of a node from a server known to require
+	// tagged nodes to keep at least one tag.
+	ErrLastTag = errors.New("cannot remove the last tag of a tagged node")
+
+	// ErrTagAdvertised is returned when removing a tag the node advertises itself from a server
+	// before v0.28.0, which keeps advertised tags whatever tags are set through the API.
+	ErrTagAdvertised = errors.New("tag advertised by the node cannot be removed")
+
+	// ErrTagConflict is wrapped by TagConflictError.
+	ErrTagConflict = errors.New("tag update conflict")
+)
+
+// TagConflictError is returned when the tags of a node changed between reading and updating them,
+// e.g. because another client changed the node at the same time, or when the tags Headscale
+// reports after an update differ from the tags sent.
+type TagConflictError struct {
+	NodeID string
+
+	// Want are the tags the node was expected to have: the tags first read, or the tags sent.
+	// Got are the tags the server reported.
+	Want []string
+	Got  []string
+}
+
+func (e *TagConflictError) Error() string {
+	return fmt.Sprintf("%s on node %s: expected [%s], server has [%s]", ErrTagConflict, e.NodeID,
+		strings.Join(e.Want, " "), strings.Join(e.Got, " "))
+}
+
+func (e *TagConflictError) Unwrap() error {
+	return ErrTagConflict
+}
+
+// SetTagsRequest represents a request to replace the tags of a node.
+type SetTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// AddTagsRequest represents a request to replace the tags of a node.
+//
+// Deprecated: use SetTagsRequest. Despite its name, the endpoint replaces all tags.
+type AddTagsRequest = SetTagsRequest
+
+// TagChange is the result of AddTags and RemoveTags.
+type TagChange struct {
+	// Node is the node after the change.
+	Node Node
+
+	// Added and Removed are the tags that changed, sorted. Both are empty if nothing changed,
+	// in which case no update was sent.
+	Added   []string
+	Removed []string
+}
+
+// Changed reports whether any tag was added or removed.
+func (c TagChange) Changed() bool {
+	return len(c.Added) > 0 || len(c.Removed) > 0
+}
+
+// String formats the change as e.g. "+tag:db -tag:web".
+func (c TagChange) String() string {
+	parts := make([]string, 0, len(c.Added)+len(c.Removed))
+	for _, t := range c.Added {
+		parts = append(parts, "+"+t)
+	}
+	for _, t := range c.Removed {
+		parts = append(parts, "-"+t)
+	}
+	return strings.Join(parts, " ")
+}
+
+// SetTags replaces all tags of a node with tags. The tags are validated and checked against the
+// tagOwners section of the policy before sending.
+//
+// Before v0.28.0, Headscale sets the forced tags of the node, and the tags the node advertises
+// stay in effect.
+func (n *NodeResource) SetTags(ctx context.Context, id string, tags []string) (NodeResponse, error) {
+	if err := validateTags(tags); err != nil {
+		return NodeResponse{}, err
+	}
+	if err := n.checkTagOwners(ctx, tags); err != nil {
+		return NodeResponse{}, err
+	}
+	return n.setTags(ctx, id, tags)
+}
+
+// AddTags adds tags to a node, keeping its other tags. The node is read first and only the new
+// tags are checked against the policy; nothing is sent if the node has all tags already.
+func (n *NodeResource) AddTags(ctx context.Context, id string, tags []string) (TagChange, error) {
+	if err := validateTags(tags); err != nil {
+		return TagChange{}, err
+	}
+
+	return n.updateTags(ctx, id, func(current nodeTags) ([]string, error) {
+		added := slices.DeleteFunc(slices.Clone(tags), func(t string) bool { return slices.Contains(current.effective, t) })
+		return append(slices.Clone(current.set), added...), nil
+	})
+}
+
+// RemoveTags removes tags from a node, keeping its other tags. Tags the node does not have are
+// ignored; nothing is sent if it has none of them.
+//
+// Before v0.28.0, tags the node advertises itself cannot be removed through the API, and an error
+// wrapping ErrTagAdvertised is returned for them before anything is sent.
+func (n *NodeResource) RemoveTags(ctx context.Context, id string, tags []string) (TagChange, error) {
+	if err := validateTags(tags); err != nil {
+		return TagChange{}, err
+	}
+
+	return n.updateTags(ctx, id, func(current nodeTags) ([]string, error) {
+		if advertised := intersection(tags, current.advertised); len(advertised) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrTagAdvertised, strings.Join(advertised, ", "))
+		}
+		return slices.DeleteFunc(slices.Clone(current.set), func(t string) bool { return slices.Contains(tags, t) }), nil
+	})
+}
+
+// nodeTags are the tags of a node as seen by the tags endpoint.
+type nodeTags struct {
+	// effective are the tags in effect, set the tags the tags endpoint replaces, and advertised the
+	// tags the node advertises itself, which only releases before v0.28.0 report apart. There, set
+	// are the forced tags; from v0.28.0 on, set are the tags in effect.
+	effective  []string
+	set        []string
+	advertised []string
+}
+
+// tagsOf returns the tags of node as seen by the tags endpoint of the server.
+func (n *NodeResource) tagsOf(node Node) nodeTags {
+	if v := requests.ServerVersion(n.r); !v.IsZero() && !v.Supports(versions.CapabilityNodeTags) {
+		return nodeTags{effective: node.Tags, set: node.forcedTags, advertised: node.validTags}
+	}
+	return nodeTags{effective: node.Tags, set: node.Tags}
+}
+
+// updateTags reads the tags of node id, computes the tags to set with update and writes them if
+// they differ. Headscale has no conditional updates, so the node is read again just before the
+// write, and a change since the first read is returned as a *TagConflictError instead of overwritten.
+func (n *NodeResource) updateTags(ctx context.Context, id string, update func(current nodeTags) ([]string, error)) (TagChange, error) {
+	resp, err := n.Get(ctx, id)
+	if err != nil {
+		return TagChange{}, err
+	}
+	current := n.tagsOf(resp.Node)
+
+	want, err := update(current)
+	if err != nil {
+		return TagChange{}, err
+	}
+	want = uniqueTags(want)
+	change := TagChange{Node: resp.Node, Added: difference(want, current.set), Removed: difference(current.set, want)}
+	if !change.Changed() {
+		return change, nil
+	}
+
+	if v := requests.ServerVersion(n.r); len(want) == 0 && !v.IsZero() && v.Supports(versions.CapabilityNodeTags) {
+		return TagChange{}, ErrLastTag
+	}
+	if err := n.checkTagOwners(ctx, change.Added); err != nil {
+		return TagChange{}, err
+	}
+
+	latest, err := n.Get(ctx, id)
+	if err != nil {
+		return TagChange{}, err
+	}
+	if got := n.tagsOf(latest.Node).set; !sameElements(current.set, got) {
+		return TagChange{Node: latest.Node}, &TagConflictError{NodeID: id, Want: current.set, Got: got}
+	}
+
+	updated, err := n.setTags(ctx, id, want)
+	if err != nil {
+		return TagChange{}, err
+	}
+	change.Node = updated.Node
+
+	if got := n.tagsOf(updated.Node).set; !sameElements(want, got) {
+		return change, &TagConflictError{NodeID: id, Want: want, Got: got}
+	}
+	return change, nil
+}
+
+// setTags sends tags to the tags endpoint, which replaces all tags of the node.
+func (n *NodeResource) setTags(ctx context.Context, id string, tags []string) (NodeResponse, error) {
+	var node NodeResponse
+
+	if err := requests.ServerVersion(n.r).Require(versions.CapabilitySetTags); err != nil {
+		return node, err
+	}
+
+	url := n.r.BuildURL("node", id, "tags")
+	req, err := n.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{
+		Body: SetTagsRequest{Tags: tags},
+	})
+	if err != nil {
+		return node, err
+	}
+
+	err = n.r.Do(ctx, req, &node)
+	return node, err
+}
+
+// checkTagOwners returns an error wrapping ErrTagNotOwned if any of tags is missing from the
+// tagOwners section of the policy. Servers without a policy document do not restrict tags.
+func (n *NodeResource) checkTagOwners(ctx context.Context, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	p, err := policy.NewPolicyResource(n.r).Get(ctx)
+	if err != nil {
+		return fmt.Errorf("check tag owners: %w", err)
+	}
+	if strings.TrimSpace(p.Policy) == "" {
+		return nil
+	}
+
+	doc, err := policy.ParseDocument(p.Policy)
+	if err != nil {
+		return fmt.Errorf("check tag owners: %w", err)
+	}
+
+	var unowned []string
+	for _, t := range tags {
+		if !doc.HasTagOwner(t) {
+			unowned = append(unowned, t)
+		}
+	}
+	if len(unowned) > 0 {
+		return fmt.Errorf("%w: %s", ErrTagNotOwned, strings.Join(unowned, ", "))
+	}
+	return nil
+}
+
+// validateTags applies the checks Headscale applies to tags.
+func validateTags(tags []string) error {
+	for _, t := range tags {
+		switch {
+		case !strings.HasPrefix(t, tagPrefix) || t == tagPrefix:
+			return fmt.Errorf("%w %q: must start with %q", ErrInvalidTag, t, tagPrefix)
+		case strings.ToLower(t) != t:
+			return fmt.Errorf("%w %q: must be lowercase", ErrInvalidTag, t)
+		case strings.ContainsFunc(t, unicode.IsSpace):
+			return fmt.Errorf("%w %q: must not contain whitespace", ErrInvalidTag, t)
+		}
+	}
+	return nil
+}
+
+// uniqueTags returns tags without duplicates, in their original order.
+func uniqueTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// difference returns the elements of a missing from b, sorted.
+func difference(a, b []string) []string {
+	var out []string
+	for _, t := range a {
+		if !slices.Contains(b, t) && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// intersection returns the elements of a also in b, sorted.
+func intersection(a, b []string) []string {
+	return difference(a, difference(a, b))
+}
+
+// sameElements reports whether a and b hold the same elements, in any order.
+func sameElements(a, b []string) bool {
+	return len(difference(a, b)) == 0 && len(difference(b, a)) == 0
+}
@@ -0,0 +1,255 @@
+package nodes
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/compat"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/policy"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tagsPolicy = `{
+	// Only web and db may be assigned.
+	"tagOwners": {"tag:web": ["alice@"], "tag:db": ["alice@"],},
+}`
+
+// tagsServer mocks the node, policy and tags endpoints used by the tag methods.
+type tagsServer struct {
+	*requests.MockRequest
+
+	// sent are the tags sent to the tags endpoint, nil if nothing was sent.
+	sent []string
+
+	// changed, if not nil, are the tags of node 1 once it was read, as if another client changed it.
+	changed []string
+	reads   int
+
+	// advertised are the tags node 1 advertises itself. Only releases before v0.28.0 report them
+	// apart from the tags set through the API.
+	advertised []string
+	legacy     bool
+}
+
+// node returns node 1 with the tags set through the API.
+func (s *tagsServer) node(set []string) Node {
+	if !s.legacy {
+		return Node{ID: "1", Tags: set}
+	}
+	return Node{ID: "1", Tags: uniqueTags(append(slices.Clone(set), s.advertised...)), forcedTags: set, validTags: s.advertised}
+}
+
+// newTagsServer returns a server where node 1 has the tags current. The tags endpoint answers
+// with the tags reply returns for the tags sent.
+func newTagsServer(t *testing.T, version versions.ServerVersion, current []string, reply func(sent []string) []string) *tagsServer {
+	t.Helper()
+
+	s := &tagsServer{MockRequest: new(requests.MockRequest), legacy: !version.IsZero() && !version.Supports(versions.CapabilityNodeTags)}
+	s.On("ServerVersion").Return(version).Maybe()
+
+	recordTags := func(opt requests.RequestOptions) {
+		if body, ok := opt.Body.(SetTagsRequest); ok {
+			s.sent = body.Tags
+		}
+	}
+
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"node", "1"}, nil, func(v any) {
+		tags := current
+		if s.reads > 0 && s.changed != nil {
+			tags = s.changed
+		}
+		s.reads++
+		*v.(*NodeResponse) = NodeResponse{Node: s.node(tags)} //nolint:errcheck // reason: type assertion in test
+	})
+	testutil.MockEndpoint(s.MockRequest, http.MethodGet, []any{"policy"}, nil, func(v any) {
+		*v.(*policy.Policy) = policy.Policy{Policy: tagsPolicy} //nolint:errcheck // reason: type assertion in test
+	})
+	testutil.MockEndpoint(s.MockRequest, http.MethodPost, []any{"node", "1", "tags"}, recordTags, func(v any) {
+		*v.(*NodeResponse) = NodeResponse{Node: s.node(reply(s.sent))} //nolint:errcheck // reason: type assertion in test
+	})
+	return s
+}
+
+func echoTags(sent []string) []string { return sent }
+
+func TestNodeResource_AddTags(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{}, []string{"tag:web"}, echoTags)
+	n := &NodeResource{r: s}
+
+	change, err := n.AddTags(t.Context(), "1", []string{"tag:db", "tag:web", "tag:db"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag:web", "tag:db"}, s.sent, "existing tags are kept")
+	assert.Equal(t, []string{"tag:db"}, change.Added)
+	assert.Empty(t, change.Removed)
+	assert.Equal(t, "+tag:db", change.String())
+	assert.Equal(t, []string{"tag:web", "tag:db"}, change.Node.Tags)
+}
+
+func TestNodeResource_AddTags_Unchanged(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{}, []string{"tag:web", "tag:legacy"}, echoTags)
+	n := &NodeResource{r: s}
+
+	change, err := n.AddTags(t.Context(), "1", []string{"tag:web"})
+	require.NoError(t, err)
+	assert.False(t, change.Changed())
+	assert.Nil(t, s.sent)
+	s.AssertNotCalled(t, "BuildURL", "policy")
+}
+
+func TestNodeResource_AddTags_NotOwned(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{}, []string{"tag:legacy"}, echoTags)
+	n := &NodeResource{r: s}
+
+	_, err := n.AddTags(t.Context(), "1", []string{"tag:web", "tag:cache"})
+	require.ErrorIs(t, err, ErrTagNotOwned)
+	assert.Contains(t, err.Error(), "tag:cache")
+	assert.NotContains(t, err.Error(), "tag:legacy", "only added tags are checked")
+	assert.Nil(t, s.sent)
+}
+
+func TestNodeResource_AddTags_Conflict(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{}, []string{"tag:web"}, func(sent []string) []string {
+		return append(sent, "tag:other")
+	})
+	n := &NodeResource{r: s}
+
+	change, err := n.AddTags(t.Context(), "1", []string{"tag:db"})
+	var conflict *TagConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.ErrorIs(t, err, ErrTagConflict)
+	assert.Equal(t, []string{"tag:web", "tag:db"}, conflict.Want)
+	assert.Equal(t, []string{"tag:web", "tag:db", "tag:other"}, conflict.Got)
+	assert.Equal(t, []string{"tag:db"}, change.Added)
+}
+
+func TestNodeResource_AddTags_ConcurrentChange(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{}, []string{"tag:web"}, echoTags)
+	s.changed = []string{"tag:web", "tag:legacy"}
+	n := &NodeResource{r: s}
+
+	change, err := n.AddTags(t.Context(), "1", []string{"tag:db"})
+	var conflict *TagConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, []string{"tag:web"}, conflict.Want)
+	assert.Equal(t, []string{"tag:web", "tag:legacy"}, conflict.Got)
+	assert.Equal(t, []string{"tag:web", "tag:legacy"}, change.Node.Tags)
+	assert.Nil(t, s.sent, "the concurrent change is not overwritten")
+}
+
+func TestNodeResource_RemoveTags(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{}, []string{"tag:web", "tag:db", "tag:legacy"}, echoTags)
+	n := &NodeResource{r: s}
+
+	change, err := n.RemoveTags(t.Context(), "1", []string{"tag:db", "tag:cache"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag:web", "tag:legacy"}, s.sent)
+	assert.Empty(t, change.Added)
+	assert.Equal(t, []string{"tag:db"}, change.Removed)
+	s.AssertNotCalled(t, "BuildURL", "policy")
+}
+
+func TestNodeResource_RemoveTags_Last(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{Minor: 28}, []string{"tag:web"}, echoTags)
+	n := &NodeResource{r: s}
+
+	_, err := n.RemoveTags(t.Context(), "1", []string{"tag:web"})
+	require.ErrorIs(t, err, ErrLastTag)
+	assert.Nil(t, s.sent)
+
+	s = newTagsServer(t, versions.ServerVersion{Minor: 27}, []string{"tag:web"}, echoTags)
+	n = &NodeResource{r: s}
+
+	change, err := n.RemoveTags(t.Context(), "1", []string{"tag:web"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, s.sent)
+	assert.Equal(t, "-tag:web", change.String())
+
+	s = newTagsServer(t, versions.ServerVersion{}, []string{"tag:web"}, echoTags)
+	n = &NodeResource{r: s}
+
+	_, err = n.RemoveTags(t.Context(), "1", []string{"tag:web"})
+	require.NoError(t, err, "the server decides when the version is unknown")
+	assert.Equal(t, []string{}, s.sent)
+}
+
+func TestNodeResource_AddTags_BeforeV028(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{Minor: 27}, []string{"tag:web"}, echoTags)
+	s.advertised = []string{"tag:db", "tag:legacy"}
+	n := &NodeResource{r: s}
+
+	change, err := n.AddTags(t.Context(), "1", []string{"tag:db"})
+	require.NoError(t, err)
+	assert.False(t, change.Changed(), "advertised tags are in effect already")
+	assert.Nil(t, s.sent)
+
+	change, err = n.AddTags(t.Context(), "1", []string{"tag:web", "tag:cache"})
+	require.ErrorIs(t, err, ErrTagNotOwned)
+	assert.NotContains(t, err.Error(), "tag:legacy", "advertised tags are not checked")
+
+	s.advertised = []string{"tag:legacy"}
+	change, err = n.AddTags(t.Context(), "1", []string{"tag:db"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag:web", "tag:db"}, s.sent, "only forced tags are sent")
+	assert.Equal(t, []string{"tag:db"}, change.Added)
+	assert.Equal(t, []string{"tag:web", "tag:db", "tag:legacy"}, change.Node.Tags)
+}
+
+func TestNodeResource_RemoveTags_BeforeV028(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{Minor: 27}, []string{"tag:web", "tag:db"}, echoTags)
+	s.advertised = []string{"tag:legacy"}
+	n := &NodeResource{r: s}
+
+	_, err := n.RemoveTags(t.Context(), "1", []string{"tag:db", "tag:legacy"})
+	require.ErrorIs(t, err, ErrTagAdvertised)
+	assert.NotErrorIs(t, err, ErrTagConflict)
+	assert.Contains(t, err.Error(), "tag:legacy")
+	assert.Nil(t, s.sent)
+
+	change, err := n.RemoveTags(t.Context(), "1", []string{"tag:db"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag:web"}, s.sent, "advertised tags are not forced")
+	assert.Equal(t, []string{"tag:db"}, change.Removed)
+	assert.Equal(t, []string{"tag:web", "tag:legacy"}, change.Node.Tags)
+}
+
+func TestNodeResource_SetTags(t *testing.T) {
+	s := newTagsServer(t, versions.ServerVersion{}, []string{"tag:legacy"}, echoTags)
+	n := &NodeResource{r: s}
+
+	resp, err := n.SetTags(t.Context(), "1", []string{"tag:db"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag:db"}, s.sent)
+	assert.Equal(t, []string{"tag:db"}, resp.Node.Tags)
+
+	_, err = n.SetTags(t.Context(), "1", []string{"tag:cache"})
+	require.ErrorIs(t, err, ErrTagNotOwned)
+}
+
+func TestNode_UnmarshalJSON_BeforeV028(t *testing.T) {
+	var resp NodeResponse
+	err := compat.ForVersion(versions.ServerVersion{Minor: 27}).Decode([]byte(`{"node":{
+		"id": "1",
+		"forcedTags": ["tag:web"],
+		"validTags": ["tag:db"],
+		"invalidTags": ["tag:cache"]
+	}}`), &resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tag:web", "tag:db"}, resp.Node.Tags)
+	assert.Equal(t, []string{"tag:web"}, resp.Node.forcedTags)
+	assert.Equal(t, []string{"tag:db"}, resp.Node.validTags)
+	assert.Nil(t, resp.Node.Extra)
+}
+
+func TestValidateTags(t *testing.T) {
+	require.NoError(t, validateTags([]string{"tag:web", "tag:db-1"}))
+
+	for _, tag := range []string{"web", "tag:", "tag:Web", "tag:web server"} {
+		require.ErrorIs(t, validateTags([]string{tag}), ErrInvalidTag, tag)
+	}
+}
@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Document is the part of a policy document the client inspects.
+type Document struct {
+	// TagOwners maps each tag to the users and groups allowed to assign it.
+	TagOwners map[string][]string `json:"tagOwners"`
+}
+
+// ParseDocument parses a policy in JSON or HuJSON (JSON with comments and trailing commas), as
+// returned by Get.
+func ParseDocument(policy string) (Document, error) {
+	var doc Document
+	if err := json.Unmarshal(standardize([]byte(policy)), &doc); err != nil {
+		return doc, fmt.Errorf("parse policy: %w", err)
+	}
+	return doc, nil
+}
+
+// HasTagOwner reports whether tag is listed in the tagOwners section.
+func (d Document) HasTagOwner(tag string) bool {
+	_, ok := d.TagOwners[tag]
+	return ok
+}
+
+// standardize turns HuJSON into JSON by blanking out comments and trailing commas. Offsets are
+// kept, so that JSON syntax errors point at the original text.
+func standardize(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	// lastComma is the offset of a comma not yet followed by a value, or -1.
+	lastComma := -1
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			lastComma = -1
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := i
+			for end < len(out) && out[end] != '\n' {
+				end++
+			}
+			blank(i, end)
+			i = end - 1
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for end+1 < len(out) && (out[end] != '*' || out[end+1] != '/') {
+				end++
+			}
+			end = min(end+2, len(out))
+			blank(i, end)
+			i = end - 1
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			lastComma = -1
+		}
+	}
+	return out
+}
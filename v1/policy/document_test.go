@@ -0,0 +1,30 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDocument(t *testing.T) {
+	doc, err := ParseDocument(`{
+	// Tags and their owners.
+	"tagOwners": {
+		"tag:web": ["group:ops", "alice@"], /* owned by ops */
+		"tag:db": ["bob@",],
+	},
+	"acls": [{"action": "accept", "src": ["*"], "dst": ["*:*"]}], // allow all
+	"hosts": {"url": "http://example.com/a,]"},
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"tag:web": {"group:ops", "alice@"},
+		"tag:db":  {"bob@"},
+	}, doc.TagOwners)
+	assert.True(t, doc.HasTagOwner("tag:db"))
+	assert.False(t, doc.HasTagOwner("tag:cache"))
+
+	_, err = ParseDocument(`{"tagOwners": [}`)
+	require.Error(t, err)
+}
@@ -3,8 +3,10 @@ package policy
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
+	"github.com/hibare/headscale-client-go/compat"
 	"github.com/hibare/headscale-client-go/requests"
 )
 
@@ -26,8 +28,28 @@ func NewPolicyResource(r requests.RequestInterface) *PolicyResource {
 
 // Policy represents a policy in Headscale.
 type Policy struct {
-	Policy    string `json:"policy"`
+	Policy    string `json:"policy" schema:"required"`
 	UpdatedAt string `json:"updatedAt"`
+
+	// Extra holds response members without a field, e.g. those added by newer Headscale releases.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a Policy, keeping unknown members in Extra.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	type policy Policy
+	extra, err := compat.UnmarshalExtra(data, (*policy)(p))
+	if err != nil {
+		return err
+	}
+	p.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a Policy, including the members in Extra.
+func (p Policy) MarshalJSON() ([]byte, error) {
+	type policy Policy
+	return compat.MarshalExtra(policy(p), p.Extra)
 }
 
 // UpdatePolicyRequest represents a request to update the policy.
@@ -3,16 +3,24 @@ package preauthkeys
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
 	"net/http"
+	"slices"
 	"time"
 
+	"github.com/hibare/headscale-client-go/compat"
+	"github.com/hibare/headscale-client-go/iterate"
 	"github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
 )
 
 // PreAuthKeyResourceInterface is an interface for managing pre-auth keys in Headscale.
 type PreAuthKeyResourceInterface interface {
 	List(ctx context.Context) (PreAuthKeysResponse, error)
+	All(ctx context.Context) iter.Seq2[PreAuthKey, error]
 	Create(ctx context.Context, createPreAuthKeyRequest CreatePreAuthKeyRequest) (PreAuthKeyResponse, error)
 	Expire(ctx context.Context, id string) error
 	Delete(ctx context.Context, id string) error
@@ -30,7 +38,7 @@ func NewPreAuthKeyResource(r requests.RequestInterface) *PreAuthKeyResource {
 
 // PreAuthKey represents a pre-auth key in Headscale.
 type PreAuthKey struct {
-	ID         string     `json:"id"`
+	ID         string     `json:"id" schema:"required"`
 	User       users.User `json:"user,omitempty"`
 	Key        string     `json:"key,omitempty"`
 	Reusable   bool       `json:"reusable"`
@@ -39,6 +47,26 @@ type PreAuthKey struct {
 	Expiration time.Time  `json:"expiration"`
 	CreatedAt  time.Time  `json:"createdAt"`
 	ACLTags    []string   `json:"aclTags"`
+
+	// Extra holds response members without a field, e.g. those added by newer Headscale releases.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a PreAuthKey, keeping unknown members in Extra.
+func (p *PreAuthKey) UnmarshalJSON(data []byte) error {
+	type preAuthKey PreAuthKey
+	extra, err := compat.UnmarshalExtra(data, (*preAuthKey)(p))
+	if err != nil {
+		return err
+	}
+	p.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a PreAuthKey, including the members in Extra.
+func (p PreAuthKey) MarshalJSON() ([]byte, error) {
+	type preAuthKey PreAuthKey
+	return compat.MarshalExtra(preAuthKey(p), p.Extra)
 }
 
 // PreAuthKeysResponse represents a list of pre-auth keys response from the API.
@@ -47,11 +75,54 @@ type PreAuthKeysResponse struct {
 }
 
 // List returns a list of pre-auth keys from the Headscale.
+//
+// Servers before v0.28.0 list the pre-auth keys of one user at a time, so the users are listed
+// first and their keys are fetched in turn.
 func (p *PreAuthKeyResource) List(ctx context.Context) (PreAuthKeysResponse, error) {
+	if !requests.ServerVersion(p.r).Supports(versions.CapabilityPreAuthKeyListAll) {
+		return p.listPerUser(ctx)
+	}
+	return p.list(ctx, nil)
+}
+
+// listPerUser lists the pre-auth keys of every user.
+func (p *PreAuthKeyResource) listPerUser(ctx context.Context) (PreAuthKeysResponse, error) {
+	var keys PreAuthKeysResponse
+
+	userList, err := users.NewUserResource(p.r).List(ctx, users.UserListFilter{})
+	if err != nil {
+		return keys, err
+	}
+
+	byID := requests.ServerVersion(p.r).Supports(versions.CapabilityPreAuthKeyUserID)
+	for _, u := range userList.Users {
+		user := u.Name
+		if byID {
+			user = u.ID
+		}
+
+		resp, err := p.list(ctx, map[string]any{"user": user})
+		if err != nil {
+			return PreAuthKeysResponse{}, fmt.Errorf("list pre-auth keys of user %s: %w", user, err)
+		}
+		// Names are not unique across OIDC providers, so a key may be listed for several users.
+		for _, key := range resp.PreAuthKeys {
+			if !slices.ContainsFunc(keys.PreAuthKeys, func(k PreAuthKey) bool { return k.ID == key.ID }) {
+				keys.PreAuthKeys = append(keys.PreAuthKeys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// list sends a single list request with queryParams.
+func (p *PreAuthKeyResource) list(ctx context.Context, queryParams map[string]any) (PreAuthKeysResponse, error) {
 	var keys PreAuthKeysResponse
 
 	url := p.r.BuildURL("preauthkey")
-	req, err := p.r.BuildRequest(ctx, http.MethodGet, url, requests.RequestOptions{})
+	req, err := p.r.BuildRequest(ctx, http.MethodGet, url, requests.RequestOptions{
+		QueryParams: queryParams,
+	})
 	if err != nil {
 		return keys, err
 	}
@@ -60,6 +131,14 @@ func (p *PreAuthKeyResource) List(ctx context.Context) (PreAuthKeysResponse, err
 	return keys, err
 }
 
+// All returns an iterator over all pre-auth keys. The list is fetched when iteration starts.
+func (p *PreAuthKeyResource) All(ctx context.Context) iter.Seq2[PreAuthKey, error] {
+	return iterate.Fetch(func() ([]PreAuthKey, error) {
+		resp, err := p.List(ctx)
+		return resp.PreAuthKeys, err
+	})
+}
+
 // CreatePreAuthKeyRequest represents a request to create a pre-auth key.
 type CreatePreAuthKeyRequest struct {
 	User       string    `json:"user"`
@@ -96,7 +175,14 @@ type ExpirePreAuthKeyRequest struct {
 }
 
 // Expire expires a pre-auth key in Headscale.
+//
+// Servers before v0.28.0 expire keys by user and key rather than ID, and yield an error wrapping
+// versions.ErrUnsupportedByServer.
 func (p *PreAuthKeyResource) Expire(ctx context.Context, id string) error {
+	if err := requests.ServerVersion(p.r).Require(versions.CapabilityPreAuthKeyExpireByID); err != nil {
+		return err
+	}
+
 	url := p.r.BuildURL("preauthkey", "expire")
 	req, err := p.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{
 		Body: ExpirePreAuthKeyRequest{ID: id},
@@ -110,6 +196,10 @@ func (p *PreAuthKeyResource) Expire(ctx context.Context, id string) error {
 
 // Delete removes a pre-auth key from the Headscale.
 func (p *PreAuthKeyResource) Delete(ctx context.Context, id string) error {
+	if err := requests.ServerVersion(p.r).Require(versions.CapabilityPreAuthKeyDelete); err != nil {
+		return err
+	}
+
 	url := p.r.BuildURL("preauthkey")
 	req, err := p.r.BuildRequest(ctx, http.MethodDelete, url, requests.RequestOptions{
 		QueryParams: map[string]any{"id": id},
@@ -2,6 +2,7 @@ package preauthkeys
 
 import (
 	"context"
+	"iter"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -17,6 +18,12 @@ func (m *MockPreAuthKeyResource) List(ctx context.Context) (PreAuthKeysResponse,
 	return args.Get(0).(PreAuthKeysResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// All returns a mock iterator over pre-auth keys.
+func (m *MockPreAuthKeyResource) All(ctx context.Context) iter.Seq2[PreAuthKey, error] {
+	args := m.Called(ctx)
+	return args.Get(0).(iter.Seq2[PreAuthKey, error]) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // Create creates a mock pre-auth key from the Headscale.
 func (m *MockPreAuthKeyResource) Create(ctx context.Context, createPreAuthKeyRequest CreatePreAuthKeyRequest) (PreAuthKeyResponse, error) {
 	args := m.Called(ctx, createPreAuthKeyRequest)
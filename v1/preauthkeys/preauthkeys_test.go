@@ -9,6 +9,10 @@ import (
 	"github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/v1/testutil"
 	"github.com/hibare/headscale-client-go/v1/users"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPreAuthKeyResource_List(t *testing.T) {
@@ -31,6 +35,40 @@ func TestPreAuthKeyResource_List(t *testing.T) {
 	})
 }
 
+func TestPreAuthKeyResource_List_PerUser(t *testing.T) {
+	for _, tt := range []struct {
+		version  versions.ServerVersion
+		wantUser []any
+	}{
+		{version: versions.ServerVersion{Minor: 25}, wantUser: []any{"alice", "bob"}},
+		{version: versions.ServerVersion{Minor: 27}, wantUser: []any{"1", "2"}},
+	} {
+		t.Run(tt.version.String(), func(t *testing.T) {
+			mockReq := new(requests.MockRequest)
+			mockReq.On("ServerVersion").Return(tt.version)
+
+			testutil.MockEndpoint(mockReq, http.MethodGet, []any{"user"}, nil, func(v any) {
+				*v.(*users.UsersResponse) = users.UsersResponse{Users: []users.User{ //nolint:errcheck // reason: type assertion in test
+					{ID: "1", Name: "alice"}, {ID: "2", Name: "bob"},
+				}}
+			})
+			var sent []any
+			testutil.MockEndpoint(mockReq, http.MethodGet, []any{"preauthkey"}, func(opt requests.RequestOptions) {
+				sent = append(sent, opt.QueryParams["user"])
+			}, func(v any) {
+				id := map[any]string{"alice": "7", "1": "7", "bob": "8", "2": "8"}[sent[len(sent)-1]]
+				*v.(*PreAuthKeysResponse) = PreAuthKeysResponse{PreAuthKeys: []PreAuthKey{{ID: id}, {ID: "9"}}} //nolint:errcheck // reason: type assertion in test
+			})
+
+			p := &PreAuthKeyResource{r: mockReq}
+			resp, err := p.List(t.Context())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantUser, sent)
+			assert.Equal(t, []PreAuthKey{{ID: "7"}, {ID: "9"}, {ID: "8"}}, resp.PreAuthKeys, "keys listed for several users are kept once")
+		})
+	}
+}
+
 func TestPreAuthKeyResource_Create(t *testing.T) {
 	request := CreatePreAuthKeyRequest{
 		User:       "testuser",
@@ -70,6 +108,15 @@ func TestPreAuthKeyResource_Expire(t *testing.T) {
 		err := p.Expire(ctx, id)
 		return struct{}{}, err
 	})
+
+	t.Run("unsupported by server", func(t *testing.T) {
+		mockReq := new(requests.MockRequest)
+		mockReq.On("ServerVersion").Return(versions.ServerVersion{Minor: 27})
+
+		p := &PreAuthKeyResource{r: mockReq}
+		require.ErrorIs(t, p.Expire(t.Context(), id), versions.ErrUnsupportedByServer)
+		mockReq.AssertNotCalled(t, "Do", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestPreAuthKeyResource_Delete(t *testing.T) {
@@ -0,0 +1,299 @@
+// Package registry manages Headscale clients for several named servers.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/hibare/headscale-client-go/v1/client"
+	"github.com/hibare/headscale-client-go/v1/nodes"
+)
+
+var (
+	// ErrUnknownProfile is returned when a profile name is not registered.
+	ErrUnknownProfile = errors.New("unknown profile")
+
+	// ErrDuplicateProfile is returned when two profiles share a name.
+	ErrDuplicateProfile = errors.New("duplicate profile")
+)
+
+// Registry holds named server profiles and lazily creates one client per profile.
+type Registry struct {
+	mu        sync.Mutex
+	profiles  map[string]client.Profile
+	clients   map[string]*profileClient
+	opt       client.ClientOptions
+	newClient func(cfg client.Config, opt client.ClientOptions) (client.ClientInterface, error)
+}
+
+// profileClient is the client of one profile. Its mutex is held while the client is created, so
+// a slow profile does not block the others.
+type profileClient struct {
+	mu     sync.Mutex
+	client client.ClientInterface
+}
+
+// New creates a new Registry for the given profiles. Each client gets its own copy of opt.
+func New(profiles []client.Profile, opt client.ClientOptions) (*Registry, error) {
+	r := &Registry{
+		profiles:  make(map[string]client.Profile, len(profiles)),
+		clients:   make(map[string]*profileClient, len(profiles)),
+		opt:       opt,
+		newClient: client.NewFromConfig,
+	}
+
+	for _, p := range profiles {
+		if _, ok := r.profiles[p.Name]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateProfile, p.Name)
+		}
+		r.profiles[p.Name] = p
+	}
+
+	return r, nil
+}
+
+// Load creates a new Registry from the profiles defined in the config file at path.
+func Load(path string, opt client.ClientOptions) (*Registry, error) {
+	profiles, err := client.LoadProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(profiles, opt)
+}
+
+// Names returns the names of all profiles, sorted.
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.profiles))
+}
+
+// Profile returns the profile with the given name.
+func (r *Registry) Profile(name string) (client.Profile, bool) {
+	p, ok := r.profiles[name]
+	return p, ok
+}
+
+// Select returns the sorted names of the profiles matching selector.
+func (r *Registry) Select(selector Selector) []string {
+	var names []string
+	for _, name := range r.Names() {
+		if selector == nil || selector(r.profiles[name]) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Client returns the client for the named profile, creating it on first use.
+func (r *Registry) Client(name string) (client.ClientInterface, error) {
+	p, ok := r.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+
+	r.mu.Lock()
+	pc, ok := r.clients[name]
+	if !ok {
+		pc = &profileClient{}
+		r.clients[name] = pc
+	}
+	r.mu.Unlock()
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.client != nil {
+		return pc.client, nil
+	}
+
+	c, err := r.newClient(p.Config, cloneOptions(r.opt))
+	if err != nil {
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	pc.client = c
+
+	return c, nil
+}
+
+// cloneOptions returns a copy of opt that shares no pointers with it, so that a client cannot
+// change the options of the clients of other profiles. The HTTP client is copied but shares its
+// transport. Interface values such as the logger and credentials are shared.
+func cloneOptions(opt client.ClientOptions) client.ClientOptions {
+	out := opt
+	out.HTTPClient = clonePtr(opt.HTTPClient)
+	out.UserAgent = clonePtr(opt.UserAgent)
+	out.LogLevel = clonePtr(opt.LogLevel)
+	out.Timeout = clonePtr(opt.Timeout)
+	out.ProxyURL = clonePtr(opt.ProxyURL)
+	out.UnixSocket = clonePtr(opt.UnixSocket)
+	out.ProbeInterval = clonePtr(opt.ProbeInterval)
+	out.ServerVersion = clonePtr(opt.ServerVersion)
+	if opt.TLS != nil {
+		tls := *opt.TLS
+		tls.CAPEM = slices.Clone(tls.CAPEM)
+		tls.CertPEM = slices.Clone(tls.CertPEM)
+		tls.KeyPEM = slices.Clone(tls.KeyPEM)
+		out.TLS = &tls
+	}
+	return out
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil if p is nil.
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// Selector reports whether a profile should be included in a fan-out.
+type Selector func(p client.Profile) bool
+
+// ByName selects the profiles with the given names.
+func ByName(names ...string) Selector {
+	return func(p client.Profile) bool {
+		return slices.Contains(names, p.Name)
+	}
+}
+
+// ByLabels selects the profiles carrying all the given labels.
+func ByLabels(labels map[string]string) Selector {
+	return func(p client.Profile) bool {
+		for k, v := range labels {
+			if p.Labels[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Result is the outcome of an operation on one server.
+type Result[T any] struct {
+	Profile string
+	Labels  map[string]string
+	Value   T
+	Err     error
+}
+
+// FanOut runs fn concurrently against every profile in names and returns one Result per profile,
+// in the order of names. Pass nil names to run against all profiles.
+func FanOut[T any](
+	ctx context.Context,
+	r *Registry,
+	names []string,
+	fn func(ctx context.Context, profile string, c client.ClientInterface) (T, error),
+) []Result[T] {
+	if names == nil {
+		names = r.Names()
+	}
+
+	results := make([]Result[T], len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i] = Result[T]{Profile: name}
+		if p, ok := r.Profile(name); ok {
+			results[i].Labels = p.Labels
+		}
+
+		wg.Go(func() {
+			c, err := r.Client(name)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+			results[i].Value, results[i].Err = fn(ctx, name, c)
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Errors joins the errors of results, each prefixed with its profile name. It returns nil if all succeeded.
+func Errors[T any](results []Result[T]) error {
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.Profile, res.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Node is a node together with the server it was listed from.
+type Node struct {
+	nodes.Node
+
+	Server string            `json:"server"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// MarshalJSON encodes the node with its server and labels. Without it, the MarshalJSON method of
+// the embedded nodes.Node would encode the node alone.
+func (n Node) MarshalJSON() ([]byte, error) {
+	node := n.Node
+	node.Extra = maps.Clone(n.Extra)
+	if node.Extra == nil {
+		node.Extra = map[string]json.RawMessage{}
+	}
+
+	var err error
+	if node.Extra["server"], err = json.Marshal(n.Server); err != nil {
+		return nil, err
+	}
+	if len(n.Labels) > 0 {
+		if node.Extra["labels"], err = json.Marshal(n.Labels); err != nil {
+			return nil, err
+		}
+	}
+	return node.MarshalJSON()
+}
+
+// UnmarshalJSON decodes a node encoded by MarshalJSON.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	if err := n.Node.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	var meta struct {
+		Server string            `json:"server"`
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+	n.Server, n.Labels = meta.Server, meta.Labels
+
+	delete(n.Extra, "server")
+	delete(n.Extra, "labels")
+	if len(n.Extra) == 0 {
+		n.Extra = nil
+	}
+	return nil
+}
+
+// ListNodes lists the nodes of every profile in names (all profiles if nil), labelled with their server.
+//
+// Nodes from servers that answered are returned even if other servers failed; the failures are
+// reported in the returned error.
+func ListNodes(ctx context.Context, r *Registry, names []string, filter nodes.NodeListFilter) ([]Node, error) {
+	results := FanOut(ctx, r, names, func(ctx context.Context, _ string, c client.ClientInterface) (nodes.NodesResponse, error) {
+		return c.Nodes().List(ctx, filter)
+	})
+
+	var out []Node
+	for _, res := range results {
+		for _, n := range res.Value.Nodes {
+			out = append(out, Node{Node: n, Server: res.Profile, Labels: res.Labels})
+		}
+	}
+
+	return out, Errors(results)
+}
@@ -0,0 +1,221 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibare/headscale-client-go/v1/client"
+	"github.com/hibare/headscale-client-go/v1/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(t *testing.T, clients map[string]client.ClientInterface) (*Registry, *atomic.Int32) {
+	t.Helper()
+	profiles := []client.Profile{
+		{Name: "prod", Config: client.Config{Address: "https://prod"}, Labels: map[string]string{"env": "prod"}},
+		{Name: "staging", Config: client.Config{Address: "https://staging"}, Labels: map[string]string{"env": "staging"}},
+		{Name: "customer-a", Config: client.Config{Address: "https://a"}, Labels: map[string]string{"env": "prod", "customer": "a"}},
+	}
+	r, err := New(profiles, client.ClientOptions{})
+	require.NoError(t, err)
+
+	var created atomic.Int32
+	r.newClient = func(cfg client.Config, _ client.ClientOptions) (client.ClientInterface, error) {
+		created.Add(1)
+		for _, p := range profiles {
+			if p.Config.Address == cfg.Address {
+				if c, ok := clients[p.Name]; ok {
+					return c, nil
+				}
+			}
+		}
+		return nil, errors.New("no client")
+	}
+	return r, &created
+}
+
+func mockNodesClient(list nodes.NodesResponse, err error) *client.MockClient {
+	nodeMock := new(nodes.MockNodeResource)
+	nodeMock.On("List", mock.Anything, nodes.NodeListFilter{}).Return(list, err)
+	c := new(client.MockClient)
+	c.On("Nodes").Return(nodeMock)
+	return c
+}
+
+func TestNew_DuplicateProfile(t *testing.T) {
+	_, err := New([]client.Profile{{Name: "a"}, {Name: "a"}}, client.ClientOptions{})
+	require.ErrorIs(t, err, ErrDuplicateProfile)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profiles:
+  prod:
+    address: https://hs.prod.example.com
+    api_key: prod-key
+    labels:
+      env: prod
+`), 0o600))
+
+	r, err := Load(path, client.ClientOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, r.Names())
+
+	c, err := r.Client("prod")
+	require.NoError(t, err)
+	again, err := r.Client("prod")
+	require.NoError(t, err)
+	assert.Same(t, c, again)
+}
+
+func TestRegistry_ClientIsLazy(t *testing.T) {
+	r, created := newTestRegistry(t, map[string]client.ClientInterface{"prod": new(client.MockClient)})
+	assert.Equal(t, int32(0), created.Load())
+
+	_, err := r.Client("prod")
+	require.NoError(t, err)
+	_, err = r.Client("prod")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), created.Load())
+
+	_, err = r.Client("unknown")
+	require.ErrorIs(t, err, ErrUnknownProfile)
+}
+
+func TestRegistry_ClientOptionsPerProfile(t *testing.T) {
+	timeout := time.Minute
+	r, err := New([]client.Profile{
+		{Name: "prod", Config: client.Config{Address: "https://prod"}},
+		{Name: "staging", Config: client.Config{Address: "https://staging"}},
+	}, client.ClientOptions{Timeout: &timeout, TLS: &client.TLSOptions{ServerName: "headscale"}})
+	require.NoError(t, err)
+
+	var seen []client.ClientOptions
+	r.newClient = func(_ client.Config, opt client.ClientOptions) (client.ClientInterface, error) {
+		seen = append(seen, opt)
+		*opt.Timeout = time.Second
+		opt.TLS.ServerName = "changed"
+		return new(client.MockClient), nil
+	}
+
+	for _, name := range r.Names() {
+		_, err = r.Client(name)
+		require.NoError(t, err)
+	}
+	require.Len(t, seen, 2)
+	assert.Equal(t, time.Minute, timeout)
+	assert.NotSame(t, seen[0].Timeout, seen[1].Timeout)
+	assert.Equal(t, "headscale", r.opt.TLS.ServerName)
+}
+
+func TestRegistry_ClientBuildsProfilesConcurrently(t *testing.T) {
+	r, err := New([]client.Profile{
+		{Name: "slow", Config: client.Config{Address: "https://slow"}},
+		{Name: "fast", Config: client.Config{Address: "https://fast"}},
+	}, client.ClientOptions{})
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	r.newClient = func(cfg client.Config, _ client.ClientOptions) (client.ClientInterface, error) {
+		if cfg.Address == "https://slow" {
+			close(started)
+			<-release
+		}
+		return new(client.MockClient), nil
+	}
+
+	slow := make(chan error)
+	go func() {
+		_, err := r.Client("slow")
+		slow <- err
+	}()
+	<-started
+
+	_, err = r.Client("fast")
+	require.NoError(t, err, "a slow profile must not block the others")
+	close(release)
+	require.NoError(t, <-slow)
+}
+
+func TestRegistry_Select(t *testing.T) {
+	r, _ := newTestRegistry(t, nil)
+	assert.Equal(t, []string{"customer-a", "prod", "staging"}, r.Select(nil))
+	assert.Equal(t, []string{"customer-a", "prod"}, r.Select(ByLabels(map[string]string{"env": "prod"})))
+	assert.Equal(t, []string{"staging"}, r.Select(ByName("staging", "missing")))
+}
+
+func TestFanOut(t *testing.T) {
+	r, _ := newTestRegistry(t, map[string]client.ClientInterface{
+		"prod":    new(client.MockClient),
+		"staging": new(client.MockClient),
+	})
+
+	results := FanOut(t.Context(), r, nil, func(_ context.Context, profile string, _ client.ClientInterface) (string, error) {
+		if profile == "staging" {
+			return "", errors.New("unreachable")
+		}
+		return "hello " + profile, nil
+	})
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "customer-a", results[0].Profile)
+	require.Error(t, results[0].Err, "client construction errors are reported per profile")
+	assert.Equal(t, "hello prod", results[1].Value)
+	assert.Equal(t, map[string]string{"env": "prod"}, results[1].Labels)
+	require.EqualError(t, results[2].Err, "unreachable")
+
+	err := Errors(results)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "staging: unreachable")
+	assert.Contains(t, err.Error(), "customer-a: ")
+	assert.NotContains(t, err.Error(), "prod:")
+}
+
+func TestListNodes(t *testing.T) {
+	r, _ := newTestRegistry(t, map[string]client.ClientInterface{
+		"prod":    mockNodesClient(nodes.NodesResponse{Nodes: []nodes.Node{{ID: "1"}, {ID: "2"}}}, nil),
+		"staging": mockNodesClient(nodes.NodesResponse{Nodes: []nodes.Node{{ID: "1"}}}, nil),
+	})
+
+	got, err := ListNodes(t.Context(), r, []string{"prod", "staging"}, nodes.NodeListFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, "prod", got[0].Server)
+	assert.Equal(t, "2", got[1].ID)
+	assert.Equal(t, "staging", got[2].Server)
+	assert.Equal(t, map[string]string{"env": "staging"}, got[2].Labels)
+
+	got, err = ListNodes(t.Context(), r, nil, nodes.NodeListFilter{})
+	require.Error(t, err)
+	assert.Len(t, got, 3, "nodes from healthy servers are still returned")
+}
+
+func TestNode_JSONRoundTrip(t *testing.T) {
+	node := Node{
+		Node:   nodes.Node{ID: "1", Name: "web", Extra: map[string]json.RawMessage{"zone": json.RawMessage(`"eu"`)}},
+		Server: "prod",
+		Labels: map[string]string{"env": "prod"},
+	}
+
+	data, err := json.Marshal(node)
+	require.NoError(t, err)
+
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.JSONEq(t, `"prod"`, string(fields["server"]))
+	assert.JSONEq(t, `{"env":"prod"}`, string(fields["labels"]))
+	assert.JSONEq(t, `"web"`, string(fields["name"]))
+
+	var got Node
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, node, got)
+}
@@ -0,0 +1,162 @@
+// Package server provides health, version and capability discovery for a Headscale server.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
+)
+
+const (
+	// HealthStatusPass is the health status reported by a healthy server.
+	HealthStatusPass = "pass"
+)
+
+// ServerResourceInterface is an interface for querying the state of a Headscale server.
+type ServerResourceInterface interface {
+	Health(ctx context.Context) (HealthResponse, error)
+	Version(ctx context.Context) (VersionInfo, error)
+	Info(ctx context.Context) (Info, error)
+}
+
+// ServerResource is a struct that implements the ServerResourceInterface.
+type ServerResource struct {
+	r requests.RequestInterface
+}
+
+// NewServerResource creates a new ServerResource.
+func NewServerResource(r requests.RequestInterface) *ServerResource {
+	return &ServerResource{r: r}
+}
+
+// HealthResponse represents the response of the Headscale health endpoint.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// Healthy reports whether the server reported itself healthy.
+func (h HealthResponse) Healthy() bool {
+	return h.Status == HealthStatusPass
+}
+
+// Health checks the health of the Headscale server, including its database connection.
+//
+// An unhealthy server answers with status 500, which is returned as a *requests.APIError.
+func (s *ServerResource) Health(ctx context.Context) (HealthResponse, error) {
+	var health HealthResponse
+
+	url := requests.BuildServerURL(s.r, "health")
+	req, err := s.r.BuildRequest(ctx, http.MethodGet, url, requests.RequestOptions{})
+	if err != nil {
+		return health, err
+	}
+
+	err = s.r.Do(ctx, req, &health)
+	return health, err
+}
+
+// GoInfo describes the Go toolchain a Headscale binary was built with.
+type GoInfo struct {
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+}
+
+// VersionInfo represents the build information reported by Headscale.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	Go        GoInfo `json:"go"`
+	Dirty     bool   `json:"dirty"`
+}
+
+// Version returns the build information of the Headscale server.
+//
+// Servers without a version endpoint (before v0.27.0) yield an error wrapping versions.ErrUnsupportedByServer.
+func (s *ServerResource) Version(ctx context.Context) (VersionInfo, error) {
+	var info VersionInfo
+
+	if err := requests.ServerVersion(s.r).Require(versions.CapabilityVersionEndpoint); err != nil {
+		return info, err
+	}
+
+	url := requests.BuildServerURL(s.r, "version")
+	req, err := s.r.BuildRequest(ctx, http.MethodGet, url, requests.RequestOptions{})
+	if err != nil {
+		return info, err
+	}
+
+	err = s.r.Do(ctx, req, &info)
+
+	var apiErr *requests.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return info, fmt.Errorf("%w: version endpoint: %w", versions.ErrUnsupportedByServer, err)
+	}
+	return info, err
+}
+
+// Info summarizes the reachability, health, version and capabilities of a Headscale server.
+type Info struct {
+	// Reachable reports whether the server answered the health check at all.
+	Reachable bool
+
+	// Healthy reports whether the server reported itself healthy.
+	Healthy bool
+
+	// Latency is the round-trip time of the health check.
+	Latency time.Duration
+
+	// Version is the detected or configured server version; zero if unknown.
+	Version versions.ServerVersion
+
+	// Build is the server's build information; nil if the server does not report it.
+	Build *VersionInfo
+
+	// Capabilities lists the capabilities of Version; nil if the version is unknown.
+	Capabilities []versions.Capability
+}
+
+// Info probes the server's health and version.
+//
+// A detected version is recorded on the client, so that resources report versions.ErrUnsupportedByServer
+// for operations the server lacks, unless a server version was configured. An error is returned only
+// if the server cannot be reached.
+func (s *ServerResource) Info(ctx context.Context) (Info, error) {
+	var info Info
+
+	start := time.Now()
+	health, err := s.Health(ctx)
+	info.Latency = time.Since(start)
+
+	var apiErr *requests.APIError
+	switch {
+	case err == nil:
+		info.Reachable = true
+		info.Healthy = health.Healthy()
+	case errors.As(err, &apiErr):
+		info.Reachable = true
+	default:
+		return info, err
+	}
+
+	info.Version = requests.ServerVersion(s.r)
+	if build, err := s.Version(ctx); err == nil {
+		info.Build = &build
+		if v, err := versions.ParseServerVersion(build.Version); err == nil && info.Version.IsZero() {
+			info.Version = v
+			requests.SetServerVersion(s.r, v)
+		}
+	}
+
+	if !info.Version.IsZero() {
+		info.Capabilities = info.Version.Capabilities()
+	}
+
+	return info, nil
+}
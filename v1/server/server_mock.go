@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockServerResource is a mock implementation of ServerResourceInterface for testing.
+type MockServerResource struct {
+	mock.Mock
+}
+
+// Health returns a mock health response.
+func (m *MockServerResource) Health(ctx context.Context) (HealthResponse, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(HealthResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// Version returns mock build information.
+func (m *MockServerResource) Version(ctx context.Context) (VersionInfo, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(VersionInfo), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
+// Info returns a mock server summary.
+func (m *MockServerResource) Info(ctx context.Context) (Info, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(Info), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
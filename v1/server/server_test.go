@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hibare/headscale-client-go/logger"
+	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerResource_Health(t *testing.T) {
+	fixture := testutil.TestFixture[HealthResponse]{
+		Endpoint:       "health",
+		ServerEndpoint: true,
+		Method:         http.MethodGet,
+		SuccessResp:    HealthResponse{Status: HealthStatusPass},
+	}
+
+	testutil.RunResourceTest(t, fixture, func(ctx context.Context, mockReq *requests.MockRequest) (HealthResponse, error) {
+		s := &ServerResource{r: mockReq}
+		return s.Health(ctx)
+	})
+}
+
+func TestServerResource_Version(t *testing.T) {
+	fixture := testutil.TestFixture[VersionInfo]{
+		Endpoint:       "version",
+		ServerEndpoint: true,
+		Method:         http.MethodGet,
+		SuccessResp:    VersionInfo{Version: "v0.28.0", Commit: "abc123"},
+	}
+
+	testutil.RunResourceTest(t, fixture, func(ctx context.Context, mockReq *requests.MockRequest) (VersionInfo, error) {
+		s := &ServerResource{r: mockReq}
+		return s.Version(ctx)
+	})
+}
+
+// newTestServer starts a Headscale stand-in serving /health and, if version is set, /version.
+func newTestServer(t *testing.T, healthy bool, version string) requests.RequestInterface {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"status":"fail"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"pass"}`))
+	})
+	if version != "" {
+		mux.HandleFunc("GET /version", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"version":"` + version + `","commit":"abc123","go":{"version":"go1.25"}}`))
+		})
+	}
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	baseURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	return requests.NewRequest(baseURL, "key", versions.APIVersionV1, requests.RequestConfig{
+		Logger: logger.NewDefaultLogger(logger.LevelError),
+	})
+}
+
+func TestServerResource_Info(t *testing.T) {
+	t.Run("detects version", func(t *testing.T) {
+		r := newTestServer(t, true, "v0.28.0")
+		info, err := NewServerResource(r).Info(t.Context())
+		require.NoError(t, err)
+
+		assert.True(t, info.Reachable)
+		assert.True(t, info.Healthy)
+		assert.Positive(t, info.Latency)
+		assert.Equal(t, versions.ServerVersion{Minor: 28}, info.Version)
+		require.NotNil(t, info.Build)
+		assert.Equal(t, "go1.25", info.Build.Go.Version)
+		assert.Contains(t, info.Capabilities, versions.CapabilityNodeTags)
+		assert.Equal(t, info.Version, requests.ServerVersion(r), "detected version is recorded")
+	})
+
+	t.Run("configured version is kept", func(t *testing.T) {
+		r := newTestServer(t, true, "v0.28.0")
+		requests.SetServerVersion(r, versions.ServerVersion{Minor: 26})
+
+		info, err := NewServerResource(r).Info(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, versions.ServerVersion{Minor: 26}, info.Version)
+		assert.Nil(t, info.Build, "the version endpoint is not probed on servers known to lack it")
+		assert.Equal(t, versions.ServerVersion{Minor: 26}, requests.ServerVersion(r))
+	})
+
+	t.Run("old server without version endpoint", func(t *testing.T) {
+		r := newTestServer(t, true, "")
+		s := NewServerResource(r)
+
+		info, err := s.Info(t.Context())
+		require.NoError(t, err)
+		assert.True(t, info.Healthy)
+		assert.True(t, info.Version.IsZero())
+		assert.Nil(t, info.Capabilities)
+
+		_, err = s.Version(t.Context())
+		require.ErrorIs(t, err, versions.ErrUnsupportedByServer)
+	})
+
+	t.Run("unhealthy", func(t *testing.T) {
+		info, err := NewServerResource(newTestServer(t, false, "v0.27.1")).Info(t.Context())
+		require.NoError(t, err)
+		assert.True(t, info.Reachable)
+		assert.False(t, info.Healthy)
+		assert.Equal(t, versions.ServerVersion{Minor: 27, Patch: 1}, info.Version)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		baseURL, _ := url.Parse("http://127.0.0.1:1")
+		r := requests.NewRequest(baseURL, "key", versions.APIVersionV1, requests.RequestConfig{
+			Logger: logger.NewDefaultLogger(logger.LevelError),
+		})
+
+		info, err := NewServerResource(r).Info(t.Context())
+		require.Error(t, err)
+		assert.False(t, info.Reachable)
+	})
+}
@@ -3,17 +3,22 @@ package testutil
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
 
 	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
 const (
+	// optionsArgIndex is the index of the options argument in BuildRequest(ctx, method, url, opt).
+	optionsArgIndex = 3
+
 	// responseArgIndex is the index of the response argument in Do(ctx, req, v).
 	responseArgIndex = 2
 )
@@ -24,6 +29,9 @@ type TestFixture[R any] struct {
 	SuccessResp R
 	BuildErr    error
 	DoErr       error
+
+	// ServerEndpoint marks Endpoint as a path outside the versioned API, built with BuildServerURL.
+	ServerEndpoint bool
 }
 
 func getEndpointArgs(endpoint any) []any {
@@ -44,15 +52,24 @@ func getEndpointArgs(endpoint any) []any {
 	}
 }
 
+// buildURLMethod returns the name of the RequestInterface method building the fixture's endpoint.
+func (fix TestFixture[R]) buildURLMethod() string {
+	if fix.ServerEndpoint {
+		return "BuildServerURL"
+	}
+	return "BuildURL"
+}
+
 func RunResourceTest[R any](t *testing.T, fix TestFixture[R], action func(ctx context.Context, mockReq *requests.MockRequest) (R, error)) {
 	t.Run("success", func(t *testing.T) {
 		mockReq := new(requests.MockRequest)
+		mockReq.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
 		ctx := t.Context()
 		fakeURL := &url.URL{Scheme: "http", Host: "example.com"}
 		fakeReq := &http.Request{}
 
 		urlArgs := getEndpointArgs(fix.Endpoint)
-		mockReq.On("BuildURL", urlArgs...).Return(fakeURL)
+		mockReq.On(fix.buildURLMethod(), urlArgs...).Return(fakeURL)
 		mockReq.On("BuildRequest", ctx, fix.Method, fakeURL, mock.Anything).Return(fakeReq, nil)
 		mockReq.On("Do", ctx, fakeReq, mock.Anything).Run(func(args mock.Arguments) {
 			if len(args) > responseArgIndex && args.Get(responseArgIndex) != nil {
@@ -70,12 +87,13 @@ func RunResourceTest[R any](t *testing.T, fix TestFixture[R], action func(ctx co
 
 	t.Run("build request error", func(t *testing.T) {
 		mockReq := new(requests.MockRequest)
+		mockReq.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
 		ctx := t.Context()
 		fakeURL := &url.URL{Scheme: "http", Host: "example.com"}
 		fakeReq := &http.Request{}
 
 		urlArgs := getEndpointArgs(fix.Endpoint)
-		mockReq.On("BuildURL", urlArgs...).Return(fakeURL)
+		mockReq.On(fix.buildURLMethod(), urlArgs...).Return(fakeURL)
 
 		buildErr := fix.BuildErr
 		if buildErr == nil {
@@ -92,12 +110,13 @@ func RunResourceTest[R any](t *testing.T, fix TestFixture[R], action func(ctx co
 
 	t.Run("do error", func(t *testing.T) {
 		mockReq := new(requests.MockRequest)
+		mockReq.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
 		ctx := t.Context()
 		fakeURL := &url.URL{Scheme: "http", Host: "example.com"}
 		fakeReq := &http.Request{}
 
 		urlArgs := getEndpointArgs(fix.Endpoint)
-		mockReq.On("BuildURL", urlArgs...).Return(fakeURL)
+		mockReq.On(fix.buildURLMethod(), urlArgs...).Return(fakeURL)
 		mockReq.On("BuildRequest", ctx, fix.Method, fakeURL, mock.Anything).Return(fakeReq, nil)
 
 		doErr := fix.DoErr
@@ -113,3 +132,23 @@ func RunResourceTest[R any](t *testing.T, fix TestFixture[R], action func(ctx co
 		mockReq.AssertExpectations(t)
 	})
 }
+
+// MockEndpoint makes m answer method requests to the endpoint built from parts, for tests of
+// methods calling several endpoints. record receives the options of each request built, respond
+// fills in the response; either may be nil. The expectations are optional.
+func MockEndpoint(m *requests.MockRequest, method string, parts []any, record func(opt requests.RequestOptions), respond func(v any)) {
+	u := &url.URL{Path: fmt.Sprintf("/%v", parts)}
+	req := &http.Request{Method: method, URL: u}
+
+	m.On("BuildURL", parts...).Return(u).Maybe()
+	m.On("BuildRequest", mock.Anything, method, u, mock.Anything).Run(func(args mock.Arguments) {
+		if record != nil {
+			record(args.Get(optionsArgIndex).(requests.RequestOptions)) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+		}
+	}).Return(req, nil).Maybe()
+	m.On("Do", mock.Anything, req, mock.Anything).Run(func(args mock.Arguments) {
+		if respond != nil {
+			respond(args.Get(responseArgIndex))
+		}
+	}).Return(nil).Maybe()
+}
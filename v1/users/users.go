@@ -3,20 +3,34 @@ package users
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/hibare/headscale-client-go/compat"
+	"github.com/hibare/headscale-client-go/iterate"
 	"github.com/hibare/headscale-client-go/requests"
+	"github.com/hibare/headscale-client-go/versions"
 )
 
 // UserResourceInterface is an interface for managing users in Headscale.
 type UserResourceInterface interface {
 	List(ctx context.Context, filter UserListFilter) (UsersResponse, error)
+	All(ctx context.Context, filter UserListFilter) iter.Seq2[User, error]
 	Create(ctx context.Context, request CreateUserRequest) (UserResponse, error)
 	Delete(ctx context.Context, id string) error
 	Rename(ctx context.Context, id, newName string) (UserResponse, error)
+	Lookup(ctx context.Context, ref string) (User, error)
 }
 
+// ErrAmbiguousUser is returned by Lookup when a name is shared by several users, e.g. users of
+// different OIDC providers.
+var ErrAmbiguousUser = errors.New("ambiguous user reference")
+
 // UserResource is a struct that implements the UserResourceInterface.
 type UserResource struct {
 	r requests.RequestInterface
@@ -30,13 +44,33 @@ func NewUserResource(r requests.RequestInterface) *UserResource {
 // User represents a user in Headscale.
 type User struct {
 	ID            string    `json:"id"`
-	Name          string    `json:"name"`
+	Name          string    `json:"name" schema:"required"`
 	CreatedAt     time.Time `json:"createdAt"`
 	DisplayName   string    `json:"displayName"`
 	Email         string    `json:"email"`
 	ProviderID    string    `json:"providerId"`
 	Provider      string    `json:"provider"`
 	ProfilePicURL string    `json:"profilePicUrl"`
+
+	// Extra holds response members without a field, e.g. those added by newer Headscale releases.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a User, keeping unknown members in Extra.
+func (u *User) UnmarshalJSON(data []byte) error {
+	type user User
+	extra, err := compat.UnmarshalExtra(data, (*user)(u))
+	if err != nil {
+		return err
+	}
+	u.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a User, including the members in Extra.
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	return compat.MarshalExtra(user(u), u.Extra)
 }
 
 // UsersResponse represents a list of users response from the API.
@@ -55,6 +89,12 @@ type UserListFilter struct {
 func (u *UserResource) List(ctx context.Context, filter UserListFilter) (UsersResponse, error) {
 	var users UsersResponse
 
+	if filter != (UserListFilter{}) {
+		if err := requests.ServerVersion(u.r).Require(versions.CapabilityUserListFilter); err != nil {
+			return users, err
+		}
+	}
+
 	queryParams := map[string]any{}
 
 	if filter.ID != "" {
@@ -79,6 +119,14 @@ func (u *UserResource) List(ctx context.Context, filter UserListFilter) (UsersRe
 	return users, err
 }
 
+// All returns an iterator over the users matching filter. The list is fetched when iteration starts.
+func (u *UserResource) All(ctx context.Context, filter UserListFilter) iter.Seq2[User, error] {
+	return iterate.Fetch(func() ([]User, error) {
+		resp, err := u.List(ctx, filter)
+		return resp.Users, err
+	})
+}
+
 // UserResponse represents a single user response from the API.
 type UserResponse struct {
 	User User `json:"user"`
@@ -87,9 +135,9 @@ type UserResponse struct {
 // CreateUserRequest represents a request to create a user.
 type CreateUserRequest struct {
 	Name        string `json:"name"`
-	DisplayName string `json:"displayName"`
-	Email       string `json:"email"`
-	PictureURL  string `json:"pictureUrl"`
+	DisplayName string `json:"displayName,omitempty"`
+	Email       string `json:"email,omitempty"`
+	PictureURL  string `json:"pictureUrl,omitempty"`
 }
 
 // Create creates a new user in Headscale.
@@ -110,6 +158,10 @@ func (u *UserResource) Create(ctx context.Context, createUserRequest CreateUserR
 
 // Delete removes a user from the Headscale.
 func (u *UserResource) Delete(ctx context.Context, id string) error {
+	if err := requests.ServerVersion(u.r).Require(versions.CapabilityUserIDs); err != nil {
+		return err
+	}
+
 	url := u.r.BuildURL("user", id)
 	req, err := u.r.BuildRequest(ctx, http.MethodDelete, url, requests.RequestOptions{})
 	if err != nil {
@@ -123,6 +175,10 @@ func (u *UserResource) Delete(ctx context.Context, id string) error {
 func (u *UserResource) Rename(ctx context.Context, id, newName string) (UserResponse, error) {
 	var user UserResponse
 
+	if err := requests.ServerVersion(u.r).Require(versions.CapabilityUserIDs); err != nil {
+		return user, err
+	}
+
 	url := u.r.BuildURL("user", id, "rename", newName)
 	req, err := u.r.BuildRequest(ctx, http.MethodPost, url, requests.RequestOptions{})
 	if err != nil {
@@ -132,3 +188,43 @@ func (u *UserResource) Rename(ctx context.Context, id, newName string) (UserResp
 	err = u.r.Do(ctx, req, &user)
 	return user, err
 }
+
+// Lookup finds the user with the ID ref, or failing that, the user named ref or with the email ref.
+// It lists all users, so it works on servers without user list filters. A name or email nobody has
+// returns an error wrapping iterate.ErrNotFound; one several users share returns one wrapping
+// ErrAmbiguousUser.
+func (u *UserResource) Lookup(ctx context.Context, ref string) (User, error) {
+	resp, err := u.List(ctx, UserListFilter{})
+	if err != nil {
+		return User{}, err
+	}
+
+	var named, mailed []User
+	for _, user := range resp.Users {
+		if user.ID == ref {
+			return user, nil
+		}
+		if user.Name == ref {
+			named = append(named, user)
+		}
+		if user.Email != "" && strings.EqualFold(user.Email, ref) {
+			mailed = append(mailed, user)
+		}
+	}
+	if len(named) == 0 {
+		named = mailed
+	}
+
+	switch len(named) {
+	case 0:
+		return User{}, fmt.Errorf("%w: %q", &iterate.NotFoundError{Type: fmt.Sprintf("%T", User{})}, ref)
+	case 1:
+		return named[0], nil
+	default:
+		ids := make([]string, len(named))
+		for i, user := range named {
+			ids[i] = user.ID
+		}
+		return User{}, fmt.Errorf("%w %q: users %s", ErrAmbiguousUser, ref, strings.Join(ids, ", "))
+	}
+}
@@ -2,6 +2,7 @@ package users
 
 import (
 	"context"
+	"iter"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -17,6 +18,12 @@ func (m *MockUserResource) List(ctx context.Context, filter UserListFilter) (Use
 	return args.Get(0).(UsersResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
 
+// All returns a mock iterator over users matching filter.
+func (m *MockUserResource) All(ctx context.Context, filter UserListFilter) iter.Seq2[User, error] {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(iter.Seq2[User, error]) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
+
 // Create creates a mock user from the Headscale.
 func (m *MockUserResource) Create(ctx context.Context, request CreateUserRequest) (UserResponse, error) {
 	args := m.Called(ctx, request)
@@ -34,3 +41,9 @@ func (m *MockUserResource) Rename(ctx context.Context, id, newName string) (User
 	args := m.Called(ctx, id, newName)
 	return args.Get(0).(UserResponse), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
 }
+
+// Lookup returns a mock user by ID or name.
+func (m *MockUserResource) Lookup(ctx context.Context, ref string) (User, error) {
+	args := m.Called(ctx, ref)
+	return args.Get(0).(User), args.Error(1) //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+}
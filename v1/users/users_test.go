@@ -3,12 +3,21 @@ package users
 import (
 	"context"
 	"net/http"
+	"net/url"
 	"testing"
 
+	"github.com/hibare/headscale-client-go/iterate"
 	"github.com/hibare/headscale-client-go/requests"
 	"github.com/hibare/headscale-client-go/v1/testutil"
+	"github.com/hibare/headscale-client-go/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+// responseArgIndex is the index of the response argument in Do(ctx, req, v).
+const responseArgIndex = 2
+
 func TestUserResource_List(t *testing.T) {
 	fixture := testutil.TestFixture[UsersResponse]{
 		Endpoint:    "user",
@@ -20,6 +29,16 @@ func TestUserResource_List(t *testing.T) {
 		u := &UserResource{r: mockReq}
 		return u.List(ctx, UserListFilter{})
 	})
+
+	t.Run("filter unsupported by server", func(t *testing.T) {
+		mockReq := new(requests.MockRequest)
+		mockReq.On("ServerVersion").Return(versions.ServerVersion{Minor: 23})
+
+		u := &UserResource{r: mockReq}
+		_, err := u.List(t.Context(), UserListFilter{Name: "test"})
+		require.ErrorIs(t, err, versions.ErrUnsupportedByServer)
+		mockReq.AssertNotCalled(t, "Do", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestUserResource_Create(t *testing.T) {
@@ -66,3 +85,52 @@ func TestUserResource_Rename(t *testing.T) {
 		return u.Rename(ctx, "1", "new-name")
 	})
 }
+
+func TestUserResource_Lookup(t *testing.T) {
+	list := UsersResponse{Users: []User{
+		{ID: "1", Name: "alice"},
+		{ID: "2", Name: "bob", Provider: "oidc"},
+		{ID: "3", Name: "bob"},
+		{ID: "4", Name: "1"},
+		{ID: "5", Name: "carol", Email: "Carol@example.com"},
+		{ID: "6", Name: "dave@example.com"},
+		{ID: "7", Name: "dave", Email: "dave@example.com"},
+	}}
+
+	tests := []struct {
+		ref     string
+		want    User
+		wantErr error
+	}{
+		{ref: "alice", want: list.Users[0]},
+		{ref: "3", want: list.Users[2]},
+		{ref: "1", want: list.Users[0]},
+		{ref: "bob", wantErr: ErrAmbiguousUser},
+		{ref: "carol@example.com", want: list.Users[4]},
+		{ref: "dave@example.com", want: list.Users[5]},
+		{ref: "erin", wantErr: iterate.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			mockReq := new(requests.MockRequest)
+			mockReq.On("ServerVersion").Return(versions.ServerVersion{}).Maybe()
+			fakeURL := &url.URL{Path: "/user"}
+			fakeReq := &http.Request{}
+			mockReq.On("BuildURL", "user").Return(fakeURL)
+			mockReq.On("BuildRequest", mock.Anything, http.MethodGet, fakeURL, mock.Anything).Return(fakeReq, nil)
+			mockReq.On("Do", mock.Anything, fakeReq, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(responseArgIndex).(*UsersResponse) = list //nolint:errcheck // reason: type assertion on mock, error not possible/needed
+			}).Return(nil)
+
+			u := &UserResource{r: mockReq}
+			user, err := u.Lookup(t.Context(), tt.ref)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, user)
+		})
+	}
+}
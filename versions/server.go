@@ -0,0 +1,278 @@
+package versions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedByServer is returned when an operation is not available in the Headscale release the server runs.
+	ErrUnsupportedByServer = errors.New("unsupported by server")
+
+	// ErrUnknownServerVersion is returned by RequireKnown when the server version is unknown.
+	ErrUnknownServerVersion = errors.New("server version unknown")
+)
+
+// versionParts is the number of dot-separated numbers in a release version.
+const versionParts = 3
+
+// ServerVersion is a Headscale release version, such as v0.28.0.
+//
+// The zero value means the version is unknown; an unknown server is assumed to support everything.
+type ServerVersion struct {
+	Major int
+	Minor int
+	Patch int
+
+	// Pre is the pre-release suffix, e.g. "beta.1". Build metadata and "-dirty" markers are dropped.
+	Pre string
+}
+
+// ParseServerVersion parses a Headscale version such as "v0.28.0", "0.26.1" or "v0.27.0-beta.2".
+func ParseServerVersion(s string) (ServerVersion, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	s = strings.TrimSuffix(s, "-dirty")
+
+	core, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != versionParts {
+		return ServerVersion{}, fmt.Errorf("invalid server version %q", raw)
+	}
+
+	nums := make([]int, versionParts)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return ServerVersion{}, fmt.Errorf("invalid server version %q", raw)
+		}
+		nums[i] = n
+	}
+
+	return ServerVersion{Major: nums[0], Minor: nums[1], Patch: nums[2], Pre: pre}, nil
+}
+
+// String returns the version in the form "v0.28.0", or "unknown" for the zero value.
+func (v ServerVersion) String() string {
+	if v.IsZero() {
+		return "unknown"
+	}
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// IsZero reports whether the version is unknown.
+func (v ServerVersion) IsZero() bool {
+	return v == ServerVersion{}
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older than, equal to or newer than o.
+// A pre-release is older than the release it precedes.
+func (v ServerVersion) Compare(o ServerVersion) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	default:
+		return strings.Compare(v.Pre, o.Pre)
+	}
+}
+
+// Supports reports whether the release provides capability c. An unknown version supports everything.
+func (v ServerVersion) Supports(c Capability) bool {
+	if v.IsZero() {
+		return true
+	}
+
+	r, ok := capabilities[c]
+	if !ok {
+		return false
+	}
+	return r.contains(v.release())
+}
+
+// Require returns an error wrapping ErrUnsupportedByServer if the release does not provide capability c.
+func (v ServerVersion) Require(c Capability) error {
+	if v.Supports(c) {
+		return nil
+	}
+
+	r, ok := capabilities[c]
+	if !ok {
+		return fmt.Errorf("%w: unknown capability %s", ErrUnsupportedByServer, c)
+	}
+	return fmt.Errorf("%w: %s requires Headscale %s, server runs %s", ErrUnsupportedByServer, c, r, v)
+}
+
+// RequireKnown is Require for operations that older releases would misinterpret rather than reject.
+// It returns an error wrapping ErrUnknownServerVersion if the version is unknown.
+func (v ServerVersion) RequireKnown(c Capability) error {
+	if v.IsZero() {
+		return fmt.Errorf("%w: %s requires a known server version", ErrUnknownServerVersion, c)
+	}
+	return v.Require(c)
+}
+
+// Capabilities returns the capabilities the release provides, sorted.
+func (v ServerVersion) Capabilities() []Capability {
+	var out []Capability
+	for c := range capabilities {
+		if v.Supports(c) {
+			out = append(out, c)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// release returns v without its pre-release suffix, so that a release candidate is matched
+// against the capabilities of the release it precedes.
+func (v ServerVersion) release() ServerVersion {
+	v.Pre = ""
+	return v
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+
+// Capability is an endpoint or field that exists only in some Headscale releases.
+type Capability string
+
+const (
+	// CapabilityVersionEndpoint is the GET /version endpoint reporting the server version.
+	CapabilityVersionEndpoint Capability = "version_endpoint"
+
+	// CapabilityUserIDs is addressing users by numeric ID rather than name when renaming and deleting.
+	CapabilityUserIDs Capability = "user_ids"
+
+	// CapabilityUserListFilter is filtering user lists by ID, name or email.
+	CapabilityUserListFilter Capability = "user_list_filter"
+
+	// CapabilityRoutesAPI is the /api/v1/routes endpoints, replaced by approved routes on nodes.
+	CapabilityRoutesAPI Capability = "routes_api"
+
+	// CapabilityApproveRoutes is POST /api/v1/node/{id}/approve_routes and the node route lists.
+	CapabilityApproveRoutes Capability = "approve_routes"
+
+	// CapabilitySetTags is POST /api/v1/node/{id}/tags, which replaces all tags of a node.
+	CapabilitySetTags Capability = "set_tags"
+
+	// CapabilityNodeTags is the single tags list on nodes, replacing forced, valid and invalid tags.
+	CapabilityNodeTags Capability = "node_tags"
+
+	// CapabilityNodeReassign is POST /api/v1/node/{id}/user, which moves a node to another user.
+	CapabilityNodeReassign Capability = "node_reassign"
+
+	// CapabilityNodeReassignBody is passing the user to POST /api/v1/node/{id}/user in the request body
+	// rather than the query.
+	CapabilityNodeReassignBody Capability = "node_reassign_body"
+
+	// CapabilityNodeReassignByID is passing the user to POST /api/v1/node/{id}/user by numeric ID rather than name.
+	CapabilityNodeReassignByID Capability = "node_reassign_by_id"
+
+	// CapabilityRegistrationID is registering nodes by the registration ID of their register URL
+	// rather than their machine key.
+	CapabilityRegistrationID Capability = "registration_id"
+
+	// CapabilityNodeExpiry is setting the expiry of a node to a given time with POST /api/v1/node/{id}/expire
+	// rather than only expiring it immediately.
+	CapabilityNodeExpiry Capability = "node_expiry"
+
+	// CapabilityNodeNameMinLength is rejecting node names shorter than two characters.
+	CapabilityNodeNameMinLength Capability = "node_name_min_length"
+
+	// CapabilityPreAuthKeyUserObject is pre-auth keys embedding their user as an object rather than a name.
+	CapabilityPreAuthKeyUserObject Capability = "preauthkey_user_object"
+
+	// CapabilityPreAuthKeyUserID is addressing the user of pre-auth key requests by numeric ID rather than name.
+	CapabilityPreAuthKeyUserID Capability = "preauthkey_user_id"
+
+	// CapabilityPreAuthKeyListAll is listing the pre-auth keys of all users in one call.
+	CapabilityPreAuthKeyListAll Capability = "preauthkey_list_all"
+
+	// CapabilityPreAuthKeyDelete is DELETE /api/v1/preauthkey.
+	CapabilityPreAuthKeyDelete Capability = "preauthkey_delete"
+
+	// CapabilityPreAuthKeyExpireByID is expiring pre-auth keys by ID rather than by user and key.
+	CapabilityPreAuthKeyExpireByID Capability = "preauthkey_expire_by_id"
+
+	// CapabilityAPIKeyExpireByID is expiring API keys by ID rather than prefix.
+	CapabilityAPIKeyExpireByID Capability = "apikey_expire_by_id"
+)
+
+// Headscale releases referenced by the capability table.
+var (
+	// MinimumServerVersion is the oldest Headscale release the capability table describes.
+	MinimumServerVersion = ServerVersion{Minor: 23}
+
+	release024 = ServerVersion{Minor: 24}
+	release025 = ServerVersion{Minor: 25}
+	release026 = ServerVersion{Minor: 26}
+	release027 = ServerVersion{Minor: 27}
+	release028 = ServerVersion{Minor: 28}
+)
+
+// versionRange is the half-open range of releases [Since, Until) providing a capability.
+// A zero Until means the capability is still available.
+type versionRange struct {
+	Since ServerVersion
+	Until ServerVersion
+}
+
+func (r versionRange) contains(v ServerVersion) bool {
+	if v.Compare(r.Since) < 0 {
+		return false
+	}
+	return r.Until.IsZero() || v.Compare(r.Until) < 0
+}
+
+func (r versionRange) String() string {
+	if r.Until.IsZero() {
+		return r.Since.String() + " or newer"
+	}
+	return fmt.Sprintf("%s up to %s (exclusive)", r.Since, r.Until)
+}
+
+// capabilities records the releases providing each capability, taken from the OpenAPI
+// specification shipped with each Headscale release.
+var capabilities = map[Capability]versionRange{
+	CapabilityVersionEndpoint:      {Since: release027},
+	CapabilityUserIDs:              {Since: release024},
+	CapabilityUserListFilter:       {Since: release024},
+	CapabilityRoutesAPI:            {Since: MinimumServerVersion, Until: release026},
+	CapabilityApproveRoutes:        {Since: release026},
+	CapabilitySetTags:              {Since: MinimumServerVersion},
+	CapabilityNodeTags:             {Since: release028},
+	CapabilityNodeReassign:         {Since: MinimumServerVersion, Until: release028},
+	CapabilityNodeReassignBody:     {Since: release024, Until: release028},
+	CapabilityNodeReassignByID:     {Since: release026, Until: release028},
+	CapabilityRegistrationID:       {Since: release025},
+	CapabilityNodeExpiry:           {Since: release028},
+	CapabilityNodeNameMinLength:    {Since: release028},
+	CapabilityPreAuthKeyUserObject: {Since: release026},
+	CapabilityPreAuthKeyUserID:     {Since: release026},
+	CapabilityPreAuthKeyListAll:    {Since: release028},
+	CapabilityPreAuthKeyDelete:     {Since: release028},
+	CapabilityPreAuthKeyExpireByID: {Since: release028},
+	CapabilityAPIKeyExpireByID:     {Since: release028},
+}
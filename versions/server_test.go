@@ -0,0 +1,145 @@
+package versions
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    ServerVersion
+		wantErr bool
+	}{
+		{"v0.28.0", ServerVersion{Minor: 28}, false},
+		{"0.26.1", ServerVersion{Minor: 26, Patch: 1}, false},
+		{"v0.27.0-beta.2", ServerVersion{Minor: 27, Pre: "beta.2"}, false},
+		{"v0.28.0-dirty", ServerVersion{Minor: 28}, false},
+		{"v1.2.3+build.5", ServerVersion{Major: 1, Minor: 2, Patch: 3}, false},
+		{"dev", ServerVersion{}, true},
+		{"v0.28", ServerVersion{}, true},
+		{"v0.x.0", ServerVersion{}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			got, err := ParseServerVersion(c.in)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ParseServerVersion(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("ParseServerVersion(%q) = %+v, want %+v", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestServerVersion_Compare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"v0.28.0", "v0.28.0", 0},
+		{"v0.27.1", "v0.28.0", -1},
+		{"v1.0.0", "v0.99.9", 1},
+		{"v0.28.0-beta.1", "v0.28.0", -1},
+		{"v0.28.0-beta.2", "v0.28.0-beta.1", 1},
+	}
+	for _, c := range cases {
+		t.Run(c.a+" "+c.b, func(t *testing.T) {
+			a, _ := ParseServerVersion(c.a)
+			b, _ := ParseServerVersion(c.b)
+			if got := a.Compare(b); got != c.want {
+				t.Errorf("Compare() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestServerVersion_String(t *testing.T) {
+	if got := (ServerVersion{Minor: 27, Pre: "rc.1"}).String(); got != "v0.27.0-rc.1" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (ServerVersion{}).String(); got != "unknown" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestServerVersion_Supports(t *testing.T) {
+	cases := []struct {
+		version    string
+		capability Capability
+		want       bool
+	}{
+		{"v0.28.0", CapabilityVersionEndpoint, true},
+		{"v0.26.0", CapabilityVersionEndpoint, false},
+		{"v0.27.0-beta.1", CapabilityVersionEndpoint, true},
+		{"v0.25.1", CapabilityRoutesAPI, true},
+		{"v0.26.0", CapabilityRoutesAPI, false},
+		{"v0.27.1", CapabilityNodeReassign, true},
+		{"v0.28.0", CapabilityNodeReassign, false},
+		{"v0.23.0", CapabilityNodeReassignBody, false},
+		{"v0.25.0", CapabilityNodeReassignByID, false},
+		{"v0.26.0", CapabilityNodeReassignByID, true},
+		{"v0.24.0", CapabilityRegistrationID, false},
+		{"v0.25.0", CapabilityRegistrationID, true},
+		{"v0.27.1", CapabilityNodeExpiry, false},
+		{"v0.28.0", CapabilityNodeExpiry, true},
+		{"v0.23.0", CapabilitySetTags, true},
+		{"v0.22.3", CapabilitySetTags, false},
+		{"v0.28.0", Capability("teleport"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.version+" "+string(c.capability), func(t *testing.T) {
+			v, err := ParseServerVersion(c.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Supports(c.capability); got != c.want {
+				t.Errorf("Supports() = %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	if !(ServerVersion{}).Supports(CapabilityNodeReassign) {
+		t.Error("unknown version should support everything")
+	}
+}
+
+func TestServerVersion_Require(t *testing.T) {
+	v := ServerVersion{Minor: 26}
+	if err := v.Require(CapabilityApproveRoutes); err != nil {
+		t.Errorf("Require() error = %v", err)
+	}
+
+	err := v.Require(CapabilityPreAuthKeyDelete)
+	if !errors.Is(err, ErrUnsupportedByServer) {
+		t.Fatalf("Require() error = %v, want ErrUnsupportedByServer", err)
+	}
+	want := "unsupported by server: preauthkey_delete requires Headscale v0.28.0 or newer, server runs v0.26.0"
+	if err.Error() != want {
+		t.Errorf("Require() error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestServerVersion_RequireKnown(t *testing.T) {
+	if err := (ServerVersion{Minor: 28}).RequireKnown(CapabilityNodeExpiry); err != nil {
+		t.Errorf("RequireKnown() error = %v", err)
+	}
+	if err := (ServerVersion{Minor: 27}).RequireKnown(CapabilityNodeExpiry); !errors.Is(err, ErrUnsupportedByServer) {
+		t.Errorf("RequireKnown() error = %v, want ErrUnsupportedByServer", err)
+	}
+	if err := (ServerVersion{}).RequireKnown(CapabilityNodeExpiry); !errors.Is(err, ErrUnknownServerVersion) {
+		t.Errorf("RequireKnown() error = %v, want ErrUnknownServerVersion", err)
+	}
+}
+
+func TestServerVersion_Capabilities(t *testing.T) {
+	caps := ServerVersion{Minor: 28}.Capabilities()
+	if !slices.IsSorted(caps) {
+		t.Errorf("Capabilities() not sorted: %v", caps)
+	}
+	if !slices.Contains(caps, CapabilityNodeTags) || slices.Contains(caps, CapabilityRoutesAPI) {
+		t.Errorf("Capabilities() = %v", caps)
+	}
+}